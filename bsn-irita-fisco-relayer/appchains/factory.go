@@ -8,15 +8,19 @@ import (
 	"github.com/spf13/viper"
 
 	"relayer/appchains/fisco"
+	"relayer/common"
 	// "relayer/appchains/ethereum"
 	"relayer/core"
 	"relayer/config"
+	"relayer/keystore"
 	"relayer/store"
 )
 
 // AppChainFactory defines an application chain factory
 type AppChainFactory struct {
-	Store *store.Store // store
+	Store      *store.Store      // store
+	Checkpoint store.Checkpoint  // relay progress checkpoint
+	KeyStore   keystore.KeyStore // signing keys of the chains
 }
 
 // AppChainFactory defines an application chain factory
@@ -25,9 +29,11 @@ type BaseConfigFactory struct {
 }
 
 // NewAppChainFactory constructs a new application chain factory
-func NewAppChainFactory(store *store.Store) *AppChainFactory {
+func NewAppChainFactory(store *store.Store, checkpoint store.Checkpoint, keyStore keystore.KeyStore) *AppChainFactory {
 	return &AppChainFactory{
-		Store: store,
+		Store:      store,
+		Checkpoint: checkpoint,
+		KeyStore:   keyStore,
 	}
 }
 
@@ -48,7 +54,7 @@ func (f *AppChainFactory) BuildAppChain(chainType string, chainParams []byte) (c
 		return nil, nil
 
 	case "fisco":
-		return fisco.BuildFISCOChain(chainParams, f.Store)
+		return fisco.BuildFISCOChain(chainParams, f.Store, f.Checkpoint, f.KeyStore)
 
 	default:
 		return nil, fmt.Errorf("application chain %s not supported", chainType)
@@ -72,6 +78,23 @@ func (f *AppChainFactory) GetChainID(chainType string, chainParams []byte) (chai
 	}
 }
 
+// GetDestID implements AppChainFactoryI
+func (f *AppChainFactory) GetDestID(chainType string, chainParams []byte) (common.DestID, error) {
+	switch strings.ToLower(chainType) {
+	case "eth":
+		return "", nil
+
+	case "fabric":
+		return "", nil
+
+	case "fisco":
+		return fisco.GetDestIDFromBytes(chainParams)
+
+	default:
+		return "", fmt.Errorf("application chain %s not supported", chainType)
+	}
+}
+
 // StoreBaseConfig implements AppChainFactoryI
 func (f *AppChainFactory) StoreBaseConfig(chainType string, baseConfig []byte) error {
 	switch strings.ToLower(chainType) {
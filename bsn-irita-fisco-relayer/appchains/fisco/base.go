@@ -3,6 +3,9 @@ package fisco
 import (
 	"encoding/json"
 	"strconv"
+	"time"
+
+	"relayer/common"
 )
 
 const (
@@ -12,7 +15,24 @@ const (
 
 // CompactBlock represents the compact block with tx hashes
 type CompactBlock struct {
-	Txs []string `json:"transactions"`
+	Hash       string   `json:"hash"`
+	ParentHash string   `json:"parentHash"`
+	Txs        []string `json:"transactions"`
+	Timestamp  string   `json:"timestamp"` // hex encoded block time in milliseconds
+}
+
+// Time returns the time the block was produced, zero if unknown
+func (b CompactBlock) Time() time.Time {
+	if len(b.Timestamp) == 0 {
+		return time.Time{}
+	}
+
+	ms, err := common.Hex2Decimal(b.Timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond))
 }
 
 // ChainParams defines the params for the specific chain
@@ -21,6 +41,12 @@ type ChainParams struct {
 	GroupID          int      `json:"groupId"`
 	ChainID          int64    `json:"chainId"`
 	IServiceCoreAddr string   `json:"iserviceCoreAddr"`
+
+	// number of blocks on top of a block before its events are relayed
+	ConfirmationDepth int64 `json:"confirmationDepth,omitempty"`
+
+	// subscribes to the new block numbers over the channel connection, besides polling
+	SubscribeBlocks bool `json:"subscribeBlocks,omitempty"`
 }
 
 type EndpointInfo struct {
@@ -36,6 +62,11 @@ func GetChainID(params ChainParams) string {
 	return strconv.FormatInt(params.ChainID, 10)
 }
 
+// GetDestID returns the dest ID of the chain from the specified chain params
+func GetDestID(params ChainParams) (common.DestID, error) {
+	return common.NewDestID(ChainType, strconv.Itoa(params.GroupID), GetChainID(params))
+}
+
 // GetChainIDFromBytes returns the unique chain id from the given chain params bytes
 func GetChainIDFromBytes(params []byte) (string, error) {
 	var chainParams ChainParams
@@ -46,3 +77,13 @@ func GetChainIDFromBytes(params []byte) (string, error) {
 
 	return GetChainID(chainParams), nil
 }
+
+// GetDestIDFromBytes returns the dest ID of the chain from the given chain params bytes
+func GetDestIDFromBytes(params []byte) (common.DestID, error) {
+	var chainParams ChainParams
+	if err := json.Unmarshal(params, &chainParams); err != nil {
+		return "", err
+	}
+
+	return GetDestID(chainParams)
+}
@@ -5,49 +5,115 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	ethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/FISCO-BCOS/go-sdk/abi"
+	"github.com/FISCO-BCOS/go-sdk/abi/bind"
 	fiscoclient "github.com/FISCO-BCOS/go-sdk/client"
 	"github.com/FISCO-BCOS/go-sdk/core/types"
 
 	"relayer/appchains/fisco/iservice"
 	"relayer/common"
 	"relayer/core"
+	"relayer/keystore"
 	"relayer/logging"
+	"relayer/metrics"
 	"relayer/mysql"
 	"relayer/store"
 )
 
+// errDuplicatedResponse is the revert reason of setResponse for a request already responded
+const errDuplicatedResponse = "duplicated response"
+
+// maxReorgDepth is the number of the recently handled block hashes kept to find the fork point of a reorg
+const maxReorgDepth = 256
+
 // FISCOChain defines the FISCO chain
 type FISCOChain struct {
 	Config  Config
 	Client  *fiscoclient.Client
 	ChainID string // unique chain ID
+	DestID  common.DestID
 
 	IServiceCoreSession *iservice.IServiceCoreExSession // iService Core Extension contract session
 	IServiceCoreABI     abi.ABI                         // parsed iService Core Extension ABI
+	Sequencer           *common.AccountSequencer        // serializes the response txs of the signing account
+	Signer              keystore.Signer                 // signs the response txs
+
+	reader     ChainReader      // chain reader for monitoring
+	subscriber HeadSubscriber   // new heads subscriber, nil for polling
+	params     ChainParams      // chain params as registered, before the overrides
+	store      *store.Store     // store backend instance
+	checkpoint store.Checkpoint // relay progress persistence
+	lastHeight int64            // last handled height, accessed atomically as read by the status and the reloads
+	lastHash   string           // hash of the last handled block
+	hashes     map[int64]string // hashes of the recently handled blocks by height, to find the fork point of a reorg
 
-	store      *store.Store // store backend instance
-	lastHeight int64        // last height
+	livenessMtx sync.Mutex
+	liveness    core.ChainLiveness // liveness of the chain monitor
 
+	// callsMtx is held for reading by the calls on the client, and for writing to close it
+	callsMtx sync.RWMutex
+
+	// forwarder of the rpc connection, closed along with the client, nil over channel
+	forwarder *common.Forwarder
+
+	runMtx  sync.Mutex                    // guards the run state below
 	done    bool                          // indicates if the chain monitor is done
+	cancel  context.CancelFunc            // cancels the chain monitor
+	stopped chan struct{}                 // closed when the chain monitor exits
 	handler core.InterchainRequestHandler // handler for the interchain request
+	queue   *core.EventQueue              // event queue waited for before scanning, nil if unbounded
 }
 
 // NewFISCOChain constructs a new FISCOChain instance
 func NewFISCOChain(
 	config Config,
 	store *store.Store,
+	checkpoint store.Checkpoint,
+	keyStore keystore.KeyStore,
 ) (*FISCOChain, error) {
-	clientConfig := BuildClientConfig(config)
+	destID, err := GetDestID(config.ChainParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain params: %s", err)
+	}
 
-	client, err := fiscoclient.Dial(clientConfig)
+	params := config.ChainParams
+	config = config.withOverrides(destID)
+
+	if config.ConfirmationDepth < 0 {
+		return nil, fmt.Errorf("invalid chain params: negative confirmation depth %d", config.ConfirmationDepth)
+	}
+
+	config.PrivateKey, err = loadPrivateKey(keyStore, destID, config.IsSMCrypto)
+	if err != nil {
+		return nil, err
+	}
+
+	algo := keystore.AlgoSecp256k1
+	if config.IsSMCrypto {
+		algo = keystore.AlgoSM2
+	}
+
+	signer, err := keystore.NewSigner(keystore.ChainTypeFISCO, keystore.SignerParams{PrivateKey: config.PrivateKey, Algo: algo})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to fisco node: %s", err)
+		return nil, fmt.Errorf("failed to build the signer of chain %s: %s", destID, err)
+	}
+
+	if config.MonitorInterval == 0 {
+		config.MonitorInterval = DefaultMonitorInterval
+	}
+
+	client, iServiceCore, forwarder, err := dial(config)
+	if err != nil {
+		return nil, err
 	}
 
 	iServiceCoreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
@@ -55,46 +121,115 @@ func NewFISCOChain(
 		return nil, fmt.Errorf("failed to parse iService Core Extension ABI: %s", err)
 	}
 
-	iServiceCore, err := iservice.NewIServiceCoreEx(ethcmn.HexToAddress(config.IServiceCoreAddr), client)
+	fisco := newFISCOChain(config, params, destID, client, iServiceCore, iServiceCoreABI, common.NewAccountSequencer(config.SubmitLimits, nil), signer, store, checkpoint)
+	fisco.forwarder = forwarder
+
+	err = fisco.storeChainParams()
 	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate the iService Core Extension contract: %s", err)
+		return nil, err
 	}
 
-	if config.MonitorInterval == 0 {
-		config.MonitorInterval = DefaultMonitorInterval
+	err = fisco.storeChainID()
+	if err != nil {
+		return nil, err
+	}
+
+	err = fisco.loadHeight()
+	if err != nil {
+		return nil, err
 	}
 
-	chainID := GetChainID(config.ChainParams)
+	return fisco, nil
+}
 
+// newFISCOChain builds the FISCOChain instance on the given connection
+func newFISCOChain(
+	config Config,
+	params ChainParams,
+	destID common.DestID,
+	client *fiscoclient.Client,
+	iServiceCore *iservice.IServiceCoreEx,
+	iServiceCoreABI abi.ABI,
+	sequencer *common.AccountSequencer,
+	signer keystore.Signer,
+	store *store.Store,
+	checkpoint store.Checkpoint,
+) *FISCOChain {
 	fisco := &FISCOChain{
 		Config:              config,
 		Client:              client,
-		ChainID:             chainID,
-		IServiceCoreSession: &iservice.IServiceCoreExSession{Contract: iServiceCore, CallOpts: *client.GetCallOpts(), TransactOpts: *client.GetTransactOpts()},
+		ChainID:             GetChainID(params),
+		DestID:              destID,
+		IServiceCoreSession: &iservice.IServiceCoreExSession{Contract: iServiceCore, CallOpts: *client.GetCallOpts(), TransactOpts: transactOpts(signer, *client.GetTransactOpts(), config.IsSMCrypto)},
 		IServiceCoreABI:     iServiceCoreABI,
+		Sequencer:           sequencer,
+		Signer:              signer,
+		reader:              clientReader{client: client, timeout: config.RequestTimeout, policy: config.RetryPolicy, destID: destID},
+		params:              params,
 		store:               store,
+		checkpoint:          checkpoint,
 		done:                true,
 	}
 
-	err = fisco.storeChainParams()
+	// the notifications are only pushed over the channel connection
+	if config.SubscribeBlocks {
+		if config.IsHTTP {
+			logging.WithChain(destID).Warn("block subscription unsupported over rpc, polling instead")
+		} else {
+			fisco.subscriber = channelHeadSubscriber{client: client}
+		}
+	}
+
+	return fisco
+}
+
+// dial connects to the FISCO node and instantiates the iService Core Extension contract
+// The forwarder returned is nil unless connected over rpc
+func dial(config Config) (*fiscoclient.Client, *iservice.IServiceCoreEx, *common.Forwarder, error) {
+	clientConfig := BuildClientConfig(config)
+
+	// the SDK HTTP client takes no transport options, which are applied by forwarding
+	var forwarder *common.Forwarder
+	if config.IsHTTP {
+		var err error
+
+		forwarder, err = common.ForwardRPC(clientConfig.NodeURL, config.Transport)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to connect to fisco node: %s", err)
+		}
+
+		clientConfig.NodeURL = forwarder.Endpoint
+	}
+
+	client, err := fiscoclient.Dial(clientConfig)
 	if err != nil {
-		return nil, err
+		closeForwarder(forwarder)
+		return nil, nil, nil, fmt.Errorf("failed to connect to fisco node: %s", err)
 	}
 
-	err = fisco.storeChainID()
+	iServiceCore, err := iservice.NewIServiceCoreEx(ethcmn.HexToAddress(config.IServiceCoreAddr), client)
 	if err != nil {
-		return nil, err
+		client.Close()
+		closeForwarder(forwarder)
+		return nil, nil, nil, fmt.Errorf("failed to instantiate the iService Core Extension contract: %s", err)
 	}
 
-	store.GetInt64(HeightKey(chainID))
+	return client, iServiceCore, forwarder, nil
+}
 
-	return fisco, nil
+// closeForwarder closes the given forwarder, if any
+func closeForwarder(forwarder *common.Forwarder) {
+	if forwarder != nil {
+		_ = forwarder.Close()
+	}
 }
 
-// BuildFISCOChain builds a FISCOChain instance from the given chain params and store
+// BuildFISCOChain builds a FISCOChain instance from the given chain params, store, checkpoint and key store
 func BuildFISCOChain(
 	chainParams []byte,
 	store *store.Store,
+	checkpoint store.Checkpoint,
+	keyStore keystore.KeyStore,
 ) (*FISCOChain, error) {
 	var params ChainParams
 	err := json.Unmarshal(chainParams, &params)
@@ -118,7 +253,95 @@ func BuildFISCOChain(
 		ChainParams: params,
 	}
 
-	return NewFISCOChain(config, store)
+	return NewFISCOChain(config, store, checkpoint, keyStore)
+}
+
+// Reload implements core.ReloadableAppChain
+// The chain is rebuilt on the given base config, reusing the connection unless the
+// endpoints changed. The signing key and submission limits only change on restart
+func (f *FISCOChain) Reload(baseConfig []byte) (core.AppChainI, error) {
+	var base BaseConfig
+	if err := json.Unmarshal(baseConfig, &base); err != nil {
+		return nil, err
+	}
+
+	base.PrivateKey = f.Config.PrivateKey
+	base.IsSMCrypto = f.Config.IsSMCrypto
+	base.SubmitLimits = f.Config.SubmitLimits
+
+	if base.MonitorInterval == 0 {
+		base.MonitorInterval = DefaultMonitorInterval
+	}
+
+	config := Config{BaseConfig: base, ChainParams: f.params}.withOverrides(f.DestID)
+	if reflect.DeepEqual(config, f.Config) {
+		return f, nil
+	}
+
+	if config.ConfirmationDepth < 0 {
+		return nil, fmt.Errorf("invalid chain params: negative confirmation depth %d", config.ConfirmationDepth)
+	}
+
+	client := f.Client
+	iServiceCore := f.IServiceCoreSession.Contract
+	forwarder := f.forwarder
+
+	if endpointsChanged(f.Config, config) {
+		var err error
+
+		client, iServiceCore, forwarder, err = dial(config)
+		if err != nil {
+			return nil, err
+		}
+
+		logging.WithChain(f.DestID).Infof("endpoints changed, reconnected to %v", config.nodeURLs())
+	}
+
+	chain := newFISCOChain(config, f.params, f.DestID, client, iServiceCore, f.IServiceCoreABI, f.Sequencer, f.Signer, f.store, f.checkpoint)
+	chain.forwarder = forwarder
+
+	return chain, nil
+}
+
+// TakeOver implements core.ReloadableAppChain
+// The recent block hashes are taken over too, so that a reorg across the reload is detected
+func (f *FISCOChain) TakeOver(previous core.AppChainI) {
+	f.setHeight(previous.GetHeight())
+
+	if chain, ok := previous.(*FISCOChain); ok {
+		f.lastHash = chain.lastHash
+		f.hashes = chain.hashes
+	}
+}
+
+// Release implements core.ReloadableAppChain
+// The client not taken over by the other chain is closed once the calls in flight on it return,
+// along with its forwarder
+func (f *FISCOChain) Release(other core.AppChainI) {
+	if chain, ok := other.(*FISCOChain); ok && chain.Client == f.Client {
+		return
+	}
+
+	go func() {
+		f.callsMtx.Lock()
+		defer f.callsMtx.Unlock()
+
+		f.Client.Close()
+		closeForwarder(f.forwarder)
+
+		logging.WithChain(f.DestID).Info("superseded connection closed")
+	}()
+}
+
+// endpointsChanged returns true if the new config requires a new connection
+func endpointsChanged(old Config, new Config) bool {
+	return old.IsHTTP != new.IsHTTP ||
+		old.CAFile != new.CAFile ||
+		old.CertFile != new.CertFile ||
+		old.KeyFile != new.KeyFile ||
+		old.BaseConfig.ChainId != new.BaseConfig.ChainId ||
+		!reflect.DeepEqual(old.Transport, new.Transport) ||
+		!reflect.DeepEqual(old.nodeURLs(), new.nodeURLs())
 }
 
 // GetChainID implements AppChainI
@@ -126,16 +349,63 @@ func (f *FISCOChain) GetChainID() string {
 	return f.ChainID
 }
 
+// GetDestID implements AppChainI
+func (f *FISCOChain) GetDestID() common.DestID {
+	return f.DestID
+}
+
+// CheckConnection implements AppChainI
+func (f *FISCOChain) CheckConnection(ctx context.Context) error {
+	f.callsMtx.RLock()
+	defer f.callsMtx.RUnlock()
+
+	if _, err := f.reader.GetBlockNumber(ctx); err != nil {
+		return fmt.Errorf("failed to connect to chain %s: %s", f.DestID, err)
+	}
+
+	return nil
+}
+
+// SetEventQueue implements core.QueueListener
+func (f *FISCOChain) SetEventQueue(queue *core.EventQueue) {
+	f.queue = queue
+}
+
 // Start implements AppChainI
 func (f *FISCOChain) Start(handler core.InterchainRequestHandler) error {
+	f.runMtx.Lock()
+	defer f.runMtx.Unlock()
+
 	if !f.done {
 		return fmt.Errorf("chain %s has been started", f.ChainID)
 	}
 
+	// resume from the checkpoint, which may be advanced by the chain this one replaces
+	if err := f.loadHeight(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the monitor is given one staleness threshold to see a new block
+	f.livenessMtx.Lock()
+	f.liveness = core.ChainLiveness{Connected: true, LastHeight: f.GetHeight(), LastSeenAt: time.Now()}
+	f.livenessMtx.Unlock()
+
 	f.done = false
+	f.cancel = cancel
+	f.stopped = make(chan struct{})
 	f.handler = handler
 
-	go f.monitor()
+	// a panic while monitoring is recovered, and the monitor restarted from the last handled height
+	go func() {
+		defer close(f.stopped)
+
+		_ = core.Supervise(ctx, f.DestID.String(), f.Config.RetryPolicy, func(ctx context.Context) error {
+			f.monitor(ctx)
+			return nil
+		})
+	}()
 
 	logging.Logger.Infof("chain %s started", f.ChainID)
 
@@ -143,46 +413,232 @@ func (f *FISCOChain) Start(handler core.InterchainRequestHandler) error {
 }
 
 // Stop implements AppChainI
+// It blocks until the block being scanned is handled and checkpointed
 func (f *FISCOChain) Stop() error {
 	logging.Logger.Infof("stopping chain %s", f.ChainID)
+
+	f.runMtx.Lock()
+	defer f.runMtx.Unlock()
+
+	if f.done {
+		return nil
+	}
+
 	f.done = true
+	f.cancel()
+	<-f.stopped
+
+	logging.Logger.Infof("chain %s stopped at height %d", f.ChainID, f.GetHeight())
 
 	return nil
 }
 
 // GetHeight implements AppChainI
 func (f *FISCOChain) GetHeight() int64 {
-	return f.lastHeight
+	return atomic.LoadInt64(&f.lastHeight)
+}
+
+// setHeight sets the last handled height
+func (f *FISCOChain) setHeight(height int64) {
+	atomic.StoreInt64(&f.lastHeight, height)
 }
 
 // SendResponse implements AppChainI
-func (f *FISCOChain) SendResponse(requestID string, response core.ResponseI) error {
+func (f *FISCOChain) SendResponse(ctx context.Context, requestID string, response core.ResponseI) (string, error) {
+	f.callsMtx.RLock()
+	defer f.callsMtx.RUnlock()
+
+	tx, err := f.submitResponse(ctx, requestID, response)
+	if err != nil || tx == nil {
+		return "", err
+	}
+
+	err = f.waitForReceipt(ctx, tx, "SetResponse")
+	if err != nil {
+		return tx.Hash().Hex(), err
+	}
+
+	// TODO
+	mysql.OnInterchainRequestSucceeded(requestID)
+
+	return tx.Hash().Hex(), nil
+}
+
+// SubmitResponse implements core.AsyncResponseSender
+func (f *FISCOChain) SubmitResponse(ctx context.Context, requestID string, response core.ResponseI) (string, error) {
+	f.callsMtx.RLock()
+	defer f.callsMtx.RUnlock()
+
+	tx, err := f.submitResponse(ctx, requestID, response)
+	if err != nil || tx == nil {
+		return "", err
+	}
+
+	return tx.Hash().Hex(), nil
+}
+
+// ConfirmResponse implements core.AsyncResponseSender
+// A single receipt query is made, the watcher polling again on failure
+func (f *FISCOChain) ConfirmResponse(ctx context.Context, requestID string, txHash string) (bool, error) {
+	f.callsMtx.RLock()
+	defer f.callsMtx.RUnlock()
+
+	callCtx, cancel := common.WithTimeout(ctx, f.Config.RequestTimeout)
+	defer cancel()
+
+	// the receipt query fails until the tx is on chain
+	receipt, err := f.Client.GetTransactionReceipt(callCtx, txHash)
+	if err != nil || receipt == nil {
+		logging.WithChain(f.DestID).WithField(logging.FieldTxHash, txHash).Debugf("response transaction not confirmed yet: %v", err)
+		return false, nil
+	}
+
+	if receipt.Status != types.Success {
+		err := fmt.Errorf("transaction %s execution failed: %s", txHash, receipt.GetErrorMessage())
+		mysql.TxErrCollection(requestID, err.Error())
+
+		return false, err
+	}
+
+	logging.Logger.Infof("SetResponse: transaction %s execution succeeded", txHash)
+
+	// TODO
+	mysql.OnInterchainRequestSucceeded(requestID)
+
+	return true, nil
+}
+
+// submitResponse broadcasts the response tx without waiting for its receipt
+// No tx is returned if the simulation finds the response already on chain
+func (f *FISCOChain) submitResponse(ctx context.Context, requestID string, response core.ResponseI) (*types.Transaction, error) {
 	requestIDBytes, err := hex.DecodeString(requestID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var requestID32Bytes [32]byte
 	copy(requestID32Bytes[:], requestIDBytes)
 
-	tx, _, err := f.IServiceCoreSession.SetResponse(requestID32Bytes, response.GetErrMsg(), response.GetOutput())
+	// the response which would revert is rejected before paying for the tx
+	if f.Config.SimulateResponse {
+		err := f.callSetResponse(ctx, requestID32Bytes, response.GetErrMsg(), response.GetOutput())
+
+		if reason, reverted := revertReason(err); reverted {
+			logger := logging.WithChain(f.DestID).WithField(logging.FieldRequestID, requestID)
+
+			if strings.Contains(reason, errDuplicatedResponse) {
+				logger.Infof("response already on chain, skipped")
+				return nil, nil
+			}
+
+			logger.Warnf("simulated response transaction reverted: %s", reason)
+			mysql.TxErrCollection(requestID, reason)
+
+			return nil, fmt.Errorf("response transaction would revert: %s", reason)
+		} else if err != nil {
+			logging.WithChain(f.DestID).WithField(logging.FieldRequestID, requestID).Warnf("failed to simulate the response transaction, sending it: %s", err)
+		}
+	}
+
+	var tx *types.Transaction
+
+	err = common.Retry(ctx, func() error {
+		var err error
+
+		// the FISCO txs carry random nonces, so no sequence is tracked
+		err = f.Sequencer.Submit(ctx, f.IServiceCoreSession.TransactOpts.From.Hex(), func(uint64) (func() error, error) {
+			callCtx, cancel := common.WithTimeout(ctx, f.Config.RequestTimeout)
+			defer cancel()
+
+			opts := f.IServiceCoreSession.TransactOpts
+			opts.Context = callCtx
+
+			// the binding builds the tx right before signing it
+			sign := opts.Signer
+			opts.Signer = func(s types.Signer, address ethcmn.Address, tx *types.Transaction) (*types.Transaction, error) {
+				core.MarkMilestone(ctx, core.MilestoneBuilt)
+				defer core.MarkMilestone(ctx, core.MilestoneSigned)
+
+				return sign(s, address, tx)
+			}
+
+			var err error
+			tx, _, err = f.IServiceCoreSession.Contract.SetResponse(&opts, requestID32Bytes, response.GetErrMsg(), response.GetOutput())
+			if err == nil {
+				core.MarkMilestone(ctx, core.MilestoneBroadcast)
+			}
+
+			return nil, err
+		})
+		if err != nil {
+			logging.WithChain(f.DestID).WithField(logging.FieldRequestID, requestID).Warnf("failed to submit the response transaction: %s", err)
+		}
+
+		return err
+	}, f.Config.RetryPolicy)
 	if err != nil {
 		mysql.TxErrCollection(requestID, err.Error())
-		return err
+		return nil, err
 	}
 
+	logging.WithChain(f.DestID).WithFields(log.Fields{
+		logging.FieldRequestID: requestID,
+		logging.FieldTxHash:    tx.Hash().Hex(),
+		logging.FieldStage:     logging.StageTxSubmitted,
+	}).Info("response transaction submitted")
+
 	// TODO
 	mysql.OnInterchainRequestResponseSent(requestID, tx.Hash().Hex())
 
-	err = f.waitForReceipt(tx, "SetResponse")
+	return tx, nil
+}
+
+// ResponseCheckEnabled implements core.ResponseChecker
+func (f *FISCOChain) ResponseCheckEnabled() bool {
+	return f.Config.CheckResponse
+}
+
+// ResponseExists implements core.ResponseChecker
+// The iService Core Extension keeps no public record of the responses, so setResponse is
+// simulated from the relayer account, which is rejected if the response exists
+func (f *FISCOChain) ResponseExists(ctx context.Context, requestID string) (bool, error) {
+	f.callsMtx.RLock()
+	defer f.callsMtx.RUnlock()
+
+	requestIDBytes, err := hex.DecodeString(requestID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// TODO
-	mysql.OnInterchainRequestSucceeded(requestID)
+	var requestID32Bytes [32]byte
+	copy(requestID32Bytes[:], requestIDBytes)
 
-	return nil
+	err = f.callSetResponse(ctx, requestID32Bytes, "", "")
+	if err == nil {
+		return false, nil
+	}
+
+	if strings.Contains(err.Error(), errDuplicatedResponse) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// callSetResponse simulates setResponse from the relayer account without broadcasting it
+func (f *FISCOChain) callSetResponse(ctx context.Context, requestID [32]byte, errMsg string, output string) error {
+	callCtx, cancel := common.WithTimeout(ctx, f.Config.RequestTimeout)
+	defer cancel()
+
+	opts := f.IServiceCoreSession.CallOpts
+	opts.From = f.IServiceCoreSession.TransactOpts.From
+	opts.Context = callCtx
+
+	var success bool
+
+	raw := iservice.IServiceCoreExRaw{Contract: f.IServiceCoreSession.Contract}
+
+	return raw.Call(&opts, &success, "setResponse", requestID, errMsg, output)
 }
 
 // buildInterchainRequest builds an interchain request from the interchain event
@@ -206,11 +662,17 @@ func (f *FISCOChain) buildInterchainRequest(e *iservice.IServiceCoreExCrossChain
 	}
 }
 
-// waitForReceipt waits for the receipt of the given tx
-func (f *FISCOChain) waitForReceipt(tx *types.Transaction, name string) error {
+// waitForReceipt waits for the receipt of the given tx within the request timeout
+func (f *FISCOChain) waitForReceipt(ctx context.Context, tx *types.Transaction, name string) error {
 	logging.Logger.Infof("%s: transaction sent to %s, hash: %s", name, f.GetChainID(), tx.Hash().Hex())
 
-	receipt, err := f.Client.WaitMined(tx)
+	submittedAt := time.Now()
+
+	waitCtx, cancel := common.WithTimeout(ctx, f.Config.RequestTimeout)
+	defer cancel()
+
+	receipt, err := bind.WaitMined(waitCtx, f.Client, tx)
+	metrics.TxConfirmationTime.WithLabelValues(f.DestID.Normalize().String()).Observe(time.Since(submittedAt).Seconds())
 	if err != nil {
 		return fmt.Errorf("failed to mint the transaction %s: %s", tx.Hash().Hex(), err)
 	}
@@ -219,115 +681,322 @@ func (f *FISCOChain) waitForReceipt(tx *types.Transaction, name string) error {
 		return fmt.Errorf("transaction %s execution failed: %s", tx.Hash().Hex(), receipt.GetErrorMessage())
 	}
 
+	core.MarkMilestone(ctx, core.MilestoneConfirmed)
+
 	logging.Logger.Infof("%s: transaction %s execution succeeded", name, tx.Hash().Hex())
 
 	return nil
 }
 
-// monitor is responsible for monitoring the chain
-func (f *FISCOChain) monitor() {
+// monitor is responsible for monitoring the chain until the context is done
+// The chain is polled, and also scanned on each new block notified if subscribed to the
+// block numbers; the polls catch up with the notifications missed, and detect the dropped
+// subscriptions, the blocks missed being scanned from the last handled height on renewal
+func (f *FISCOChain) monitor(ctx context.Context) {
+	schedule := common.PollSchedule{
+		Interval: time.Duration(f.Config.MonitorInterval) * time.Second,
+		Jitter:   f.Config.PollJitter,
+	}
+
+	var (
+		subscription *headSubscription
+		notified     <-chan int64
+		resubscribed <-chan struct{}
+	)
+
+	subscribed := false
+
+	if f.subscriber != nil {
+		subscription = newHeadSubscription(f.subscriber, f.DestID, f.Config.RetryPolicy)
+		notified, resubscribed = subscription.heights, subscription.resubscribed
+
+		subscribed = subscription.start(ctx)
+	}
+
+	// the listeners started together are staggered over the interval, unless notified
+	if !subscribed {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(schedule.StartDelay()):
+		}
+	}
+
+	var (
+		lastPoll time.Time
+		height   int64 // notified height to scan to, polled if zero
+	)
+
 	for {
-		f.scan()
+		if !f.awaitQueue(ctx) {
+			return
+		}
+
+		if height != 0 {
+			f.setLiveness(true, height)
+			f.scanTo(ctx, height)
+		} else {
+			if !lastPoll.IsZero() {
+				metrics.PollInterval.WithLabelValues(f.DestID.String()).Observe(time.Since(lastPoll).Seconds())
+			}
+
+			lastPoll = time.Now()
+			metrics.ChainPolls.WithLabelValues(f.DestID.String()).Inc()
+
+			currentHeight := f.scan(ctx)
+
+			if subscription != nil && subscription.check(currentHeight) {
+				logging.WithChain(f.DestID).Warnf("chain advanced to height %d with no block notified, resubscribing", currentHeight)
+			}
+		}
+
+		timer := time.NewTimer(schedule.Next())
 
-		if f.done {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
 			return
+
+		case <-timer.C:
+			height = 0
+
+		case height = <-notified:
+			timer.Stop()
+
+		case <-resubscribed:
+			timer.Stop()
+			height = 0
 		}
+	}
+}
+
+// awaitQueue blocks while the event queue is full, returning false if the context is done
+// The scanning resumes from the last handled height once the queue drains
+func (f *FISCOChain) awaitQueue(ctx context.Context) bool {
+	if f.queue == nil || !f.queue.Paused() {
+		return true
+	}
+
+	logging.WithChain(f.DestID).Infof("listener paused after height %d, event queue full", f.GetHeight())
+
+	if err := f.queue.Wait(ctx); err != nil {
+		return false
+	}
 
-		time.Sleep(time.Duration(f.Config.MonitorInterval) * time.Second)
+	logging.WithChain(f.DestID).Infof("listener resumed from height %d", f.GetHeight()+1)
+
+	return true
+}
+
+// GetLiveness implements AppChainI
+func (f *FISCOChain) GetLiveness() core.ChainLiveness {
+	f.livenessMtx.Lock()
+	defer f.livenessMtx.Unlock()
+
+	return f.liveness
+}
+
+// setLiveness records the connectivity and the latest height seen
+// The seen time only advances on a new height
+func (f *FISCOChain) setLiveness(connected bool, height int64) {
+	f.livenessMtx.Lock()
+	defer f.livenessMtx.Unlock()
+
+	f.liveness.Connected = connected
+
+	if height > f.liveness.LastHeight {
+		f.liveness.LastHeight = height
+		f.liveness.LastSeenAt = time.Now()
 	}
 }
 
-// scan performs chain scanning
-func (f *FISCOChain) scan() {
-	currentHeight, err := f.getBlockNumber()
+// scan performs chain scanning, returning the current height, 0 if it failed to be read
+func (f *FISCOChain) scan(ctx context.Context) int64 {
+	currentHeight, err := f.reader.GetBlockNumber(ctx)
 	if err != nil {
+		f.setLiveness(false, 0)
 		logging.Logger.Errorf("failed to get the current block height: %s", err)
-		return
+		return 0
 	}
 
-	if f.lastHeight == 0 {
-		f.lastHeight = currentHeight - 1
+	f.setLiveness(true, currentHeight)
+
+	f.scanTo(ctx, currentHeight)
+
+	return currentHeight
+}
+
+// scanTo scans the blocks up to the given current height
+func (f *FISCOChain) scanTo(ctx context.Context, currentHeight int64) {
+	// only the blocks buried under the confirmation depth are consumed, so the
+	// events of the blocks reorged out within the depth are never relayed
+	confirmedHeight := currentHeight - f.Config.ConfirmationDepth
+
+	lastHeight := f.GetHeight()
+
+	if lastHeight == 0 && confirmedHeight > 0 {
+		lastHeight = confirmedHeight - 1
+		f.setHeight(lastHeight)
 	}
 
-	if currentHeight <= f.lastHeight {
+	if confirmedHeight <= lastHeight {
 		return
 	}
 
-	f.scanBlocks(f.lastHeight+1, currentHeight)
+	f.scanBlocks(ctx, lastHeight+1, confirmedHeight)
 }
 
 // scanBlocks scans the blocks of the specified range
-func (f *FISCOChain) scanBlocks(startHeight int64, endHeight int64) {
-	for h := startHeight; h <= endHeight; {
-		block, err := f.getBlock(h)
+// The checkpoint advances only after all events in a block are handled,
+// and the context is only checked between blocks
+func (f *FISCOChain) scanBlocks(ctx context.Context, startHeight int64, endHeight int64) {
+	for h := startHeight; h <= endHeight; h++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		block, err := f.reader.GetBlock(ctx, h)
 		if err != nil {
-			logging.Logger.Errorf(err.Error())
+			logging.WithChain(f.DestID).Errorf(err.Error())
+			return
+		}
+
+		// the blocks of the abandoned branch are scanned again from the fork point
+		if len(f.lastHash) > 0 && len(block.ParentHash) > 0 && block.ParentHash != f.lastHash {
+			logging.WithChain(f.DestID).Warnf(
+				"block %d reorged out beyond the confirmation depth %d, expected parent %s, got %s",
+				h-1, f.Config.ConfirmationDepth, f.lastHash, block.ParentHash,
+			)
+
+			fork, err := f.rewind(ctx, h-1)
+			if err != nil {
+				logging.WithChain(f.DestID).Errorf("failed to rewind to the fork point: %s", err)
+				return
+			}
+
+			h = fork
 			continue
 		}
 
-		f.parseInterchainEventsFromBlock(block)
+		receipts, err := f.getReceipts(ctx, block)
+		if err != nil {
+			logging.WithChain(f.DestID).Errorf("failed to get the receipts, height: %d, err: %s", h, err)
+			return
+		}
+
+		for _, receipt := range receipts {
+			if err := f.parseCrossChaiRequestSentEvents(receipt, block.Time()); err != nil {
+				logging.WithChain(f.DestID).Warnf("scanning paused at height %d: %s", h, err)
+				return
+			}
+		}
 
 		err = f.updateHeight(h)
 		if err != nil {
-			logging.Logger.Errorf("failed to update height: %s", err)
+			logging.WithChain(f.DestID).Errorf("failed to update height: %s", err)
+			return
+		}
+
+		f.lastHash = block.Hash
+
+		if f.hashes == nil {
+			f.hashes = make(map[int64]string)
 		}
 
-		h++
+		f.hashes[h] = block.Hash
+		delete(f.hashes, h-maxReorgDepth)
 	}
 }
 
-// getBlockNumber retrieves the current block number
-func (f *FISCOChain) getBlockNumber() (int64, error) {
-	blockNumber, err := f.Client.GetBlockNumber(context.Background())
-	if err != nil {
-		return -1, err
-	}
+// rewind moves the checkpoint back to the fork point of the reorg which replaced the block
+// at the given height, i.e. the highest handled block still on the canonical chain, and
+// returns its height. Without a block in common within maxReorgDepth, the checkpoint
+// moves below the recent blocks, the first of which is then scanned unchecked
+func (f *FISCOChain) rewind(ctx context.Context, height int64) (int64, error) {
+	fork := height
+
+	for ; fork > 0; fork-- {
+		hash, ok := f.hashes[fork]
+		if !ok {
+			break
+		}
 
-	blockNumberStr := string(blockNumber)
+		block, err := f.reader.GetBlock(ctx, fork)
+		if err != nil {
+			return 0, err
+		}
 
-	return common.Hex2Decimal(blockNumberStr[3 : len(blockNumberStr)-1])
-}
+		if block.Hash == hash {
+			break
+		}
 
-// getBlock gets the block in the given height
-func (f *FISCOChain) getBlock(height int64) (block CompactBlock, err error) {
-	blockBz, err := f.Client.GetBlockByNumber(context.Background(), fmt.Sprintf("0x%x", height), false)
-	if err != nil {
-		return block, fmt.Errorf("failed to retrieve the block, height: %d, err: %s", height, err)
+		delete(f.hashes, fork)
 	}
 
-	err = json.Unmarshal(blockBz, &block)
-	if err != nil {
-		return block, fmt.Errorf("failed to unmarshal the block, height: %d, err: %s", height, err)
+	if err := f.updateHeight(fork); err != nil {
+		return 0, err
 	}
 
-	return
+	f.lastHash = f.hashes[fork]
+
+	logging.WithChain(f.DestID).Warnf("rewound to the fork point %d, scanning the blocks reorged in", fork)
+
+	return fork, nil
 }
 
-// parseInterchainEventsFromBlock parses the interchain events from the block
-func (f *FISCOChain) parseInterchainEventsFromBlock(block CompactBlock) {
+// getReceipts retrieves the successful receipts of the txs in the block
+func (f *FISCOChain) getReceipts(ctx context.Context, block CompactBlock) ([]*types.Receipt, error) {
+	receipts := make([]*types.Receipt, 0, len(block.Txs))
+
 	for _, txHash := range block.Txs {
-		receipt, err := f.Client.GetTransactionReceipt(context.Background(), txHash)
+		receipt, err := f.reader.GetReceipt(ctx, txHash)
 		if err != nil {
-			logging.Logger.Errorf("failed to get the receipt, tx: %s, err: %s", txHash, err)
-			continue
+			return nil, fmt.Errorf("tx: %s, err: %s", txHash, err)
 		}
 
 		if receipt.Status != types.Success {
 			continue
 		}
 
-		f.parseCrossChaiRequestSentEvents(receipt)
+		receipts = append(receipts, receipt)
 	}
+
+	return receipts, nil
 }
 
 // parseServiceInvokedEvents parses the ServiceInvoked events from the receipt
-func (f *FISCOChain) parseCrossChaiRequestSentEvents(receipt *types.Receipt) {
-	for _, log := range receipt.Logs {
-		if !strings.EqualFold(log.Address, f.Config.IServiceCoreAddr) {
+// An error is returned unless the handler handled or rejected the events for good, e.g.
+// deferred them by an open circuit, the block being scanned again later and the events
+// already handled deduplicated
+func (f *FISCOChain) parseCrossChaiRequestSentEvents(receipt *types.Receipt, emittedAt time.Time) error {
+	for _, request := range f.parseRequests(receipt) {
+		request.EmittedAt = emittedAt
+
+		logging.WithChain(f.DestID).WithFields(log.Fields{
+			logging.FieldRequestID: request.ID,
+			logging.FieldTxHash:    receipt.TransactionHash,
+			logging.FieldStage:     logging.StageEventReceived,
+		}).Info("interchain event received")
+
+		if err := f.handler(f.ChainID, request, receipt.TransactionHash); err != nil && !core.IsRejected(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseRequests builds the interchain requests of the CrossChainRequestSent events in the receipt
+func (f *FISCOChain) parseRequests(receipt *types.Receipt) []core.InterchainRequest {
+	var requests []core.InterchainRequest
+
+	for _, eventLog := range receipt.Logs {
+		if !strings.EqualFold(eventLog.Address, f.Config.IServiceCoreAddr) {
 			continue
 		}
 
-		data, err := hex.DecodeString(log.Data[2:])
+		data, err := hex.DecodeString(eventLog.Data[2:])
 		if err != nil {
 			logging.Logger.Errorf("failed to decode the log data: %s", err)
 			continue
@@ -340,14 +1009,60 @@ func (f *FISCOChain) parseCrossChaiRequestSentEvents(receipt *types.Receipt) {
 			continue
 		}
 
-		request := f.buildInterchainRequest(&event)
-		f.handler(f.ChainID, request, receipt.TransactionHash)
+		requests = append(requests, f.buildInterchainRequest(&event))
+	}
+
+	return requests
+}
+
+// ScanRange implements core.RangeScanner
+// The blocks are read regardless of the monitor, neither checking the parents nor advancing the checkpoint
+func (f *FISCOChain) ScanRange(ctx context.Context, startHeight int64, endHeight int64, fn func(request core.InterchainRequest, txHash string) error) error {
+	for h := startHeight; h <= endHeight; h++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		receipts, err := f.readReceipts(ctx, h)
+		if err != nil {
+			return err
+		}
+
+		for _, receipt := range receipts {
+			for _, request := range f.parseRequests(receipt) {
+				if err := fn(request, receipt.TransactionHash); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// readReceipts reads the successful receipts of the block at the given height
+// callsMtx is only held for the reads, as the ScanRange callback calls the client again
+// and a recursive read lock would deadlock with a waiting Reload.
+func (f *FISCOChain) readReceipts(ctx context.Context, height int64) ([]*types.Receipt, error) {
+	f.callsMtx.RLock()
+	defer f.callsMtx.RUnlock()
+
+	block, err := f.reader.GetBlock(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, err := f.getReceipts(ctx, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the receipts, height: %d, err: %s", height, err)
 	}
+
+	return receipts, nil
 }
 
 // storeChainParams stores the chain params
 func (f *FISCOChain) storeChainParams() error {
-	bz, err := json.Marshal(f.Config.ChainParams)
+	bz, err := json.Marshal(f.params)
 	if err != nil {
 		return err
 	}
@@ -370,8 +1085,32 @@ func (f *FISCOChain) storeChainID() error {
 	return f.store.Set([]byte("chainIDs"), bz)
 }
 
-// updateHeight updates the height
+// loadHeight loads the last handled height from the checkpoint
+func (f *FISCOChain) loadHeight() error {
+	height, err := f.checkpoint.Load(f.DestID)
+	if err != nil {
+		return fmt.Errorf("failed to load the checkpoint: %s", err)
+	}
+
+	// fall back to the height stored by the previous versions
+	if height == 0 && f.store != nil {
+		if legacyHeight, err := f.store.GetInt64(HeightKey(f.ChainID)); err == nil {
+			height = legacyHeight
+		}
+	}
+
+	f.setHeight(height)
+
+	return nil
+}
+
+// updateHeight updates the height and advances the checkpoint
 func (f *FISCOChain) updateHeight(height int64) error {
-	f.lastHeight = height
-	return f.store.SetInt64(HeightKey(f.ChainID), height)
+	if err := f.checkpoint.Save(f.DestID, height); err != nil {
+		return err
+	}
+
+	f.setHeight(height)
+
+	return nil
 }
@@ -0,0 +1,607 @@
+package fisco
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FISCO-BCOS/go-sdk/abi"
+	"github.com/FISCO-BCOS/go-sdk/abi/bind"
+	"github.com/FISCO-BCOS/go-sdk/core/types"
+	ethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"relayer/appchains/fisco/iservice"
+	"relayer/common"
+	"relayer/core"
+	"relayer/keystore"
+	"relayer/store"
+)
+
+const testIServiceCoreAddr = "0x7f7a0b9b1e4c2b6a2e5c4f9b0d8e3a1f6c5b4a39"
+
+// mockChainReader is a ChainReader with in-memory blocks
+type mockChainReader struct {
+	mtx      sync.Mutex
+	blocks   map[int64]CompactBlock
+	receipts map[string]*types.Receipt
+	height   int64
+	fork     int // incremented on each reorg to produce distinct hashes
+}
+
+func newMockChainReader() *mockChainReader {
+	return &mockChainReader{
+		blocks:   map[int64]CompactBlock{},
+		receipts: map[string]*types.Receipt{},
+	}
+}
+
+func (r *mockChainReader) GetBlockNumber(ctx context.Context) (int64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.height, nil
+}
+
+func (r *mockChainReader) GetBlock(ctx context.Context, height int64) (CompactBlock, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	block, ok := r.blocks[height]
+	if !ok {
+		return block, fmt.Errorf("block %d not found", height)
+	}
+
+	return block, nil
+}
+
+func (r *mockChainReader) GetReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	receipt, ok := r.receipts[txHash]
+	if !ok {
+		return nil, fmt.Errorf("receipt %s not found", txHash)
+	}
+
+	return receipt, nil
+}
+
+// addBlock appends a block with one interchain request per given request ID
+func (r *mockChainReader) addBlock(t *testing.T, coreABI abi.ABI, requestIDs ...string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.height++
+
+	block := CompactBlock{
+		Hash:       r.blockHash(r.height),
+		ParentHash: r.blockHash(r.height - 1),
+	}
+
+	for _, id := range requestIDs {
+		var requestID [32]byte
+		copy(requestID[:], id)
+
+		data, err := coreABI.Events["CrossChainRequestSent"].Inputs.Pack(
+			requestID,
+			`{"dest_chain_id":"1","dest_chain_type":"eth"}`,
+			"hello",
+			[]byte("{}"),
+			ethcmn.HexToAddress(testIServiceCoreAddr),
+		)
+		require.NoError(t, err)
+
+		txHash := fmt.Sprintf("0x%d%d%s", r.fork, r.height, hex.EncodeToString([]byte(id)))
+
+		r.receipts[txHash] = &types.Receipt{
+			TransactionHash: txHash,
+			Status:          types.Success,
+			Logs: []*types.NewLog{{
+				Address: testIServiceCoreAddr,
+				Data:    "0x" + hex.EncodeToString(data),
+			}},
+		}
+
+		block.Txs = append(block.Txs, txHash)
+	}
+
+	r.blocks[r.height] = block
+}
+
+// reorg drops the blocks from the given height so that they can be replaced
+func (r *mockChainReader) reorg(height int64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for h := height; h <= r.height; h++ {
+		delete(r.blocks, h)
+	}
+
+	r.height = height - 1
+	r.fork++
+}
+
+// blockHash returns the hash of the block in the given height on the current fork
+func (r *mockChainReader) blockHash(height int64) string {
+	if block, ok := r.blocks[height]; ok {
+		return block.Hash
+	}
+
+	return fmt.Sprintf("0x%d-%d", height, r.fork)
+}
+
+// testRequestID returns the request ID built from the given ID in the interchain event
+func testRequestID(id string) string {
+	var requestID [32]byte
+	copy(requestID[:], id)
+
+	return hex.EncodeToString(requestID[:])
+}
+
+// newTestFISCOChain builds a FISCOChain monitoring the given reader
+func newTestFISCOChain(t *testing.T, reader ChainReader, checkpoint store.Checkpoint, handler core.InterchainRequestHandler) *FISCOChain {
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	params := ChainParams{GroupID: 1, ChainID: 5, IServiceCoreAddr: testIServiceCoreAddr}
+
+	destID, err := GetDestID(params)
+	require.NoError(t, err)
+
+	f := &FISCOChain{
+		Config:          Config{ChainParams: params},
+		ChainID:         GetChainID(params),
+		DestID:          destID,
+		IServiceCoreABI: coreABI,
+		reader:          reader,
+		params:          params,
+		checkpoint:      checkpoint,
+		handler:         handler,
+	}
+
+	require.NoError(t, f.loadHeight())
+
+	return f
+}
+
+func TestScanResumesFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := store.NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+
+	handled := map[string]int{}
+	handler := func(chainID string, request core.InterchainRequest, txHash string) error {
+		handled[request.ID]++
+		return nil
+	}
+
+	reader.addBlock(t, coreABI, "req-1")
+
+	chain := newTestFISCOChain(t, reader, checkpoint, handler)
+	chain.scan(context.Background())
+
+	reader.addBlock(t, coreABI, "req-2", "req-3")
+	reader.addBlock(t, coreABI)
+	chain.scan(context.Background())
+
+	// the listener is killed; events keep arriving while it is down
+	reader.addBlock(t, coreABI, "req-4")
+	reader.addBlock(t, coreABI, "req-5")
+
+	chain = newTestFISCOChain(t, reader, checkpoint, handler)
+	require.Equal(t, int64(3), chain.GetHeight())
+
+	chain.scan(context.Background())
+	chain.scan(context.Background())
+
+	require.Len(t, handled, 5)
+	for id, count := range handled {
+		require.Equal(t, 1, count, id)
+	}
+
+	height, err := checkpoint.Load(chain.DestID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), height)
+}
+
+func TestScanWithConfirmationDepth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := store.NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+
+	handled := map[string]int{}
+	handler := func(chainID string, request core.InterchainRequest, txHash string) error {
+		handled[request.ID]++
+		return nil
+	}
+
+	chain := newTestFISCOChain(t, reader, checkpoint, handler)
+	chain.Config.ConfirmationDepth = 2
+
+	reader.addBlock(t, coreABI, "req-1")
+	reader.addBlock(t, coreABI, "req-orphan")
+	reader.addBlock(t, coreABI)
+	chain.scan(context.Background())
+
+	require.Equal(t, int64(1), chain.GetHeight())
+
+	// the blocks 2 and 3 are reorged out before being confirmed
+	reader.reorg(2)
+	reader.addBlock(t, coreABI, "req-2")
+	reader.addBlock(t, coreABI)
+	reader.addBlock(t, coreABI)
+	reader.addBlock(t, coreABI)
+	chain.scan(context.Background())
+
+	require.Equal(t, int64(3), chain.GetHeight())
+
+	require.NotContains(t, handled, testRequestID("req-orphan"))
+	require.Equal(t, 1, handled[testRequestID("req-1")])
+	require.Equal(t, 1, handled[testRequestID("req-2")])
+}
+
+func TestScanRewindsOnReorg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := store.NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+
+	handled := map[string]int{}
+	handler := func(chainID string, request core.InterchainRequest, txHash string) error {
+		handled[request.ID]++
+		return nil
+	}
+
+	reader.addBlock(t, coreABI, "req-1")
+
+	chain := newTestFISCOChain(t, reader, checkpoint, handler)
+	chain.scan(context.Background())
+
+	reader.addBlock(t, coreABI)
+	reader.addBlock(t, coreABI, "req-2")
+	chain.scan(context.Background())
+
+	require.Equal(t, int64(3), chain.GetHeight())
+	require.Equal(t, 1, handled[testRequestID("req-2")])
+
+	// the blocks 2 and 3 are reorged out after being handled
+	reader.reorg(2)
+	reader.addBlock(t, coreABI, "req-3")
+	reader.addBlock(t, coreABI)
+	reader.addBlock(t, coreABI, "req-4")
+	chain.scan(context.Background())
+
+	// the blocks reorged in are scanned from the fork point at 1
+	require.Equal(t, int64(4), chain.GetHeight())
+	require.Equal(t, 1, handled[testRequestID("req-1")])
+	require.Equal(t, 1, handled[testRequestID("req-3")])
+	require.Equal(t, 1, handled[testRequestID("req-4")])
+
+	height, err := checkpoint.Load(chain.DestID)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), height)
+}
+
+func TestScanRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := store.NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI, "req-1")
+	reader.addBlock(t, coreABI, "req-2", "req-3")
+	reader.addBlock(t, coreABI)
+	reader.addBlock(t, coreABI, "req-4")
+
+	chain := newTestFISCOChain(t, reader, checkpoint, nil)
+
+	var found []string
+	err = chain.ScanRange(context.Background(), 2, 3, func(request core.InterchainRequest, txHash string) error {
+		found = append(found, request.ID)
+
+		// the client lock is released while the callback runs, letting a Reload through
+		locked := make(chan struct{})
+		go func() {
+			chain.callsMtx.Lock()
+			chain.callsMtx.Unlock()
+			close(locked)
+		}()
+
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Fatal("the client lock is held during the callback")
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{testRequestID("req-2"), testRequestID("req-3")}, found)
+
+	// the checkpoint is left untouched
+	height, err := checkpoint.Load(chain.DestID)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), height)
+
+	err = chain.ScanRange(context.Background(), 4, 5, func(request core.InterchainRequest, txHash string) error {
+		return nil
+	})
+	require.Error(t, err)
+}
+
+func TestConfigOverrides(t *testing.T) {
+	depth := int64(3)
+	checkResponse := true
+	subscribe := true
+	jitter := 0.3
+
+	config := Config{
+		BaseConfig: BaseConfig{
+			NodesMap:   map[string]string{"node1": "127.0.0.1:20200"},
+			PollJitter: 0.1,
+			ChainOverrides: map[string]ChainOverride{
+				"fisco-1-5": {SubscribeBlocks: &subscribe, ConfirmationDepth: &depth, CheckResponse: &checkResponse, PollJitter: &jitter},
+			},
+		},
+		ChainParams: ChainParams{NodeURLs: []string{"node1", "127.0.0.1:20201"}, ConfirmationDepth: 1},
+	}
+
+	overridden := config.withOverrides("fisco-1-5")
+	require.True(t, overridden.SubscribeBlocks)
+	require.Equal(t, int64(3), overridden.ConfirmationDepth)
+	require.True(t, overridden.CheckResponse)
+	require.False(t, config.CheckResponse)
+	require.Equal(t, 0.3, overridden.PollJitter)
+	require.True(t, config.withOverrides("FISCO-1-5").SubscribeBlocks)
+	require.Equal(t, int64(1), config.withOverrides("fisco-1-6").ConfirmationDepth)
+	require.Equal(t, 0.1, config.withOverrides("fisco-1-6").PollJitter)
+
+	// the transport options loaded are merged into the current ones
+	setTransports(map[string]ChainOverride{
+		"fisco-1-5": {TransportConfig: common.TransportConfig{Headers: common.Headers{"Authorization": "Bearer token"}}},
+	})
+	defer setTransports(nil)
+
+	transport := config
+	transport.Transport = common.TransportConfig{Headers: common.Headers{"X-Api-Key": "key"}, TLSCAFile: "ca.pem"}
+
+	require.Equal(t, common.TransportConfig{
+		Headers:   common.Headers{"X-Api-Key": "key", "Authorization": "Bearer token"},
+		TLSCAFile: "ca.pem",
+	}, transport.withOverrides("fisco-1-5").Transport)
+	require.Len(t, transport.Transport.Headers, 1)
+
+	require.Equal(t, []string{"127.0.0.1:20200", "127.0.0.1:20201"}, config.nodeURLs())
+
+	// only the resolved node addresses matter
+	renamed := config
+	renamed.NodesMap = map[string]string{"node1": "127.0.0.1:20200", "node2": "127.0.0.1:20202"}
+	require.False(t, endpointsChanged(config, renamed))
+
+	renamed.NodesMap = map[string]string{"node1": "127.0.0.1:20202"}
+	require.True(t, endpointsChanged(config, renamed))
+	require.False(t, endpointsChanged(config, overridden))
+}
+
+func TestTransportNotStored(t *testing.T) {
+	defer setTransports(nil)
+
+	v := viper.New()
+	v.Set("fisco.connection_type", "rpc")
+	v.Set("fisco.chains", map[string]interface{}{
+		"fisco-1-5": map[string]interface{}{
+			"confirmation_depth": 2,
+			"headers":            map[string]interface{}{"Authorization": "Bearer token"},
+		},
+	})
+
+	baseConfig, err := NewBaseConfig(v)
+	require.NoError(t, err)
+
+	// the headers are left out of the stored config, and rebuilt from the loaded one
+	bz, err := json.Marshal(baseConfig)
+	require.NoError(t, err)
+	require.NotContains(t, string(bz), "Bearer token")
+
+	var stored BaseConfig
+	require.NoError(t, json.Unmarshal(bz, &stored))
+
+	config := Config{BaseConfig: stored}.withOverrides("fisco-1-5")
+	require.Equal(t, int64(2), config.ConfirmationDepth)
+	require.Equal(t, "Bearer token", config.Transport.Headers["authorization"])
+
+	// the options are rejected on the channel connection, which takes none
+	v.Set("fisco.connection_type", "channel")
+	_, err = NewBaseConfig(v)
+	require.Error(t, err)
+}
+
+func TestReloadUnchanged(t *testing.T) {
+	chain := newTestFISCOChain(t, newMockChainReader(), store.NewMemCheckpoint(nil), nil)
+	chain.Config.MonitorInterval = DefaultMonitorInterval
+
+	bz, err := json.Marshal(chain.Config.BaseConfig)
+	require.NoError(t, err)
+
+	reloaded, err := chain.Reload(bz)
+	require.NoError(t, err)
+	require.True(t, reloaded == chain)
+}
+
+func TestReloadTakeOver(t *testing.T) {
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI)
+
+	chain, isHandled := newMonitoredChain(t, reader)
+	chain.Config.MonitorInterval = 1
+
+	require.NoError(t, chain.Start(chain.handler))
+
+	// the height is read while the monitor advances it
+	reader.addBlock(t, coreABI, "req-1")
+	require.Eventually(t, func() bool { return chain.GetHeight() == 2 }, 3*time.Second, time.Millisecond)
+	require.Eventually(t, isHandled("req-1"), time.Second, time.Millisecond)
+
+	require.NoError(t, chain.Stop())
+
+	// the reloaded chain resumes from the height and the hash of the stopped one
+	reloaded := newTestFISCOChain(t, reader, store.NewMemCheckpoint(nil), chain.handler)
+	reloaded.TakeOver(chain)
+
+	require.Equal(t, int64(2), reloaded.GetHeight())
+	require.Equal(t, chain.lastHash, reloaded.lastHash)
+}
+
+func TestTransactOpts(t *testing.T) {
+	privKey, err := hex.DecodeString("c87509a1c067bbde78beb793e6fa76530b6382a4c0241e5e4a9ec0a0f44dc0d3")
+	require.NoError(t, err)
+
+	signer, err := keystore.NewEVMSigner(privKey, keystore.AlgoSecp256k1)
+	require.NoError(t, err)
+
+	opts := transactOpts(signer, bind.TransactOpts{GasLimit: big.NewInt(30000000)}, false)
+	require.Equal(t, signer.Address(), opts.From.Hex())
+	require.Equal(t, big.NewInt(30000000), opts.GasLimit)
+
+	tx := types.NewTransaction(big.NewInt(1), ethcmn.HexToAddress(testIServiceCoreAddr), big.NewInt(0), big.NewInt(30000000), big.NewInt(1), big.NewInt(100), []byte{1}, big.NewInt(1), big.NewInt(1), nil, false)
+
+	signed, err := opts.Signer(types.HomesteadSigner{}, opts.From, tx)
+	require.NoError(t, err)
+
+	sender, err := types.Sender(types.HomesteadSigner{}, signed)
+	require.NoError(t, err)
+	require.Equal(t, opts.From, sender)
+
+	_, err = opts.Signer(types.HomesteadSigner{}, ethcmn.HexToAddress(testIServiceCoreAddr), tx)
+	require.Error(t, err)
+}
+
+func TestScanPausesOnFullQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := store.NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	queue := core.NewEventQueue(core.QueueConfig{Capacity: 2, LowWater: 1})
+
+	// the handler accepts each request once while the queue is not full
+	accepted := map[string]bool{}
+	handler := func(chainID string, request core.InterchainRequest, txHash string) error {
+		if queue.Paused() {
+			return core.ErrQueueFull
+		}
+
+		if !accepted[request.ID] {
+			accepted[request.ID] = true
+			queue.Add()
+		}
+
+		return nil
+	}
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI, "req-1", "req-2", "req-3")
+
+	chain := newTestFISCOChain(t, reader, checkpoint, handler)
+	chain.SetEventQueue(queue)
+
+	chain.scan(context.Background())
+
+	require.Len(t, accepted, 2)
+	require.Equal(t, int64(0), chain.GetHeight())
+
+	resumed := make(chan bool, 1)
+	go func() {
+		resumed <- chain.awaitQueue(context.Background())
+	}()
+
+	queue.Done()
+
+	select {
+	case <-resumed:
+		t.Fatal("listener resumed above the low-water mark")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	queue.Done()
+	require.True(t, <-resumed)
+
+	chain.scan(context.Background())
+
+	require.Len(t, accepted, 3)
+	require.Equal(t, int64(1), chain.GetHeight())
+}
+
+func TestScanPausesOnHandlerError(t *testing.T) {
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	handlerErr := errors.New("failed to save the seen mark")
+
+	handler := func(chainID string, request core.InterchainRequest, txHash string) error {
+		return handlerErr
+	}
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI, "req-1")
+
+	chain := newTestFISCOChain(t, reader, store.NewMemCheckpoint(nil), handler)
+
+	// the block is scanned again on a failure
+	chain.scan(context.Background())
+	require.Equal(t, int64(0), chain.GetHeight())
+
+	// not on a rejection
+	handlerErr = core.Rejected(errors.New("no route for the request"))
+
+	chain.scan(context.Background())
+	require.Equal(t, int64(1), chain.GetHeight())
+}
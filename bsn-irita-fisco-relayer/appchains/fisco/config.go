@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/viper"
+	"relayer/common"
 	"relayer/logging"
 
 	"github.com/FISCO-BCOS/go-sdk/conf"
@@ -18,33 +21,98 @@ const (
 	Prefix = "fisco"
 
 	// base config
-	ChainId         = "chainId"
-	ConnectionType  = "connection_type"
-	CAFile          = "ca_file"
-	CertFile        = "cert_file"
-	KeyFile         = "key_file"
-	SMCrypto        = "sm_crypto"
-	PrivateKeyFile  = "priv_key_file"
-	MonitorInterval = "monitor_interval"
-	Nodes           = "nodes"
+	ChainId          = "chainId"
+	ConnectionType   = "connection_type"
+	CAFile           = "ca_file"
+	CertFile         = "cert_file"
+	KeyFile          = "key_file"
+	SMCrypto         = "sm_crypto"
+	PrivateKeyFile   = "priv_key_file" // consumed by the file keystore
+	MonitorInterval  = "monitor_interval"
+	Nodes            = "nodes"
+	Chains           = "chains"
+	CheckResponse    = "check_response"
+	SimulateResponse = "simulate_response"
+	PollJitter       = "poll_jitter"
 )
 
 // BaseConfig defines the base config
 type BaseConfig struct {
-	IsHTTP          bool
-	CAFile          string
-	KeyFile         string
-	CertFile        string
-	PrivateKey      []byte
-	IsSMCrypto      bool
-	MonitorInterval uint64
-	NodesMap        map[string]string
-	ChainId         int64
+	IsHTTP           bool
+	CAFile           string
+	KeyFile          string
+	CertFile         string
+	PrivateKey       []byte `json:"-"` // loaded from the keystore per chain, never persisted
+	IsSMCrypto       bool
+	MonitorInterval  uint64
+	NodesMap         map[string]string
+	ChainId          int64
+	RetryPolicy      common.RetryPolicy
+	SubmitLimits     common.SubmitLimits
+	RequestTimeout   time.Duration            // deadline of a single RPC call
+	ChainOverrides   map[string]ChainOverride // chain params overridden by dest ID
+	CheckResponse    bool                     // checks if the response is on chain before sending it
+	SimulateResponse bool                     // simulates the response tx before broadcasting it, failing the reverting ones
+	PollJitter       float64                  // fraction of the monitor interval the polls are shifted by at random
+	Transport        common.TransportConfig   `json:"-"` // RPC transport options of the chain, from its override
+}
+
+// ChainOverride defines the chain params overridden by the config file
+// The overrides are reapplied on reload, while the registered params are kept intact
+type ChainOverride struct {
+	SubscribeBlocks   *bool    `json:"subscribe_blocks,omitempty" mapstructure:"subscribe_blocks"`
+	ConfirmationDepth *int64   `json:"confirmation_depth,omitempty" mapstructure:"confirmation_depth"`
+	CheckResponse     *bool    `json:"check_response,omitempty" mapstructure:"check_response"`
+	SimulateResponse  *bool    `json:"simulate_response,omitempty" mapstructure:"simulate_response"`
+	PollJitter        *float64 `json:"poll_jitter,omitempty" mapstructure:"poll_jitter"`
+
+	// headers and TLS options of the rpc connection, never stored as they usually carry credentials
+	common.TransportConfig `json:"-" mapstructure:",squash"`
+}
+
+// transports are the transport options of the chains by lowercased dest ID, loaded from the config file
+// They are left out of the stored base config, and looked up here when the chains are built
+var (
+	transportsMtx sync.RWMutex
+	transports    = map[string]common.TransportConfig{}
+)
+
+// setTransports replaces the transport options of the chains with those of the given overrides
+func setTransports(overrides map[string]ChainOverride) {
+	loaded := make(map[string]common.TransportConfig, len(overrides))
+	for destID, override := range overrides {
+		if override.TransportConfig.Enabled() {
+			loaded[strings.ToLower(destID)] = override.TransportConfig
+		}
+	}
+
+	transportsMtx.Lock()
+	defer transportsMtx.Unlock()
+
+	transports = loaded
+}
+
+// chainTransport returns the transport options of the given chain loaded from the config file
+func chainTransport(destID common.DestID) common.TransportConfig {
+	transportsMtx.RLock()
+	defer transportsMtx.RUnlock()
+
+	return transports[strings.ToLower(destID.String())]
 }
 
 func (bc *BaseConfig) PrintConfig(){
 }
 
+// ChainDestIDs implements config.BaseConfigI, returning the dest IDs of the chain overrides
+func (bc *BaseConfig) ChainDestIDs() map[string]common.DestID {
+	destIDs := make(map[string]common.DestID, len(bc.ChainOverrides))
+	for destID := range bc.ChainOverrides {
+		destIDs[cfg.GetConfigKey(Prefix, Chains)+"."+destID] = common.DestID(destID)
+	}
+
+	return destIDs
+}
+
 // Config defines the specific chain config
 type Config struct {
 	BaseConfig
@@ -58,7 +126,6 @@ func NewBaseConfig(v *viper.Viper) (*BaseConfig, error) {
 	certFile := v.GetString(cfg.GetConfigKey(Prefix, CertFile))
 	keyFile := v.GetString(cfg.GetConfigKey(Prefix, KeyFile))
 	smCrypto := v.GetBool(cfg.GetConfigKey(Prefix, SMCrypto))
-	privKeyFile := v.GetString(cfg.GetConfigKey(Prefix, PrivateKeyFile))
 	monitorInterval := v.GetUint64(cfg.GetConfigKey(Prefix, MonitorInterval))
 
 	chainId := v.GetInt64(cfg.GetConfigKey(Prefix, ChainId))
@@ -74,32 +141,130 @@ func NewBaseConfig(v *viper.Viper) (*BaseConfig, error) {
 
 	config.IsSMCrypto = smCrypto
 
-	keyBytes, curve, err := conf.LoadECPrivateKeyFromPEM(privKeyFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key, err: %v", err)
-	}
-
-	if config.IsSMCrypto && curve != "sm2p256v1" {
-		return nil, fmt.Errorf("smcrypto must use sm2p256v1 private key, but found %s", curve)
-	}
-	if !config.IsSMCrypto && curve != "secp256k1" {
-		return nil, fmt.Errorf("must use secp256k1 private key, but found %s", curve)
-	}
 	if chainId == 0 {
 		chainId = 1
 	}
 	config.ChainId = chainId
-	config.PrivateKey = keyBytes
 	config.CAFile = caFile
 	config.CertFile = certFile
 	config.KeyFile = keyFile
 	config.MonitorInterval = monitorInterval
 
+	config.RetryPolicy = cfg.LoadRetryPolicy(v, Prefix)
+	config.SubmitLimits = cfg.LoadSubmitLimits(v, Prefix)
+	config.RequestTimeout = cfg.LoadRequestTimeout(v, Prefix)
+	config.CheckResponse = v.GetBool(cfg.GetConfigKey(Prefix, CheckResponse))
+	config.SimulateResponse = v.GetBool(cfg.GetConfigKey(Prefix, SimulateResponse))
+
+	config.PollJitter = common.DefaultPollJitter
+	if v.IsSet(cfg.GetConfigKey(Prefix, PollJitter)) {
+		config.PollJitter = v.GetFloat64(cfg.GetConfigKey(Prefix, PollJitter))
+	}
+
+	if err := common.ValidatePollJitter(config.PollJitter); err != nil {
+		return nil, err
+	}
+
 	config.NodesMap = v.GetStringMapString(cfg.GetConfigKey(Prefix, Nodes))
 	logging.Logger.Infof("config fisco nods : %v", config.NodesMap)
 
+	if err := v.UnmarshalKey(cfg.GetConfigKey(Prefix, Chains), &config.ChainOverrides); err != nil {
+		return nil, fmt.Errorf("invalid chain overrides: %s", err)
+	}
+
+	for destID, override := range config.ChainOverrides {
+		if err := common.DestID(destID).Validate(); err != nil {
+			return nil, fmt.Errorf("invalid chain override %s: %s", destID, err)
+		}
+
+		if override.ConfirmationDepth != nil && *override.ConfirmationDepth < 0 {
+			return nil, fmt.Errorf("invalid chain override %s: negative confirmation depth %d", destID, *override.ConfirmationDepth)
+		}
+
+		if override.PollJitter != nil {
+			if err := common.ValidatePollJitter(*override.PollJitter); err != nil {
+				return nil, fmt.Errorf("invalid chain override %s: %s", destID, err)
+			}
+		}
+
+		if err := override.TransportConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid chain override %s: %s", destID, err)
+		}
+
+		// the channel connection takes no transport options
+		if !config.IsHTTP && override.TransportConfig.Enabled() {
+			return nil, fmt.Errorf("invalid chain override %s: the headers and TLS options only apply to the rpc connection", destID)
+		}
+	}
+
+	setTransports(config.ChainOverrides)
+
 	return config, nil
 }
+
+// chainOverride returns the override of the given chain
+// The dest IDs match regardless of case, as the config keys are lowercased on load
+func (c BaseConfig) chainOverride(destID common.DestID) (ChainOverride, bool) {
+	if override, ok := c.ChainOverrides[destID.String()]; ok {
+		return override, true
+	}
+
+	for id, override := range c.ChainOverrides {
+		if strings.EqualFold(id, destID.String()) {
+			return override, true
+		}
+	}
+
+	return ChainOverride{}, false
+}
+
+// withOverrides returns the config with the chain params overridden for the given chain
+// The transport options are those loaded from the config file, as they are not stored
+func (c Config) withOverrides(destID common.DestID) Config {
+	c.Transport = c.Transport.Merge(chainTransport(destID))
+
+	override, ok := c.chainOverride(destID)
+	if !ok {
+		return c
+	}
+
+	if override.SubscribeBlocks != nil {
+		c.SubscribeBlocks = *override.SubscribeBlocks
+	}
+
+	if override.ConfirmationDepth != nil {
+		c.ConfirmationDepth = *override.ConfirmationDepth
+	}
+
+	if override.CheckResponse != nil {
+		c.CheckResponse = *override.CheckResponse
+	}
+
+	if override.SimulateResponse != nil {
+		c.SimulateResponse = *override.SimulateResponse
+	}
+
+	if override.PollJitter != nil {
+		c.PollJitter = *override.PollJitter
+	}
+
+	return c
+}
+
+// nodeURLs returns the node addresses resolved from the node names of the chain
+func (c Config) nodeURLs() []string {
+	urls := make([]string, 0, len(c.NodeURLs))
+
+	for _, name := range c.NodeURLs {
+		if url, ok := c.NodesMap[name]; ok {
+			name = url
+		}
+
+		urls = append(urls, name)
+	}
+
+	return urls
+}
 func randURL(m []string) string {
 	if len(m) == 0 {
 		return ""
@@ -0,0 +1,90 @@
+package fisco
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/FISCO-BCOS/go-sdk/abi/bind"
+	"github.com/FISCO-BCOS/go-sdk/core/types"
+	ethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/irisnet/service-sdk-go/crypto/hd"
+
+	"relayer/common"
+	"relayer/keystore"
+)
+
+// HDPath is the derivation path of the key recovered from a mnemonic
+const HDPath = keystore.EVMHDPath
+
+// loadPrivateKey retrieves the private key of the given chain from the key store
+// The returned key is a copy held by the FISCO client, while the key material
+// of the secret is wiped
+func loadPrivateKey(keyStore keystore.KeyStore, destID common.DestID, isSMCrypto bool) ([]byte, error) {
+	secret, err := keyStore.GetSecret(destID)
+	if err == keystore.ErrKeyNotFound {
+		return nil, fmt.Errorf("no signing key configured for chain %s", destID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer secret.Wipe()
+
+	algo := keystore.AlgoSecp256k1
+	if isSMCrypto {
+		algo = keystore.AlgoSM2
+	}
+
+	if len(secret.Algo()) != 0 && secret.Algo() != algo {
+		return nil, fmt.Errorf("chain %s requires a %s private key, but found %s", destID, algo, secret.Algo())
+	}
+
+	if len(secret.PrivateKey()) != 0 {
+		privKey := make([]byte, len(secret.PrivateKey()))
+		copy(privKey, secret.PrivateKey())
+
+		return privKey, nil
+	}
+
+	signingAlgo, err := hd.NewSigningAlgoFromString(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, err := signingAlgo.Derive()(secret.Mnemonic(), "", HDPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the private key of chain %s: %s", destID, err)
+	}
+
+	return privKey, nil
+}
+
+// transactOpts returns the tx options of the client signing the txs by the given signer
+// The txs are signed over the sighash, or the SM3 hash for the sm crypto
+func transactOpts(signer keystore.Signer, opts bind.TransactOpts, isSMCrypto bool) bind.TransactOpts {
+	from := ethcmn.HexToAddress(signer.Address())
+
+	opts.From = from
+	opts.Signer = func(s types.Signer, address ethcmn.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if address != from {
+			return nil, errors.New("not authorized to sign this account")
+		}
+
+		if isSMCrypto {
+			sig, err := signer.Sign(tx.SM3HashNonSig().Bytes())
+			if err != nil {
+				return nil, err
+			}
+
+			return tx.WithSM2Signature(s, sig)
+		}
+
+		sig, err := signer.Sign(s.Hash(tx).Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		return tx.WithSignature(s, sig)
+	}
+
+	return opts
+}
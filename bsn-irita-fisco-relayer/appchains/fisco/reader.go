@@ -0,0 +1,105 @@
+package fisco
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	fiscoclient "github.com/FISCO-BCOS/go-sdk/client"
+	"github.com/FISCO-BCOS/go-sdk/core/types"
+
+	"relayer/common"
+	"relayer/logging"
+)
+
+// ChainReader defines the chain queries required by the chain monitor
+type ChainReader interface {
+	// get the current block number
+	GetBlockNumber(ctx context.Context) (int64, error)
+
+	// get the compact block in the given height
+	GetBlock(ctx context.Context, height int64) (CompactBlock, error)
+
+	// get the receipt of the given tx
+	GetReceipt(ctx context.Context, txHash string) (*types.Receipt, error)
+}
+
+// clientReader implements ChainReader with the FISCO client
+// Each query attempt is bounded by the request timeout, and the failed
+// attempts are retried according to the retry policy
+type clientReader struct {
+	client  *fiscoclient.Client
+	timeout time.Duration
+	policy  common.RetryPolicy
+	destID  common.DestID
+}
+
+var _ ChainReader = clientReader{}
+
+// call performs the given query with the timeout and retry policy
+func (r clientReader) call(ctx context.Context, name string, query func(ctx context.Context) error) error {
+	return common.Retry(ctx, func() error {
+		callCtx, cancel := common.WithTimeout(ctx, r.timeout)
+		defer cancel()
+
+		err := query(callCtx)
+		if err != nil && ctx.Err() == nil {
+			logging.WithChain(r.destID).Warnf("%s failed: %s", name, err)
+		}
+
+		return err
+	}, r.policy)
+}
+
+// GetBlockNumber implements ChainReader
+func (r clientReader) GetBlockNumber(ctx context.Context) (int64, error) {
+	var blockNumber []byte
+
+	err := r.call(ctx, "GetBlockNumber", func(ctx context.Context) (err error) {
+		blockNumber, err = r.client.GetBlockNumber(ctx)
+		return
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	var blockNumberStr string
+	if err := json.Unmarshal(blockNumber, &blockNumberStr); err != nil {
+		return -1, fmt.Errorf("failed to unmarshal the block number %s: %s", blockNumber, err)
+	}
+
+	return common.Hex2Decimal(blockNumberStr)
+}
+
+// GetBlock implements ChainReader
+func (r clientReader) GetBlock(ctx context.Context, height int64) (block CompactBlock, err error) {
+	var blockBz []byte
+
+	err = r.call(ctx, "GetBlockByNumber", func(ctx context.Context) (err error) {
+		blockBz, err = r.client.GetBlockByNumber(ctx, fmt.Sprintf("0x%x", height), false)
+		return
+	})
+	if err != nil {
+		return block, fmt.Errorf("failed to retrieve the block, height: %d, err: %s", height, err)
+	}
+
+	err = json.Unmarshal(blockBz, &block)
+	if err != nil {
+		return block, fmt.Errorf("failed to unmarshal the block, height: %d, err: %s", height, err)
+	}
+
+	return
+}
+
+// GetReceipt implements ChainReader
+func (r clientReader) GetReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	var receipt *types.Receipt
+
+	err := r.call(ctx, "GetTransactionReceipt", func(ctx context.Context) (err error) {
+		receipt, err = r.client.GetTransactionReceipt(ctx, txHash)
+		return
+	})
+
+	return receipt, err
+}
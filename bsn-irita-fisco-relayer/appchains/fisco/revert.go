@@ -0,0 +1,38 @@
+package fisco
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/FISCO-BCOS/go-sdk/core/types"
+)
+
+// callErrPattern matches the error of a call executed and failed on chain, which the
+// SDK formats with the status and the string following the Error(string) length word
+var callErrPattern = regexp.MustCompile(`(?s)call error of status (0x[0-9a-fA-F]+), (.*)`)
+
+// revertReason extracts the readable reason of the call failed on chain
+// False is returned if the error does not come from the execution, e.g. a connection error
+func revertReason(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	matches := callErrPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return "", false
+	}
+
+	// the reason is padded to 32 bytes
+	if reason := strings.TrimSpace(strings.TrimRight(matches[2], "\x00")); len(reason) != 0 {
+		return reason, true
+	}
+
+	status, parseErr := strconv.ParseInt(strings.TrimPrefix(matches[1], "0x"), 16, 64)
+	if parseErr == nil && status == types.RevertInstruction {
+		return "reverted without a reason", true
+	}
+
+	return "execution failed with status " + matches[1], true
+}
@@ -0,0 +1,28 @@
+package fisco
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevertReason(t *testing.T) {
+	reason, ok := revertReason(errors.New("call error of status 0x16, " + errDuplicatedResponse + "\x00\x00\x00"))
+	require.True(t, ok)
+	require.Equal(t, errDuplicatedResponse, reason)
+
+	reason, ok = revertReason(errors.New("call error of status 0x16, \x00\x00"))
+	require.True(t, ok)
+	require.Equal(t, "reverted without a reason", reason)
+
+	reason, ok = revertReason(errors.New("call error of status 0x1a, "))
+	require.True(t, ok)
+	require.Equal(t, "execution failed with status 0x1a", reason)
+
+	_, ok = revertReason(errors.New("dial tcp 127.0.0.1:20200: connect: connection refused"))
+	require.False(t, ok)
+
+	_, ok = revertReason(nil)
+	require.False(t, ok)
+}
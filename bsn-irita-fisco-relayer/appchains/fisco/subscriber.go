@@ -0,0 +1,193 @@
+package fisco
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	fiscoclient "github.com/FISCO-BCOS/go-sdk/client"
+
+	"relayer/common"
+	"relayer/logging"
+	"relayer/metrics"
+)
+
+// HeadSubscriber defines the interface to be notified of the new block heights
+type HeadSubscriber interface {
+	// SubscribeBlockNumbers calls the handler with the height of each new block until unsubscribed
+	SubscribeBlockNumbers(handler func(height int64)) (unsubscribe func(), err error)
+}
+
+// channelHeadSubscriber is a HeadSubscriber on the block number notifications of the channel connection
+// The notifications are not supported over rpc
+type channelHeadSubscriber struct {
+	client *fiscoclient.Client
+}
+
+var _ HeadSubscriber = channelHeadSubscriber{}
+
+// SubscribeBlockNumbers implements HeadSubscriber
+func (s channelHeadSubscriber) SubscribeBlockNumbers(handler func(height int64)) (func(), error) {
+	if err := s.client.SubscribeBlockNumberNotify(handler); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = s.client.UnsubscribeBlockNumberNotify()
+	}, nil
+}
+
+// headSubscription keeps a chain subscribed to the new block numbers until its context is done
+// The subscriber reports no disconnect, so the subscription is taken as dropped when a poll sees
+// the chain advance while no block was notified since the previous poll. It is then renewed, with
+// the backoff of the retry policy while failing, the chain being polled meanwhile
+type headSubscription struct {
+	subscriber HeadSubscriber
+	destID     common.DestID
+	policy     common.RetryPolicy
+
+	heights      chan int64    // notified heights, the latest one kept while busy
+	resubscribed chan struct{} // signaled on each renewal, for the blocks missed to be scanned
+	drops        chan struct{} // signaled on a drop detected by a poll
+
+	active   int32 // 1 while subscribed, accessed atomically
+	checked  int32 // 1 once polled since subscribing, accessed atomically
+	notified int64 // highest height notified, accessed atomically
+	count    int64 // notifications since the previous poll, accessed atomically
+	polled   int64 // height seen by the previous poll, only accessed by the monitor
+}
+
+// newHeadSubscription constructs a new headSubscription instance on the given subscriber
+func newHeadSubscription(subscriber HeadSubscriber, destID common.DestID, policy common.RetryPolicy) *headSubscription {
+	return &headSubscription{
+		subscriber:   subscriber,
+		destID:       destID,
+		policy:       policy,
+		heights:      make(chan int64, 1),
+		resubscribed: make(chan struct{}, 1),
+		drops:        make(chan struct{}, 1),
+	}
+}
+
+// start subscribes and keeps the subscription renewed until the context is done,
+// returning true if subscribed on the first attempt
+func (s *headSubscription) start(ctx context.Context) bool {
+	unsubscribe, err := s.subscribe()
+	if err != nil {
+		logging.WithChain(s.destID).Warnf("failed to subscribe to the new blocks, polling meanwhile: %s", err)
+	} else {
+		logging.WithChain(s.destID).Info("subscribed to the new blocks")
+	}
+
+	go s.run(ctx, unsubscribe)
+
+	return err == nil
+}
+
+// run renews the subscription on each drop until the context is done
+// The given unsubscribe is that of the current subscription, nil if not subscribed
+func (s *headSubscription) run(ctx context.Context, unsubscribe func()) {
+	attempts := 0
+
+	for {
+		if unsubscribe == nil {
+			attempts++
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-time.After(s.policy.Delay(attempts)):
+			}
+
+			var err error
+
+			unsubscribe, err = s.subscribe()
+			if err != nil {
+				logging.WithChain(s.destID).Warnf("failed to resubscribe to the new blocks, polling meanwhile: %s", err)
+				continue
+			}
+
+			attempts = 0
+			metrics.SubscriptionReconnects.WithLabelValues(s.destID.String()).Inc()
+			logging.WithChain(s.destID).Info("resubscribed to the new blocks")
+
+			select {
+			case s.resubscribed <- struct{}{}:
+			default:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			unsubscribe()
+			return
+
+		case <-s.drops:
+			unsubscribe()
+			unsubscribe = nil
+		}
+	}
+}
+
+// subscribe subscribes to the new block numbers, returning the unsubscribe
+func (s *headSubscription) subscribe() (func(), error) {
+	unsubscribe, err := s.subscriber.SubscribeBlockNumbers(s.notify)
+	if err != nil {
+		metrics.SubscriptionFailures.WithLabelValues(s.destID.String()).Inc()
+		return nil, err
+	}
+
+	atomic.StoreInt32(&s.checked, 0)
+	atomic.StoreInt32(&s.active, 1)
+
+	return unsubscribe, nil
+}
+
+// notify handles the given notified height
+func (s *headSubscription) notify(height int64) {
+	atomic.AddInt64(&s.count, 1)
+
+	for {
+		notified := atomic.LoadInt64(&s.notified)
+		if height <= notified || atomic.CompareAndSwapInt64(&s.notified, notified, height) {
+			break
+		}
+	}
+
+	// the scan catches up with the heights skipped while busy
+	select {
+	case s.heights <- height:
+	default:
+	}
+}
+
+// check checks the subscription against the height seen by a poll, 0 if the poll failed, and
+// returns true if it is taken as dropped, in which case it is renewed in the background
+// The first poll after subscribing only gives the node a poll interval to notify
+func (s *headSubscription) check(height int64) bool {
+	if height == 0 || atomic.LoadInt32(&s.active) == 0 {
+		return false
+	}
+
+	count := atomic.SwapInt64(&s.count, 0)
+	polled := s.polled
+	s.polled = height
+
+	if atomic.CompareAndSwapInt32(&s.checked, 0, 1) {
+		return false
+	}
+
+	if count > 0 || height <= polled || height <= atomic.LoadInt64(&s.notified) {
+		return false
+	}
+
+	atomic.StoreInt32(&s.active, 0)
+
+	select {
+	case s.drops <- struct{}{}:
+	default:
+	}
+
+	return true
+}
@@ -0,0 +1,180 @@
+package fisco
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FISCO-BCOS/go-sdk/abi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"relayer/appchains/fisco/iservice"
+	"relayer/core"
+	"relayer/metrics"
+	"relayer/store"
+)
+
+// mockHeadSubscriber hands the block number handler over to the test
+type mockHeadSubscriber struct {
+	err          error
+	handlers     chan func(int64)
+	unsubscribed chan struct{}
+}
+
+func (m *mockHeadSubscriber) SubscribeBlockNumbers(handler func(height int64)) (func(), error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.handlers <- handler
+
+	return func() { m.unsubscribed <- struct{}{} }, nil
+}
+
+// newMonitoredChain returns a chain on the mock reader recording the requests handled
+func newMonitoredChain(t *testing.T, reader ChainReader) (*FISCOChain, func(ids ...string) func() bool) {
+	var mtx sync.Mutex
+	handled := map[string]int{}
+
+	handler := func(chainID string, request core.InterchainRequest, txHash string) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		handled[request.ID]++
+		return nil
+	}
+
+	isHandled := func(ids ...string) func() bool {
+		return func() bool {
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			for _, id := range ids {
+				if handled[testRequestID(id)] != 1 {
+					return false
+				}
+			}
+
+			return true
+		}
+	}
+
+	chain := newTestFISCOChain(t, reader, store.NewMemCheckpoint(nil), handler)
+	chain.done = true
+
+	return chain, isHandled
+}
+
+func TestSubscribeBlocks(t *testing.T) {
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI)
+
+	subscriber := &mockHeadSubscriber{handlers: make(chan func(int64)), unsubscribed: make(chan struct{}, 1)}
+
+	// the polls are too far apart to see the new blocks
+	chain, isHandled := newMonitoredChain(t, reader)
+	chain.subscriber = subscriber
+	chain.Config.MonitorInterval = 3600
+
+	require.NoError(t, chain.Start(chain.handler))
+
+	notify := <-subscriber.handlers
+
+	reader.addBlock(t, coreABI, "req-1")
+	notify(2)
+	require.Eventually(t, isHandled("req-1"), time.Second, time.Millisecond)
+
+	// the blocks of the notifications missed are scanned up to the next notified one
+	reader.addBlock(t, coreABI, "req-2")
+	reader.addBlock(t, coreABI, "req-3")
+	notify(4)
+	require.Eventually(t, isHandled("req-2", "req-3"), time.Second, time.Millisecond)
+
+	require.NoError(t, chain.Stop())
+	<-subscriber.unsubscribed
+
+	require.Equal(t, int64(4), chain.GetHeight())
+}
+
+func TestSubscribeBlocksFallsBackToPolling(t *testing.T) {
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI)
+
+	chain, isHandled := newMonitoredChain(t, reader)
+	chain.subscriber = &mockHeadSubscriber{err: errors.New("method not found")}
+	chain.Config.MonitorInterval = 1
+
+	require.NoError(t, chain.Start(chain.handler))
+
+	reader.addBlock(t, coreABI, "req-1")
+	require.Eventually(t, isHandled("req-1"), 3*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, chain.Stop())
+}
+
+func TestResubscribeBlocks(t *testing.T) {
+	coreABI, err := abi.JSON(strings.NewReader(iservice.IServiceCoreExABI))
+	require.NoError(t, err)
+
+	reader := newMockChainReader()
+	reader.addBlock(t, coreABI)
+
+	subscriber := &mockHeadSubscriber{handlers: make(chan func(int64)), unsubscribed: make(chan struct{}, 1)}
+
+	chain, isHandled := newMonitoredChain(t, reader)
+	chain.subscriber = subscriber
+	chain.Config.MonitorInterval = 1
+
+	reconnects := testutil.ToFloat64(metrics.SubscriptionReconnects.WithLabelValues(chain.DestID.String()))
+
+	require.NoError(t, chain.Start(chain.handler))
+
+	notify := <-subscriber.handlers
+
+	reader.addBlock(t, coreABI, "req-1")
+	notify(2)
+	require.Eventually(t, isHandled("req-1"), time.Second, time.Millisecond)
+
+	// the subscription drops: the blocks are no longer notified, but polled
+	reader.addBlock(t, coreABI, "req-2")
+	reader.addBlock(t, coreABI, "req-3")
+	require.Eventually(t, isHandled("req-2", "req-3"), 3*time.Second, 10*time.Millisecond)
+
+	// the chain advancing with no block notified over a poll interval renews the subscription
+	reader.addBlock(t, coreABI, "req-4")
+
+	select {
+	case <-subscriber.unsubscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dropped subscription not detected")
+	}
+
+	select {
+	case notify = <-subscriber.handlers:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not resubscribed")
+	}
+
+	// the gap is backfilled from the last handled height, and the new blocks notified again
+	require.Eventually(t, isHandled("req-4"), time.Second, time.Millisecond)
+
+	reader.addBlock(t, coreABI, "req-5")
+	notify(6)
+	require.Eventually(t, isHandled("req-5"), time.Second, time.Millisecond)
+
+	require.Equal(t, reconnects+1, testutil.ToFloat64(metrics.SubscriptionReconnects.WithLabelValues(chain.DestID.String())))
+
+	require.NoError(t, chain.Stop())
+	<-subscriber.unsubscribed
+
+	require.Equal(t, int64(6), chain.GetHeight())
+}
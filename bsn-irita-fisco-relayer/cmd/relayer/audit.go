@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cfg "relayer/config"
+	"relayer/core"
+)
+
+const (
+	flagLines  = "lines"
+	flagFollow = "follow"
+
+	auditPollInterval = time.Second
+)
+
+// AuditCmd implements the audit command
+func AuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit [config-file]",
+		Short: "Print the last records of the audit trail of the relay decisions",
+		Long: `Print the last records of the audit trail of the relay decisions. With --follow, the
+records appended afterwards are printed as they are written, across the file rotations`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFileName := cfg.DefaultConfigFileName
+			if len(args) == 1 {
+				configFileName = args[0]
+			}
+
+			lines, err := cmd.Flags().GetInt(flagLines)
+			if err != nil {
+				return err
+			}
+
+			follow, err := cmd.Flags().GetBool(flagFollow)
+			if err != nil {
+				return err
+			}
+
+			asJSON, err := cmd.Flags().GetBool(flagJSON)
+			if err != nil {
+				return err
+			}
+
+			config, err := cfg.LoadYAMLConfig(configFileName)
+			if err != nil {
+				return err
+			}
+
+			path, err := auditPath(config)
+			if err != nil {
+				return err
+			}
+
+			printRecord := printAuditRecord
+			if asJSON {
+				printRecord = printAuditRecordJSON
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+			go func() {
+				<-sigCh
+				cancel()
+			}()
+
+			return tailAuditFile(ctx, path, lines, follow, printRecord)
+		},
+	}
+
+	cmd.Flags().IntP(flagLines, "n", 10, "number of the last records to print, all if 0")
+	cmd.Flags().BoolP(flagFollow, "f", false, "print the records appended afterwards until interrupted")
+	cmd.Flags().Bool(flagJSON, false, "print the records in JSON")
+
+	return cmd
+}
+
+// auditFollower reads the records of the audit file as they are appended
+// The file is reopened once rotated, after the records of the rotated one are read
+type auditFollower struct {
+	path    string
+	file    *os.File
+	reader  *bufio.Reader
+	partial []byte // trailing line not yet completely written
+}
+
+// open opens the audit file from its start
+func (f *auditFollower) open() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.file = file
+	f.reader = bufio.NewReader(file)
+	f.partial = nil
+
+	return nil
+}
+
+// next returns the next complete record, false on the end of the file
+func (f *auditFollower) next() (core.AuditRecord, bool, error) {
+	var record core.AuditRecord
+
+	for {
+		line, err := f.reader.ReadBytes('\n')
+		if err == io.EOF {
+			f.partial = append(f.partial, line...)
+			return record, false, nil
+		} else if err != nil {
+			return record, false, err
+		}
+
+		line = append(f.partial, line...)
+		f.partial = nil
+
+		if len(line) <= 1 {
+			continue
+		}
+
+		if err := json.Unmarshal(line, &record); err != nil {
+			return record, false, fmt.Errorf("invalid audit record in %s: %s", f.path, err)
+		}
+
+		return record, true, nil
+	}
+}
+
+// drain prints the complete records up to the end of the file
+func (f *auditFollower) drain(printRecord func(core.AuditRecord)) error {
+	for {
+		record, ok, err := f.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		printRecord(record)
+	}
+}
+
+// rotated reports whether the audit file was replaced since opened
+func (f *auditFollower) rotated() bool {
+	current, err := os.Stat(f.path)
+	if err != nil {
+		return false
+	}
+
+	opened, err := f.file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return !os.SameFile(current, opened)
+}
+
+// tailAuditFile prints the last records of the audit file, and the appended ones if following
+func tailAuditFile(ctx context.Context, path string, lines int, follow bool, printRecord func(core.AuditRecord)) error {
+	follower := &auditFollower{path: path}
+
+	if err := follower.open(); os.IsNotExist(err) && !follow {
+		fmt.Println("no audit record")
+		return nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if follower.file != nil {
+		var last []core.AuditRecord
+
+		for {
+			record, ok, err := follower.next()
+			if err != nil {
+				follower.file.Close()
+				return err
+			}
+
+			if !ok {
+				break
+			}
+
+			last = append(last, record)
+			if lines > 0 && len(last) > lines {
+				last = last[1:]
+			}
+		}
+
+		for _, record := range last {
+			printRecord(record)
+		}
+	}
+
+	if !follow {
+		return follower.file.Close()
+	}
+
+	ticker := time.NewTicker(auditPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if follower.file != nil {
+				follower.file.Close()
+			}
+
+			return nil
+
+		case <-ticker.C:
+		}
+
+		if follower.file == nil {
+			if err := follower.open(); os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+		}
+
+		// the rotated file is read to its end before the new one
+		rotated := follower.rotated()
+
+		if err := follower.drain(printRecord); err != nil {
+			follower.file.Close()
+			return err
+		}
+
+		if rotated {
+			follower.file.Close()
+			follower.file = nil
+		}
+	}
+}
+
+// printAuditRecord prints the audit record in the human-readable format
+func printAuditRecord(record core.AuditRecord) {
+	line := fmt.Sprintf(
+		"%s  %-13s  %s  %s -> %s",
+		record.Timestamp.Format(time.RFC3339), record.Decision, record.RequestID, record.SourceID, record.DestID,
+	)
+
+	if len(record.SourceTxHash) != 0 {
+		line += fmt.Sprintf("  source tx %s", record.SourceTxHash)
+	}
+
+	if len(record.TxHash) != 0 {
+		line += fmt.Sprintf("  response tx %s", record.TxHash)
+	}
+
+	if len(record.Reason) != 0 {
+		line += fmt.Sprintf("  reason: %s", record.Reason)
+	}
+
+	fmt.Println(line)
+}
+
+// printAuditRecordJSON prints the audit record in JSON
+func printAuditRecordJSON(record core.AuditRecord) {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	fmt.Println(string(bz))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cfg "relayer/config"
+	"relayer/core"
+)
+
+const (
+	flagNode = "node"
+
+	defaultNode = "http://127.0.0.1:8082"
+)
+
+var (
+	DeadLetterCmd = &cobra.Command{
+		Use:   "deadletter",
+		Short: "Dead-lettered request commands",
+	}
+)
+
+// DeadLetterListCmd implements the deadletter list command
+func DeadLetterListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [config-file]",
+		Short: "List the requests which failed to relay permanently",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFileName := cfg.DefaultConfigFileName
+			if len(args) == 1 {
+				configFileName = args[0]
+			}
+
+			config, err := cfg.LoadYAMLConfig(configFileName)
+			if err != nil {
+				return err
+			}
+
+			path, err := deadLetterPath(config)
+			if err != nil {
+				return err
+			}
+
+			queue, err := core.NewFileDeadLetterQueue(path)
+			if err != nil {
+				return err
+			}
+
+			letters, err := queue.List()
+			if err != nil {
+				return err
+			}
+
+			if len(letters) == 0 {
+				fmt.Println("no dead-lettered request")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+			fmt.Fprintln(w, "REQUEST ID\tCHAIN ID\tDEST ID\tSTAGE\tFAILED AT\tERROR")
+			for _, l := range letters {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", l.Request.ID, l.ChainID, l.DestID, l.Stage, l.FailedAt.Format(time.RFC3339), l.Error)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	return cmd
+}
+
+// DeadLetterResubmitCmd implements the deadletter resubmit command
+func DeadLetterResubmitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resubmit [request-id]",
+		Short: "Relay the dead-lettered request again through the running relayer",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := cmd.Flags().GetString(flagNode)
+			if err != nil {
+				return err
+			}
+
+			url := fmt.Sprintf("%s/api/v0/deadletters/%s/resubmit", node, args[0])
+
+			resp, err := http.Post(url, "application/json", nil)
+			if err != nil {
+				return fmt.Errorf("failed to reach the relayer: %s", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				var errResp struct {
+					Error string `json:"msg"`
+				}
+
+				if err := json.Unmarshal(body, &errResp); err != nil || len(errResp.Error) == 0 {
+					return fmt.Errorf("failed to resubmit the request: %s", resp.Status)
+				}
+
+				return fmt.Errorf("failed to resubmit the request: %s", errResp.Error)
+			}
+
+			fmt.Printf("request %s resubmitted\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagNode, defaultNode, "address of the running relayer web server")
+
+	return cmd
+}
+
+func init() {
+	DeadLetterCmd.AddCommand(
+		DeadLetterListCmd(),
+		DeadLetterResubmitCmd(),
+	)
+}
@@ -5,9 +5,11 @@ import (
 	"io/ioutil"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	cfg "relayer/config"
 	"relayer/hub"
+	"relayer/keystore"
 )
 
 var (
@@ -42,7 +44,10 @@ func KeysAddCmd() *cobra.Command {
 				return err
 			}
 
-			hubChain := hub.BuildIritaHubChain(hub.NewConfig(config))
+			hubChain, err := buildKeyringHubChain(config)
+			if err != nil {
+				return err
+			}
 
 			addr, mnemonic, err := hubChain.AddKey(args[0], args[1])
 			if err != nil {
@@ -78,7 +83,10 @@ func KeysShowCmd() *cobra.Command {
 				return err
 			}
 
-			hubChain := hub.BuildIritaHubChain(hub.NewConfig(config))
+			hubChain, err := buildKeyringHubChain(config)
+			if err != nil {
+				return err
+			}
 
 			addr, err := hubChain.ShowKey(args[0], args[1])
 			if err != nil {
@@ -119,7 +127,10 @@ func KeysImportCmd() *cobra.Command {
 				return err
 			}
 
-			hubChain := hub.BuildIritaHubChain(hub.NewConfig(config))
+			hubChain, err := buildKeyringHubChain(config)
+			if err != nil {
+				return err
+			}
 
 			addr, err := hubChain.ImportKey(args[0], args[1], string(keyArmor))
 			if err != nil {
@@ -135,6 +146,16 @@ func KeysImportCmd() *cobra.Command {
 	return cmd
 }
 
+// buildKeyringHubChain builds the Irita-Hub instance on the keyring managed by the key commands
+func buildKeyringHubChain(config *viper.Viper) (hub.IritaHubChain, error) {
+	hubConfig, err := hub.NewConfig(config)
+	if err != nil {
+		return hub.IritaHubChain{}, err
+	}
+
+	return hub.BuildIritaHubChain(hubConfig, keystore.NewFileKeyStore(nil, ""))
+}
+
 func init() {
 	KeysCmd.AddCommand(
 		KeysAddCmd(),
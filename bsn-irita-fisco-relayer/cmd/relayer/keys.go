@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cosmos/go-bip39"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"relayer/appchains/fisco"
+	cfg "relayer/config"
+	"relayer/keystore"
+)
+
+const (
+	flagRecover = "recover"
+	flagAlgo    = "algo"
+	flagYes     = "yes"
+
+	mnemonicEntropySize = 256
+)
+
+var (
+	KeyStoreCmd = &cobra.Command{
+		Use:   "keys",
+		Short: "Signing key commands of the configured keystore",
+		Long: `Manage the signing keys of the configured keystore by name, the name being the dest ID
+or the chain type the key applies to, e.g. fisco-1-1 or irita-hub. The key material is
+never printed, except the mnemonic generated by add`,
+	}
+)
+
+// KeyStoreAddCmd implements the keys add command
+func KeyStoreAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [name] [config-file]",
+		Short: "Generate a new key, or recover it from a mnemonic read from stdin",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := keystore.ValidateKeyName(name); err != nil {
+				return err
+			}
+
+			recoverKey, err := cmd.Flags().GetBool(flagRecover)
+			if err != nil {
+				return err
+			}
+
+			config, ks, err := loadKeyManager(args[1:])
+			if err != nil {
+				return err
+			}
+
+			algo, err := keyAlgo(cmd, config, name)
+			if err != nil {
+				return err
+			}
+
+			var mnemonic string
+
+			if recoverKey {
+				fmt.Fprintln(os.Stderr, "enter the mnemonic:")
+
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && len(line) == 0 {
+					return fmt.Errorf("failed to read the mnemonic: %s", err)
+				}
+
+				mnemonic = strings.Join(strings.Fields(line), " ")
+				if !bip39.IsMnemonicValid(mnemonic) {
+					return errors.New("invalid mnemonic")
+				}
+			} else {
+				entropy, err := bip39.NewEntropy(mnemonicEntropySize)
+				if err != nil {
+					return err
+				}
+
+				mnemonic, err = bip39.NewMnemonic(entropy)
+				if err != nil {
+					return err
+				}
+			}
+
+			info, err := keystore.AddMnemonic(ks, name, mnemonic, algo)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("key added successfully: \n\nname: %s\nchain type: %s\nalgo: %s\naddress: %s\n", info.Name, info.ChainType, info.Algo, info.Address)
+
+			if !recoverKey {
+				fmt.Printf("mnemonic: %s\n\nwrite the mnemonic down and keep it safe, it is the only way to recover the key and is not shown again\n", mnemonic)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool(flagRecover, false, "recover the key from a mnemonic read from stdin")
+	cmd.Flags().String(flagAlgo, "", "key algorithm, secp256k1 or sm2; sm2 for the hub and the sm crypto FISCO chains by default")
+
+	return cmd
+}
+
+// KeyStoreListCmd implements the keys list command
+func KeyStoreListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [config-file]",
+		Short: "List the keys of the keystore with the derived addresses",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ks, err := loadKeyManager(args)
+			if err != nil {
+				return err
+			}
+
+			names, err := ks.List()
+			if err != nil {
+				return err
+			}
+
+			if len(names) == 0 {
+				fmt.Println("no key")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+			fmt.Fprintln(w, "NAME\tCHAIN TYPE\tALGO\tADDRESS")
+			for _, name := range names {
+				algo, err := keyAlgo(cmd, config, name)
+				if err != nil {
+					return err
+				}
+
+				info, err := keystore.Describe(ks, name, algo)
+				if err != nil {
+					fmt.Fprintf(w, "%s\t%s\t\terror: %s\n", name, keystore.KeyChainType(name), err)
+					continue
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Name, info.ChainType, info.Algo, info.Address)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().String(flagAlgo, "", "key algorithm of the mnemonic keys, secp256k1 or sm2")
+
+	return cmd
+}
+
+// KeyStoreShowCmd implements the keys show command
+func KeyStoreShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [name] [config-file]",
+		Short: "Show the chain type, algorithm and address of the key by name",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, ks, err := loadKeyManager(args[1:])
+			if err != nil {
+				return err
+			}
+
+			algo, err := keyAlgo(cmd, config, args[0])
+			if err != nil {
+				return err
+			}
+
+			info, err := keystore.Describe(ks, args[0], algo)
+			if err == keystore.ErrKeyNotFound {
+				return fmt.Errorf("key %s not found", args[0])
+			} else if err != nil {
+				return err
+			}
+
+			fmt.Printf("name: %s\nchain type: %s\nalgo: %s\naddress: %s\n", info.Name, info.ChainType, info.Algo, info.Address)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagAlgo, "", "key algorithm of the mnemonic key, secp256k1 or sm2")
+
+	return cmd
+}
+
+// KeyStoreDeleteCmd implements the keys delete command
+func KeyStoreDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete [name] [config-file]",
+		Short: "Delete the key by name",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			yes, err := cmd.Flags().GetBool(flagYes)
+			if err != nil {
+				return err
+			}
+
+			_, ks, err := loadKeyManager(args[1:])
+			if err != nil {
+				return err
+			}
+
+			if !yes {
+				fmt.Fprintf(os.Stderr, "delete the key %s? the key can not be recovered without its mnemonic [y/N] ", name)
+
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+					fmt.Println("aborted")
+					return nil
+				}
+			}
+
+			if err := ks.Delete(name); err == keystore.ErrKeyNotFound {
+				return fmt.Errorf("key %s not found", name)
+			} else if err != nil {
+				return err
+			}
+
+			fmt.Printf("key %s deleted\n", name)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP(flagYes, "y", false, "skip the confirmation")
+
+	return cmd
+}
+
+// loadKeyManager loads the config of the optional config file arg and the keystore it configures
+func loadKeyManager(args []string) (*viper.Viper, keystore.KeyManager, error) {
+	configFileName := cfg.DefaultConfigFileName
+	if len(args) == 1 {
+		configFileName = args[0]
+	}
+
+	config, err := cfg.LoadYAMLConfig(configFileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ks, err := keystore.NewKeyStore(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manager, ok := ks.(keystore.KeyManager)
+	if !ok {
+		return nil, nil, fmt.Errorf("the keys of the configured keystore can not be managed")
+	}
+
+	return config, manager, nil
+}
+
+// keyAlgo returns the key algorithm of the named key set by the flag, the FISCO
+// chains defaulting to the configured crypto, the others to the chain type one
+func keyAlgo(cmd *cobra.Command, config *viper.Viper, name string) (string, error) {
+	algo, err := cmd.Flags().GetString(flagAlgo)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(algo) != 0:
+		if algo != keystore.AlgoSecp256k1 && algo != keystore.AlgoSM2 {
+			return "", fmt.Errorf("unsupported key algorithm %s", algo)
+		}
+
+		return algo, nil
+
+	case keystore.KeyChainType(name) == keystore.ChainTypeFISCO && config.GetBool(cfg.GetConfigKey(fisco.Prefix, fisco.SMCrypto)):
+		return keystore.AlgoSM2, nil
+
+	default:
+		return keystore.DefaultAlgo(keystore.KeyChainType(name)), nil
+	}
+}
+
+func init() {
+	KeyStoreCmd.AddCommand(
+		KeyStoreAddCmd(),
+		KeyStoreListCmd(),
+		KeyStoreShowCmd(),
+		KeyStoreDeleteCmd(),
+	)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"relayer/appchains"
+	cfg "relayer/config"
+	"relayer/core"
+	"relayer/hub"
+	"relayer/keystore"
+	"relayer/logging"
+	storepkg "relayer/store"
+)
+
+const (
+	flagChain = "chain"
+	flagFrom  = "from"
+	flagTo    = "to"
+)
+
+// ReplayCmd implements the replay command
+func ReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay [config-file]",
+		Short: "Relay again the requests of a source block height range",
+		Long: `Relay again the requests emitted by the chain in the closed height range [from, to], skipping
+the ones already responded on the destination. The checkpoint of the chain is left untouched.
+The command opens the relayer store, so the relayer must be stopped while replaying`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFileName := cfg.DefaultConfigFileName
+			if len(args) == 1 {
+				configFileName = args[0]
+			}
+
+			destID, err := cmd.Flags().GetString(flagChain)
+			if err != nil {
+				return err
+			}
+
+			from, err := cmd.Flags().GetInt64(flagFrom)
+			if err != nil {
+				return err
+			}
+
+			to, err := cmd.Flags().GetInt64(flagTo)
+			if err != nil {
+				return err
+			}
+
+			if from <= 0 || to < from {
+				return fmt.Errorf("invalid height range [%d, %d]", from, to)
+			}
+
+			config, err := cfg.LoadYAMLConfig(configFileName)
+			if err != nil {
+				return err
+			}
+
+			if err := logging.SetLevel(config.GetString(cfg.ConfigKeyLogLevel)); err != nil {
+				return err
+			}
+
+			appChainType := config.GetString(cfg.ConfigKeyAppChainType)
+
+			store, err := storepkg.NewStore(config.GetString(cfg.ConfigKeyStorePath))
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			state, err := openStateStore(config, store)
+			if err != nil {
+				return err
+			}
+			defer state.Close()
+
+			// the live checkpoint is never advanced by the replay
+			checkpoint := storepkg.NewMemCheckpoint(nil)
+
+			keyStore, err := keystore.NewKeyStore(config)
+			if err != nil {
+				return err
+			}
+
+			appChainFactory := appchains.NewAppChainFactory(store, checkpoint, keyStore)
+
+			baseConfig, err := appchains.NewBaseConfigFactory(config).NewBaseConfig(appChainType)
+			if err != nil {
+				return err
+			}
+
+			baseConfigBz, err := json.Marshal(baseConfig)
+			if err != nil {
+				return err
+			}
+
+			if err := appChainFactory.StoreBaseConfig(appChainType, baseConfigBz); err != nil {
+				return err
+			}
+
+			hubConfig, err := hub.NewConfig(config)
+			if err != nil {
+				return err
+			}
+
+			hubChain, err := hub.BuildIritaHubChain(hubConfig, keyStore)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance := core.NewRelayer(appChainType, hubChain, appChainFactory, store, logging.Logger)
+			relayerInstance.State = state
+
+			relayerInstance.Audit, err = openAuditLog(config)
+			if err != nil {
+				return err
+			}
+
+			if err := registerEncoders(relayerInstance.Encoders, config, hubChain.ServiceInfo.ServiceName); err != nil {
+				return err
+			}
+
+			relayerInstance.Routes, err = loadRoutingTable(config)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance.Schemas, err = loadInputSchemas(config, hubChain.ServiceInfo.ServiceName)
+			if err != nil {
+				return err
+			}
+
+			chainID, err := loadChainByDestID(relayerInstance, store, appChainType, destID)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+			go func() {
+				<-sigCh
+				cancel()
+			}()
+
+			summary, err := relayerInstance.Replay(ctx, chainID, from, to)
+
+			fmt.Printf(
+				"replayed %s [%d, %d]: %d found, %d relayed, %d skipped, %d filtered, %d failed\n",
+				destID, from, to, summary.Found, summary.Relayed, summary.Skipped, summary.Filtered, summary.Failed,
+			)
+
+			return err
+		},
+	}
+
+	cmd.Flags().String(flagChain, "", "dest ID of the source chain to replay")
+	cmd.Flags().Int64(flagFrom, 0, "first height of the range")
+	cmd.Flags().Int64(flagTo, 0, "last height of the range")
+
+	_ = cmd.MarkFlagRequired(flagChain)
+	_ = cmd.MarkFlagRequired(flagFrom)
+	_ = cmd.MarkFlagRequired(flagTo)
+
+	return cmd
+}
+
+// loadChainByDestID loads the stored app chain of the given dest ID, returning its chain ID
+func loadChainByDestID(relayer *core.Relayer, store *storepkg.Store, appChainType string, destID string) (string, error) {
+	chainIDsBz, err := store.Get([]byte("chainIDs"))
+	if err != nil {
+		return "", fmt.Errorf("no chain stored: %s", err)
+	}
+
+	chainIDs := map[string]string{}
+	if err := json.Unmarshal(chainIDsBz, &chainIDs); err != nil {
+		return "", err
+	}
+
+	for chainID, chainType := range chainIDs {
+		if chainType != appChainType {
+			continue
+		}
+
+		chainParams, err := store.Get([]byte(fmt.Sprintf("%s:params:%s", appChainType, chainID)))
+		if err != nil {
+			return "", err
+		}
+
+		// the dest ID is only known once the chain is built
+		chainID, err := relayer.LoadChain(chainParams)
+		if err != nil {
+			return "", err
+		}
+
+		chain, err := relayer.GetChain(chainID)
+		if err != nil {
+			return "", err
+		}
+
+		if strings.EqualFold(chain.GetDestID().String(), destID) {
+			return chainID, nil
+		}
+	}
+
+	return "", fmt.Errorf("chain %s not found", destID)
+}
@@ -20,6 +20,11 @@ func main() {
 
 	rootCmd.AddCommand(StartCmd())
 	rootCmd.AddCommand(HubCmd)
+	rootCmd.AddCommand(DeadLetterCmd)
+	rootCmd.AddCommand(StatusCmd())
+	rootCmd.AddCommand(ReplayCmd())
+	rootCmd.AddCommand(AuditCmd())
+	rootCmd.AddCommand(KeyStoreCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
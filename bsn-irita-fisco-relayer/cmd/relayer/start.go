@@ -1,21 +1,39 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/irisnet/service-sdk-go/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"relayer/appchains"
+	"relayer/appchains/fisco"
+	"relayer/common"
 	cfg "relayer/config"
 	"relayer/core"
 	"relayer/hub"
+	"relayer/keystore"
 	"relayer/logging"
+	"relayer/metrics"
 	"relayer/mysql"
 	"relayer/server"
-	"relayer/store"
+	storepkg "relayer/store"
 )
 
 const (
 	_HttpPort = "base.http_port"
+
+	flagDryRun = "dry-run"
+
+	dryRunCheckTimeout = 15 * time.Second
 )
 
 // StartCmd implements the start command
@@ -38,20 +56,165 @@ func StartCmd() *cobra.Command {
 				return err
 			}
 
+			if err := logging.SetLevel(config.GetString(cfg.ConfigKeyLogLevel)); err != nil {
+				return err
+			}
+
+			if err := logging.SetFormat(config.GetString(cfg.ConfigKeyLogFormat)); err != nil {
+				return err
+			}
+
+			var metricsServer *http.Server
+			if config.GetBool(cfg.ConfigKeyMetricsEnabled) {
+				metricsServer = metrics.StartServer(config.GetString(cfg.ConfigKeyMetricsAddress))
+			}
+
 			appChainType := config.GetString(cfg.ConfigKeyAppChainType)
 
-			store, err := store.NewStore(config.GetString(cfg.ConfigKeyStorePath))
+			store, err := storepkg.NewStore(config.GetString(cfg.ConfigKeyStorePath))
 			if err != nil {
 				return err
 			}
+			defer store.Close()
 
 			mysqlConfig := mysql.NewConfig(config)
 			mysql.NewDB(mysqlConfig)
 			defer mysql.Close()
 
-			appChainFactory := appchains.NewAppChainFactory(store)
-			hubChain := hub.BuildIritaHubChain(hub.NewConfig(config))
-			relayerInstance := core.NewRelayer(appChainType, hubChain, appChainFactory, logging.Logger)
+			state, err := openStateStore(config, store)
+			if err != nil {
+				return err
+			}
+			defer state.Close()
+
+			dryRun, err := cmd.Flags().GetBool(flagDryRun)
+			if err != nil {
+				return err
+			}
+
+			var checkpoint storepkg.Checkpoint = state
+
+			if dryRun {
+				// never advance the persistent checkpoints in the dry run
+				checkpoint = storepkg.NewMemCheckpoint(checkpoint)
+				logging.Logger.Infof("dry run enabled, no transaction will be broadcast")
+			}
+
+			keyStore, err := keystore.NewKeyStore(config)
+			if err != nil {
+				return err
+			}
+
+			appChainFactory := appchains.NewAppChainFactory(store, checkpoint, keyStore)
+
+			hubConfig, err := hub.NewConfig(config)
+			if err != nil {
+				return err
+			}
+
+			hubChain, err := hub.BuildIritaHubChain(hubConfig, keyStore)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance := core.NewRelayer(appChainType, hubChain, appChainFactory, store, logging.Logger)
+			relayerInstance.DryRun = dryRun
+			relayerInstance.State = state
+			relayerInstance.Health, err = loadHealthConfig(config)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance.Dedup = core.NewDedupCache(
+				config.GetInt(cfg.ConfigKeyDedupCapacity),
+				config.GetDuration(cfg.ConfigKeyDedupTTL),
+			)
+
+			relayerInstance.Breakers = core.NewBreakerRegistry(core.BreakerConfig{
+				FailureThreshold: config.GetInt(cfg.ConfigKeyBreakerThreshold),
+				Cooldown:         config.GetDuration(cfg.ConfigKeyBreakerCooldown),
+				MaxBuffered:      config.GetInt(cfg.ConfigKeyBreakerMaxBuffered),
+			})
+
+			relayerInstance.Queue = core.NewEventQueue(core.QueueConfig{
+				Capacity: config.GetInt(cfg.ConfigKeyQueueCapacity),
+				LowWater: config.GetInt(cfg.ConfigKeyQueueLowWater),
+			})
+
+			relayerInstance.Async = core.AsyncConfig{
+				Enabled:  config.GetBool(cfg.ConfigKeyAsyncEnabled),
+				Interval: config.GetDuration(cfg.ConfigKeyAsyncInterval),
+				Timeout:  config.GetDuration(cfg.ConfigKeyAsyncTimeout),
+			}
+
+			relayerInstance.Ordering = core.OrderingConfig{
+				Enabled:  config.GetBool(cfg.ConfigKeyOrderingEnabled),
+				Key:      config.GetString(cfg.ConfigKeyOrderingKey),
+				Services: config.GetStringSlice(cfg.ConfigKeyOrderingServices),
+			}
+
+			if err := relayerInstance.Ordering.Validate(); err != nil {
+				return err
+			}
+
+			relayerInstance.LatencyLogSample = config.GetFloat64(cfg.ConfigKeyLatencyLogSample)
+			if relayerInstance.LatencyLogSample < 0 || relayerInstance.LatencyLogSample > 1 {
+				return fmt.Errorf("invalid %s %v: expected a fraction between 0 and 1", cfg.ConfigKeyLatencyLogSample, relayerInstance.LatencyLogSample)
+			}
+
+			relayerInstance.Notifications, err = loadNotifications(config)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance.Breakers.OnOpen(func(name string, failures int) {
+				relayerInstance.Notify(core.NotificationEvent{
+					Type:    core.NotifyCircuitOpen,
+					DestID:  name,
+					Message: fmt.Sprintf("circuit opened after %d consecutive failures", failures),
+				})
+			})
+
+			hubChain.Balances.OnLowBalance(func(keyName string, address string, balance types.Coins, threshold types.Coins) {
+				relayerInstance.Notify(core.NotificationEvent{
+					Type:    core.NotifyLowBalance,
+					DestID:  hubChain.ChainID,
+					Message: fmt.Sprintf("balance of %s is %s, below the threshold %s", keyName, balance, threshold),
+					Details: map[string]string{
+						"account": keyName,
+						"address": address,
+					},
+				})
+			})
+
+			deadLetterPath, err := deadLetterPath(config)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance.DeadLetters, err = core.NewFileDeadLetterQueue(deadLetterPath)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance.Audit, err = openAuditLog(config)
+			if err != nil {
+				return err
+			}
+
+			if err := registerEncoders(relayerInstance.Encoders, config, hubChain.ServiceInfo.ServiceName); err != nil {
+				return err
+			}
+
+			relayerInstance.Routes, err = loadRoutingTable(config)
+			if err != nil {
+				return err
+			}
+
+			relayerInstance.Schemas, err = loadInputSchemas(config, hubChain.ServiceInfo.ServiceName)
+			if err != nil {
+				return err
+			}
 
 			baseConfigFactory := appchains.NewBaseConfigFactory(config)
 			BaseConfig, err := baseConfigFactory.NewBaseConfig(appChainType)
@@ -60,6 +223,21 @@ func StartCmd() *cobra.Command {
 			}
 			baseConfigByte, _ := json.Marshal(BaseConfig)
 			appChainFactory.StoreBaseConfig(appChainType, baseConfigByte)
+			// each chain is restored in its own supervised goroutine, so that a chain failing
+			// to start is retried with backoff without holding back or affecting the others
+			restoreCtx, cancelRestore := context.WithCancel(context.Background())
+			defer cancelRestore()
+
+			if dryRun {
+				restoreCtx, cancelRestore = context.WithTimeout(restoreCtx, dryRunCheckTimeout)
+				defer cancelRestore()
+			}
+
+			restorer := core.NewSupervisor(restoreCtx, cfg.LoadRetryPolicy(config, appChainType))
+			restoring := 0
+
+			restored := map[string][]byte{}
+
 			chainIDsbz, _ := store.Get([]byte("chainIDs"))
 			if chainIDsbz == nil {
 				chainIDsbz, err = json.Marshal(map[string]string{})
@@ -76,34 +254,454 @@ func StartCmd() *cobra.Command {
 						if err != nil {
 							return err
 						}
-						chain, err := relayerInstance.AppChainFactory.BuildAppChain(chainType, chainParams)
-						if err != nil {
-							return err
-						}
-
-						if err := chain.Start(relayerInstance.HandleInterchainRequest); err != nil {
-							return err
-						}
 
-						relayerInstance.AppChains[chainID] = chain
-						relayerInstance.AppChainStates[chainID] = true
+						restored[chainID] = chainParams
 					}
 				}
 			}
 
-			chainManager := server.NewChainManager(relayerInstance)
+			// the colliding chains fail the startup before any monitor starts
+			if err := validateAppChains(relayerInstance, restored, BaseConfig); err != nil {
+				return err
+			}
 
+			for chainID, chainParams := range restored {
+				chainParams := chainParams
+
+				restorer.Go(chainID, func(context.Context) error {
+					return relayerInstance.RestoreChain(chainParams)
+				})
+				restoring++
+			}
+
+			if dryRun {
+				if err := restorer.Wait(); err != nil {
+					return err
+				}
+
+				if restored := len(relayerInstance.GetChains()); restored < restoring {
+					return fmt.Errorf("%d chain(s) failed to start", restoring-restored)
+				}
+
+				checkCtx, cancel := context.WithTimeout(context.Background(), dryRunCheckTimeout)
+				err := relayerInstance.CheckChains(checkCtx)
+				cancel()
+
+				if err != nil {
+					return err
+				}
+			}
+
+			balanceCtx, cancelBalances := context.WithCancel(context.Background())
+			defer cancelBalances()
+
+			hubChain.Balances.Start(balanceCtx)
+
+			listenersCtx, cancelListeners := context.WithCancel(context.Background())
+			defer cancelListeners()
+
+			if relayerInstance.Notifications.Enabled(core.NotifyListenerDown) {
+				go relayerInstance.WatchListeners(listenersCtx, config.GetDuration(cfg.ConfigKeyNotifyCheckInterval))
+			}
+
+			pruneCtx, cancelPrune := context.WithCancel(context.Background())
+			defer cancelPrune()
+
+			if !dryRun {
+				go relayerInstance.PruneSeenMarks(pruneCtx)
+			}
+
+			confirmCtx, cancelConfirm := context.WithCancel(context.Background())
+			defer cancelConfirm()
+
+			// the response txs left pending by the last run are watched as well
+			if relayerInstance.Async.Enabled && !dryRun {
+				go func() {
+					_ = core.Supervise(confirmCtx, "confirmations", cfg.LoadRetryPolicy(config, appChainType), func(ctx context.Context) error {
+						relayerInstance.WatchConfirmations(ctx)
+						return nil
+					})
+				}()
+			}
+
+			chainManager := server.NewChainManager(relayerInstance)
 
 			httpPort := config.GetInt(_HttpPort)
 			if httpPort == 0 {
 				httpPort = 8082
 			}
 
-			server.StartWebServer(chainManager, httpPort)
+			webServer := server.StartWebServer(chainManager, httpPort)
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+			var abortErr error
+
+			// only an unrecoverable error of the chain restoration aborts the relayer
+			restoreFailed := restorer.Done()
+
+			// the heartbeats of the main loop back /livez
+			heartbeat := time.NewTicker(relayerInstance.Health.Interval())
+			defer heartbeat.Stop()
+
+		loop:
+			for {
+				select {
+				case <-heartbeat.C:
+					relayerInstance.Heartbeat()
+
+				case sig := <-sigCh:
+					if sig == syscall.SIGHUP {
+						reloadConfig(configFileName, config, hubChain, relayerInstance)
+						continue
+					}
+
+					logging.Logger.Infof("received signal %s, shutting down", sig)
+
+					break loop
+
+				case <-restoreFailed:
+					if abortErr = restorer.Err(); abortErr != nil {
+						logging.Logger.Errorf("unrecoverable error, shutting down: %s", abortErr)
+						break loop
+					}
+
+					restoreFailed = nil
+				}
+			}
+
+			cancelRestore()
+			cancelBalances()
+			cancelListeners()
+			cancelConfirm()
+
+			shutdownTimeout := config.GetDuration(cfg.ConfigKeyShutdownTimeout)
+			if shutdownTimeout == 0 {
+				shutdownTimeout = cfg.DefaultShutdownTimeout
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			_ = webServer.Shutdown(ctx)
 
-			return nil
+			err = relayerInstance.Shutdown(ctx)
+
+			if metricsServer != nil {
+				_ = metricsServer.Shutdown(context.Background())
+			}
+
+			if abortErr != nil {
+				return abortErr
+			}
+
+			return err
 		},
 	}
 
+	cmd.Flags().Bool(flagDryRun, false, "validate the config and connectivity and log the requests without relaying them")
+
 	return cmd
 }
+
+// reloadableConfigKeys are the config keys applied on reload, the others taking effect on restart
+var reloadableConfigKeys = []string{
+	cfg.ConfigKeyLogLevel,
+	cfg.GetConfigKey(hub.Prefix, hub.Accounts),
+	cfg.GetConfigKey(fisco.Prefix, fisco.ConnectionType),
+	cfg.GetConfigKey(fisco.Prefix, fisco.CAFile),
+	cfg.GetConfigKey(fisco.Prefix, fisco.CertFile),
+	cfg.GetConfigKey(fisco.Prefix, fisco.KeyFile),
+	cfg.GetConfigKey(fisco.Prefix, fisco.ChainId),
+	cfg.GetConfigKey(fisco.Prefix, fisco.MonitorInterval),
+	cfg.GetConfigKey(fisco.Prefix, fisco.Nodes),
+	cfg.GetConfigKey(fisco.Prefix, fisco.Chains),
+	cfg.GetConfigKey(fisco.Prefix, fisco.CheckResponse),
+	cfg.GetConfigKey(fisco.Prefix, fisco.SimulateResponse),
+	cfg.GetConfigKey(fisco.Prefix, fisco.PollJitter),
+	cfg.GetConfigKey(fisco.Prefix, cfg.RetryPrefix),
+	cfg.GetConfigKey(fisco.Prefix, cfg.RequestTimeout),
+}
+
+// reloadConfig re-reads the config file and applies the changes which take effect live:
+// the log level, the hub signing accounts, and the app chain endpoints, retry policy,
+// request timeout and confirmation depth. The new config is validated as a whole before
+// being applied, and the current settings are kept if it is invalid. The other changes
+// are logged as requiring a restart against the config the relayer started with
+func reloadConfig(configFileName string, startConfig *viper.Viper, hubChain hub.IritaHubChain, relayer *core.Relayer) {
+	logging.Logger.Infof("reloading the config from %s", configFileName)
+
+	config, err := cfg.LoadYAMLConfig(configFileName)
+	if err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	level, err := logging.ParseLevel(config.GetString(cfg.ConfigKeyLogLevel))
+	if err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	hubConfig, err := hub.NewConfig(config)
+	if err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	if err := hubChain.ValidateAccounts(hubConfig.SigningAccounts()); err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	baseConfig, err := appchains.NewBaseConfigFactory(config).NewBaseConfig(relayer.AppChainType)
+	if err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	baseConfigBz, err := json.Marshal(baseConfig)
+	if err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	if err := relayer.ReloadAppChains(baseConfigBz); err != nil {
+		logging.Logger.Errorf("failed to reload the config: %s", err)
+		return
+	}
+
+	if err := hubChain.SetAccounts(hubConfig.SigningAccounts()); err != nil {
+		logging.Logger.Errorf("failed to reload the signing accounts: %s", err)
+	}
+
+	logging.Logger.SetLevel(level)
+
+	for _, key := range cfg.ChangedKeys(startConfig, config, reloadableConfigKeys...) {
+		logging.Logger.Warnf("config %s changed, which takes effect on restart", key)
+	}
+
+	logging.Logger.Infof("config reloaded")
+}
+
+// deadLetterPath returns the configured dead letter file, defaulting to the one under the home directory
+func deadLetterPath(v *viper.Viper) (string, error) {
+	if path := v.GetString(cfg.ConfigKeyDeadLetterPath); len(path) != 0 {
+		return path, nil
+	}
+
+	return core.DefaultDeadLetterPath()
+}
+
+// openStateStore opens the relay state store of the configured backend
+// The file state is imported into the BoltDB backend on its first run
+func openStateStore(v *viper.Viper, store *storepkg.Store) (storepkg.StateStore, error) {
+	dir, err := checkpointPath(v)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := storepkg.NewFileCheckpoint(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fileState := storepkg.NewFileStateStore(checkpoint, store)
+
+	switch backend := v.GetString(cfg.ConfigKeyStateBackend); backend {
+	case "", storepkg.StateBackendFile:
+		return fileState, nil
+
+	case storepkg.StateBackendBolt:
+		path, err := statePath(v)
+		if err != nil {
+			return nil, err
+		}
+
+		boltState, err := storepkg.NewBoltStateStore(path)
+		if err != nil {
+			return nil, err
+		}
+
+		migrated, err := storepkg.MigrateFileState(boltState, fileState)
+		if err != nil {
+			boltState.Close()
+			return nil, err
+		}
+
+		if migrated {
+			logging.Logger.Infof("imported the file state into %s", path)
+		}
+
+		return boltState, nil
+
+	default:
+		return nil, fmt.Errorf("unknown state backend %s, expected %s or %s", backend, storepkg.StateBackendFile, storepkg.StateBackendBolt)
+	}
+}
+
+// checkpointPath returns the configured checkpoint directory, defaulting to the one under the home directory
+func checkpointPath(v *viper.Viper) (string, error) {
+	if path := v.GetString(cfg.ConfigKeyCheckpoint); len(path) != 0 {
+		return path, nil
+	}
+
+	return storepkg.DefaultCheckpointPath()
+}
+
+// statePath returns the configured BoltDB state file, defaulting to the one under the home directory
+func statePath(v *viper.Viper) (string, error) {
+	if path := v.GetString(cfg.ConfigKeyStatePath); len(path) != 0 {
+		return path, nil
+	}
+
+	return storepkg.DefaultStatePath()
+}
+
+// auditPath returns the configured audit file, defaulting to the one under the home directory
+func auditPath(v *viper.Viper) (string, error) {
+	if path := v.GetString(cfg.ConfigKeyAuditPath); len(path) != 0 {
+		return path, nil
+	}
+
+	return core.DefaultAuditPath()
+}
+
+// openAuditLog opens the audit log of the relay decisions
+func openAuditLog(v *viper.Viper) (*core.FileAuditLog, error) {
+	path, err := auditPath(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return core.NewFileAuditLog(
+		path,
+		int64(v.GetSizeInBytes(cfg.ConfigKeyAuditMaxSize)),
+		v.GetInt(cfg.ConfigKeyAuditMaxBackups),
+	)
+}
+
+// loadHealthConfig loads the health check config
+func loadHealthConfig(v *viper.Viper) (core.HealthConfig, error) {
+	healthConfig := core.HealthConfig{
+		StalenessThreshold: v.GetDuration(cfg.ConfigKeyHealthStaleness),
+		ChainThresholds:    make(map[string]time.Duration),
+		HeartbeatInterval:  v.GetDuration(cfg.ConfigKeyHealthHeartbeat),
+	}
+
+	for chain, threshold := range v.GetStringMapString(cfg.ConfigKeyHealthChains) {
+		d, err := time.ParseDuration(threshold)
+		if err != nil {
+			return healthConfig, fmt.Errorf("invalid staleness threshold of %s: %s", chain, err)
+		}
+
+		healthConfig.ChainThresholds[chain] = d
+	}
+
+	return healthConfig, nil
+}
+
+// registerEncoders registers the response encoders by service name
+// The hub service defaults to the JSON passthrough encoder
+func registerEncoders(registry *core.EncoderRegistry, v *viper.Viper, hubService string) error {
+	registry.Register(hubService, core.JSONEncoder{})
+
+	for service, encoder := range v.GetStringMapString(cfg.ConfigKeyServiceEncoders) {
+		if err := registry.RegisterBuiltin(service, encoder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadInputSchemas loads the JSON schemas of the service inputs by service name
+func loadInputSchemas(v *viper.Viper, hubService string) (*core.SchemaRegistry, error) {
+	registry := core.NewSchemaRegistry(hubService)
+
+	for service, schemaJSON := range v.GetStringMapString(cfg.ConfigKeyServiceInputSchemas) {
+		schema, err := core.ParseInputSchema([]byte(schemaJSON))
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %s", service, err)
+		}
+
+		registry.Register(service, schema)
+	}
+
+	return registry, nil
+}
+
+// loadNotifications loads the notifications of the critical failures
+// None is delivered if no webhook is configured
+func loadNotifications(v *viper.Viper) (*core.Notifications, error) {
+	config := core.NotifyConfig{
+		Events:     v.GetStringSlice(cfg.ConfigKeyNotifyEvents),
+		Timeout:    v.GetDuration(cfg.ConfigKeyNotifyTimeout),
+		MaxPending: v.GetInt(cfg.ConfigKeyNotifyMaxPending),
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	webhookURL := v.GetString(cfg.ConfigKeyNotifyWebhookURL)
+	if len(webhookURL) == 0 {
+		return core.NewNotifications(core.NopNotifier{}, config), nil
+	}
+
+	notifier, err := core.NewWebhookNotifier(webhookURL, v.GetStringMapString(cfg.ConfigKeyNotifyHeaders))
+	if err != nil {
+		return nil, err
+	}
+
+	return core.NewNotifications(notifier, config), nil
+}
+
+// validateAppChains validates the restored chains along with the ones configured in the base config
+func validateAppChains(relayer *core.Relayer, restored map[string][]byte, baseConfig cfg.BaseConfigI) error {
+	chainIDs := make([]string, 0, len(restored))
+	for chainID := range restored {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Strings(chainIDs)
+
+	params := make([][]byte, 0, len(restored))
+	for _, chainID := range chainIDs {
+		params = append(params, restored[chainID])
+	}
+
+	var configured map[string]common.DestID
+	if baseConfig != nil {
+		configured = baseConfig.ChainDestIDs()
+	}
+
+	return relayer.ValidateAppChains(params, configured)
+}
+
+// loadRoutingTable loads the routes of the requests to the destination chains
+// nil is returned if no route is configured, the requests being relayed as they are.
+// It is called before any chain is restored, failing the startup on the duplicate chains
+func loadRoutingTable(v *viper.Viper) (*core.RoutingTable, error) {
+	var routes []core.Route
+	if err := v.UnmarshalKey(cfg.ConfigKeyRoutingRoutes, &routes); err != nil {
+		return nil, fmt.Errorf("invalid routes: %s", err)
+	}
+
+	var chains []core.RouteChain
+	if err := v.UnmarshalKey(cfg.ConfigKeyRoutingChains, &chains); err != nil {
+		return nil, fmt.Errorf("invalid routing chains: %s", err)
+	}
+
+	// the chains are validated even without routes, so that the duplicates fail the startup
+	destIDs, err := core.RouteChainDestIDs(chains)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	return core.NewRoutingTable(routes, destIDs)
+}
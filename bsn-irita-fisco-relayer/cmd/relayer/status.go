@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"relayer/common"
+	cfg "relayer/config"
+	"relayer/core"
+	"relayer/logging"
+	storepkg "relayer/store"
+)
+
+const (
+	flagJSON = "json"
+)
+
+// errUnreachable is returned if the relayer web server can not be reached
+var errUnreachable = errors.New("failed to reach the relayer")
+
+// StatusCmd implements the status command
+func StatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [request-id] [config-file]",
+		Short: "Query the relay status of the request from the running relayer, or from its stores if stopped",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFileName := cfg.DefaultConfigFileName
+			if len(args) == 2 {
+				configFileName = args[1]
+			}
+
+			node, err := cmd.Flags().GetString(flagNode)
+			if err != nil {
+				return err
+			}
+
+			asJSON, err := cmd.Flags().GetBool(flagJSON)
+			if err != nil {
+				return err
+			}
+
+			destID, err := cmd.Flags().GetString(flagChain)
+			if err != nil {
+				return err
+			}
+
+			status, err := queryRequestStatus(node, args[0], destID)
+			if errors.Is(err, errUnreachable) {
+				fmt.Fprintf(os.Stderr, "%s, reading its stores\n", err)
+				status, err = readRequestStatus(configFileName, args[0], destID)
+			}
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				bz, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					return err
+				}
+
+				fmt.Println(string(bz))
+
+				return nil
+			}
+
+			return printRequestStatus(status)
+		},
+	}
+
+	cmd.Flags().String(flagNode, defaultNode, "address of the running relayer web server")
+	cmd.Flags().Bool(flagJSON, false, "print the status in JSON")
+	cmd.Flags().String(flagChain, "", "dest ID of the source chain, required if the request ID is relayed from several chains")
+
+	return cmd
+}
+
+// queryRequestStatus retrieves the request status from the relayer web server
+func queryRequestStatus(node string, requestID string, destID string) (status core.RequestStatus, err error) {
+	url := fmt.Sprintf("%s/api/v0/requests/%s/status", node, requestID)
+	if len(destID) != 0 {
+		url += "?dest_id=" + neturl.QueryEscape(destID)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return status, fmt.Errorf("%w: %s", errUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return status, err
+	}
+
+	var result struct {
+		Error  string             `json:"msg"`
+		Status core.RequestStatus `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return status, fmt.Errorf("failed to query the request status: %s", resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("failed to query the request status: %s", result.Error)
+	}
+
+	return result.Status, nil
+}
+
+// readRequestStatus reads the request status from the stores of the stopped relayer
+// The stores are opened read-only and fail to open while the relayer holds them
+func readRequestStatus(configFileName string, requestID string, destID string) (status core.RequestStatus, err error) {
+	config, err := cfg.LoadYAMLConfig(configFileName)
+	if err != nil {
+		return status, err
+	}
+
+	store, err := storepkg.NewReadOnlyStore(config.GetString(cfg.ConfigKeyStorePath))
+	if err != nil {
+		return status, fmt.Errorf("failed to open the store: %s", err)
+	}
+	defer store.Close()
+
+	state, err := openReadOnlyStateStore(config, store)
+	if err != nil {
+		return status, err
+	}
+	defer state.Close()
+
+	path, err := deadLetterPath(config)
+	if err != nil {
+		return status, err
+	}
+
+	relayer := core.NewRelayer(config.GetString(cfg.ConfigKeyAppChainType), nil, nil, store, logging.Logger)
+	relayer.State = state
+	relayer.DeadLetters, err = core.NewFileDeadLetterQueue(path)
+	if err != nil {
+		return status, err
+	}
+
+	return relayer.GetRequestStatus(requestID, common.DestID(destID))
+}
+
+// openReadOnlyStateStore opens the relay state store of the configured backend for reading only
+// Unlike openStateStore, the file state is never imported
+func openReadOnlyStateStore(v *viper.Viper, store *storepkg.Store) (storepkg.StateStore, error) {
+	switch backend := v.GetString(cfg.ConfigKeyStateBackend); backend {
+	case "", storepkg.StateBackendFile:
+		dir, err := checkpointPath(v)
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoint, err := storepkg.NewFileCheckpoint(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		return storepkg.NewFileStateStore(checkpoint, store), nil
+
+	case storepkg.StateBackendBolt:
+		path, err := statePath(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return storepkg.NewReadOnlyBoltStateStore(path)
+
+	default:
+		return nil, fmt.Errorf("unknown state backend %s, expected %s or %s", backend, storepkg.StateBackendFile, storepkg.StateBackendBolt)
+	}
+}
+
+// printRequestStatus prints the request status in the human-readable format
+func printRequestStatus(status core.RequestStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "request ID:\t%s\n", status.RequestID)
+	fmt.Fprintf(w, "status:\t%s\n", status.Status)
+
+	if record := status.Record; record != nil {
+		fmt.Fprintf(w, "chain ID:\t%s\n", record.ChainID)
+		fmt.Fprintf(w, "source tx:\t%s\n", record.TxHash)
+
+		if len(record.ReqCtxID) != 0 {
+			fmt.Fprintf(w, "hub request context:\t%s\n", record.ReqCtxID)
+		}
+
+		if len(record.ICRequestID) != 0 {
+			fmt.Fprintf(w, "hub request:\t%s\n", record.ICRequestID)
+		}
+
+		if len(record.ResponseTxHash) != 0 {
+			fmt.Fprintf(w, "response tx:\t%s\n", record.ResponseTxHash)
+		}
+
+		fmt.Fprintf(w, "updated at:\t%s\n", record.UpdatedAt.Format(time.RFC3339))
+	}
+
+	if letter := status.DeadLetter; letter != nil {
+		fmt.Fprintf(w, "failed stage:\t%s\n", letter.Stage)
+		fmt.Fprintf(w, "failed at:\t%s\n", letter.FailedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "error:\t%s\n", letter.Error)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,154 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DestID is the unique identifier of a chain, composed of
+// the chain type, the optional group ID and the chain ID
+// The chain type is case-insensitive, e.g. Eth and eth are the same type, while the group
+// and chain IDs are compared exactly as they may be case-sensitive on their chains
+type DestID string
+
+// NewDestID constructs a new DestID from the given chain params
+func NewDestID(chainType string, groupID string, chainID string) (DestID, error) {
+	if err := ValidateChainParams(chainType, groupID, chainID); err != nil {
+		return "", err
+	}
+
+	return newDestID(chainType, groupID, chainID), nil
+}
+
+// newDestID builds the DestID without validation
+func newDestID(chainType string, groupID string, chainID string) DestID {
+	if len(groupID) == 0 {
+		return DestID(strings.Join([]string{chainType, chainID}, DestIDDelimiter))
+	}
+
+	return DestID(strings.Join([]string{chainType, groupID, chainID}, DestIDDelimiter))
+}
+
+// Split splits the DestID into the chain params
+func (d DestID) Split() (chainType string, groupID string, chainID string, err error) {
+	segments := strings.Split(string(d), DestIDDelimiter)
+
+	for _, s := range segments {
+		if len(s) == 0 {
+			return "", "", "", fmt.Errorf("invalid dest ID %q: empty segment", d)
+		}
+	}
+
+	switch len(segments) {
+	case 2:
+		return segments[0], "", segments[1], nil
+
+	case 3:
+		return segments[0], segments[1], segments[2], nil
+
+	default:
+		return "", "", "", fmt.Errorf("invalid dest ID %q: expected 2 or 3 segments, got %d", d, len(segments))
+	}
+}
+
+// Validate checks if the DestID is well-formed
+func (d DestID) Validate() error {
+	chainType, groupID, chainID, err := d.Split()
+	if err != nil {
+		return err
+	}
+
+	return ValidateChainParams(chainType, groupID, chainID)
+}
+
+// ChainType returns the chain type segment
+// An empty string is returned if the DestID is malformed
+func (d DestID) ChainType() string {
+	chainType, _, _, _ := d.Split()
+	return chainType
+}
+
+// GroupID returns the group ID segment, which may be empty
+func (d DestID) GroupID() string {
+	_, groupID, _, _ := d.Split()
+	return groupID
+}
+
+// ChainID returns the chain ID segment
+// An empty string is returned if the DestID is malformed
+func (d DestID) ChainID() string {
+	_, _, chainID, _ := d.Split()
+	return chainID
+}
+
+// Normalize returns the DestID with the chain type lowercased, the other segments preserved
+// The malformed DestID is returned unchanged
+func (d DestID) Normalize() DestID {
+	chainType, groupID, chainID, err := d.Split()
+	if err != nil {
+		return d
+	}
+
+	return newDestID(strings.ToLower(chainType), groupID, chainID)
+}
+
+// String implements fmt.Stringer
+func (d DestID) String() string {
+	return string(d)
+}
+
+// MarshalJSON implements json.Marshaler
+func (d DestID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(d))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *DestID) UnmarshalJSON(bz []byte) error {
+	var s string
+	if err := json.Unmarshal(bz, &s); err != nil {
+		return err
+	}
+
+	destID := DestID(s)
+	if err := destID.Validate(); err != nil {
+		return err
+	}
+
+	*d = destID
+
+	return nil
+}
+
+// ValidateChainParams validates the chain params which make up a dest ID
+// The group ID is optional
+func ValidateChainParams(chainType string, groupID string, chainID string) error {
+	if len(chainType) == 0 {
+		return fmt.Errorf("chain type can not be empty")
+	}
+
+	if len(chainID) == 0 {
+		return fmt.Errorf("chain ID can not be empty")
+	}
+
+	params := []struct {
+		name  string
+		value string
+	}{
+		{"chain type", chainType},
+		{"group ID", groupID},
+		{"chain ID", chainID},
+	}
+
+	for _, p := range params {
+		if strings.Contains(p.value, DestIDDelimiter) {
+			return fmt.Errorf("%s %q can not contain %q", p.name, p.value, DestIDDelimiter)
+		}
+
+		if len(p.value) > MaxChainParamLength {
+			return fmt.Errorf("%s %q exceeds the maximum length %d", p.name, p.value, MaxChainParamLength)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestID(t *testing.T) {
+	destID, err := NewDestID("fisco", "1", "5")
+	require.NoError(t, err)
+	require.Equal(t, "fisco", destID.ChainType())
+	require.Equal(t, "1", destID.GroupID())
+	require.Equal(t, "5", destID.ChainID())
+	require.Equal(t, GetDestID("fisco", "1", "5"), destID.String())
+
+	destID, err = NewDestID("fisco", "", "5")
+	require.NoError(t, err)
+	require.Equal(t, "", destID.GroupID())
+	require.Equal(t, "fisco-5", destID.String())
+
+	_, err = NewDestID("fisco", "1-2", "5")
+	require.Error(t, err)
+}
+
+func TestNormalizeDestID(t *testing.T) {
+	require.Equal(t, "eth-1", NormalizeDestID("Eth-1"))
+	require.Equal(t, "fabric-Org1-MyChannel", NormalizeDestID("FABRIC-Org1-MyChannel"))
+	require.Equal(t, "cosmos-Hub", NormalizeDestID("cosmos-Hub"))
+
+	// the malformed dest IDs are left unchanged
+	require.Equal(t, "Eth", NormalizeDestID("Eth"))
+
+	require.True(t, EqualDestID("Eth-1", "eth-1"))
+	require.False(t, EqualDestID("eth-Hub", "eth-hub"))
+	require.False(t, EqualDestID("eth-1", "eth-1-1"))
+}
+
+func TestDestIDJSON(t *testing.T) {
+	type state struct {
+		Dest DestID `json:"dest"`
+	}
+
+	bz, err := json.Marshal(state{Dest: DestID("fisco-1-5")})
+	require.NoError(t, err)
+	require.Equal(t, `{"dest":"fisco-1-5"}`, string(bz))
+
+	var s state
+	require.NoError(t, json.Unmarshal(bz, &s))
+	require.Equal(t, DestID("fisco-1-5"), s.Dest)
+
+	require.Error(t, json.Unmarshal([]byte(`{"dest":"fisco"}`), &s))
+}
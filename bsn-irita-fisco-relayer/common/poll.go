@@ -0,0 +1,57 @@
+package common
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultPollJitter is the default fraction of the poll interval the polls are shifted by
+	DefaultPollJitter = 0.1
+	// MaxPollJitter bounds the jitter, so that two polls are at least half an interval apart
+	MaxPollJitter = 0.5
+)
+
+// PollSchedule spreads the polls of the chain listeners over the interval, so that the
+// listeners started together do not hit their endpoints at the same time
+type PollSchedule struct {
+	Interval time.Duration // mean interval between two polls
+	Jitter   float64       // fraction of the interval each poll is shifted by at random, disabled if 0
+}
+
+// ValidatePollJitter validates the jitter is within [0, MaxPollJitter]
+func ValidatePollJitter(jitter float64) error {
+	if jitter < 0 || jitter > MaxPollJitter {
+		return fmt.Errorf("invalid poll jitter %v: expected a fraction of the interval between 0 and %v", jitter, MaxPollJitter)
+	}
+
+	return nil
+}
+
+// StartDelay returns the random delay before the first poll within one interval,
+// staggering the listeners started together. It is zero if the jitter is disabled
+func (s PollSchedule) StartDelay() time.Duration {
+	if s.Jitter <= 0 || s.Interval <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(s.Interval)))
+}
+
+// Next returns the delay before the next poll, picked at random within the jitter
+// around the interval, i.e. in [interval*(1-jitter), interval*(1+jitter))
+func (s PollSchedule) Next() time.Duration {
+	jitter := s.Jitter
+	if jitter > MaxPollJitter {
+		jitter = MaxPollJitter
+	}
+
+	if jitter <= 0 || s.Interval <= 0 {
+		return s.Interval
+	}
+
+	spread := float64(s.Interval) * jitter
+
+	return time.Duration(float64(s.Interval) - spread + rand.Float64()*2*spread)
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollSchedule(t *testing.T) {
+	schedule := PollSchedule{Interval: time.Second, Jitter: 0.2}
+
+	distinct := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		next := schedule.Next()
+		require.True(t, next >= 800*time.Millisecond && next < 1200*time.Millisecond, next)
+		distinct[next] = true
+
+		delay := schedule.StartDelay()
+		require.True(t, delay >= 0 && delay < time.Second, delay)
+	}
+
+	require.True(t, len(distinct) > 1)
+
+	// the jitter is bounded
+	next := PollSchedule{Interval: time.Second, Jitter: 3}.Next()
+	require.True(t, next >= 500*time.Millisecond && next < 1500*time.Millisecond, next)
+
+	// no jitter keeps the aligned schedule
+	require.Equal(t, time.Second, PollSchedule{Interval: time.Second}.Next())
+	require.Equal(t, time.Duration(0), PollSchedule{Interval: time.Second}.StartDelay())
+
+	require.NoError(t, ValidatePollJitter(0))
+	require.NoError(t, ValidatePollJitter(MaxPollJitter))
+	require.Error(t, ValidatePollJitter(-0.1))
+	require.Error(t, ValidatePollJitter(0.6))
+}
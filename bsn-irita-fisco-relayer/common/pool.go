@@ -0,0 +1,222 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// account selection strategies
+const (
+	StrategyRoundRobin    = "round_robin"
+	StrategyLeastInFlight = "least_in_flight"
+)
+
+// DefaultBalanceCheckInterval is the default interval between two balance checks of an account
+const DefaultBalanceCheckInterval = time.Minute
+
+// ErrNoAccountAvailable is returned when every account of the pool is below the minimum balance
+var ErrNoAccountAvailable = errors.New("no signing account available")
+
+// BalanceChecker returns true if the balance of the given account is sufficient to pay the fees
+type BalanceChecker func(account string) (bool, error)
+
+// AccountPool selects the signing accounts to spread the concurrent txs over
+// The accounts with insufficient balances are skipped until they are topped up
+type AccountPool struct {
+	strategy      string
+	checkBalance  BalanceChecker
+	checkInterval time.Duration
+
+	mtx      sync.Mutex
+	accounts []string
+	next     int                      // index of the next account for the round robin
+	inflight map[string]int           // unreleased selections by account
+	balances map[string]balanceStatus // last balance check by account
+	checking map[string]bool          // accounts of the balance checks in progress
+	now      func() time.Time
+}
+
+// balanceStatus is the result of the last balance check of an account
+type balanceStatus struct {
+	sufficient bool
+	checkedAt  time.Time
+}
+
+// NewAccountPool constructs a new AccountPool instance
+// The balances are not checked if checkBalance is nil
+func NewAccountPool(strategy string, accounts []string, checkBalance BalanceChecker) (*AccountPool, error) {
+	strategy = strings.ToLower(strategy)
+	if len(strategy) == 0 {
+		strategy = StrategyRoundRobin
+	}
+
+	if strategy != StrategyRoundRobin && strategy != StrategyLeastInFlight {
+		return nil, fmt.Errorf("unknown account selection strategy %s", strategy)
+	}
+
+	pool := &AccountPool{
+		strategy:      strategy,
+		checkBalance:  checkBalance,
+		checkInterval: DefaultBalanceCheckInterval,
+		inflight:      make(map[string]int),
+		balances:      make(map[string]balanceStatus),
+		checking:      make(map[string]bool),
+		now:           time.Now,
+	}
+
+	if err := pool.SetAccounts(accounts); err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// SetAccounts replaces the accounts of the pool
+// The in-flight selections of the removed accounts are still released normally
+func (p *AccountPool) SetAccounts(accounts []string) error {
+	if len(accounts) == 0 {
+		return errors.New("the account pool requires at least one account")
+	}
+
+	seen := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		if seen[account] {
+			return fmt.Errorf("duplicate account %s in the pool", account)
+		}
+
+		seen[account] = true
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.accounts = append([]string(nil), accounts...)
+	p.next = 0
+
+	return nil
+}
+
+// Accounts returns the accounts of the pool
+func (p *AccountPool) Accounts() []string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return append([]string(nil), p.accounts...)
+}
+
+// Acquire selects an account by the strategy of the pool
+// The returned release func must be called once the tx of the account is done
+func (p *AccountPool) Acquire() (account string, release func(), err error) {
+	p.refreshBalances()
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	candidates := make([]string, 0, len(p.accounts))
+
+	// the candidates are listed from the next account in turn
+	for i := range p.accounts {
+		candidate := p.accounts[(p.next+i)%len(p.accounts)]
+
+		if p.hasSufficientBalance(candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", nil, ErrNoAccountAvailable
+	}
+
+	account = candidates[0]
+
+	if p.strategy == StrategyLeastInFlight {
+		for _, candidate := range candidates[1:] {
+			if p.inflight[candidate] < p.inflight[account] {
+				account = candidate
+			}
+		}
+	}
+
+	for i, a := range p.accounts {
+		if a == account {
+			p.next = (i + 1) % len(p.accounts)
+			break
+		}
+	}
+
+	p.inflight[account]++
+
+	var once sync.Once
+
+	release = func() {
+		once.Do(func() {
+			p.mtx.Lock()
+			defer p.mtx.Unlock()
+
+			if p.inflight[account]--; p.inflight[account] <= 0 {
+				delete(p.inflight, account)
+			}
+		})
+	}
+
+	return account, release, nil
+}
+
+// InFlight returns the number of unreleased selections of the given account
+func (p *AccountPool) InFlight(account string) int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.inflight[account]
+}
+
+// refreshBalances checks the balances of the accounts not checked within the check interval
+// The checks query the chain outside the lock, so that the concurrent selections are not held
+// back; they rely on the last results meanwhile
+func (p *AccountPool) refreshBalances() {
+	if p.checkBalance == nil {
+		return
+	}
+
+	p.mtx.Lock()
+
+	var stale []string
+	for _, account := range p.accounts {
+		status, ok := p.balances[account]
+		if ok && p.now().Sub(status.checkedAt) < p.checkInterval || p.checking[account] {
+			continue
+		}
+
+		p.checking[account] = true
+		stale = append(stale, account)
+	}
+
+	p.mtx.Unlock()
+
+	for _, account := range stale {
+		// the account is considered sufficient if the check fails, not to stall the relaying
+		// on a transient query error
+		sufficient, err := p.checkBalance(account)
+		if err != nil {
+			sufficient = true
+		}
+
+		p.mtx.Lock()
+		p.balances[account] = balanceStatus{sufficient: sufficient, checkedAt: p.now()}
+		delete(p.checking, account)
+		p.mtx.Unlock()
+	}
+}
+
+// hasSufficientBalance returns the result of the last balance check of the account
+// The account never checked yet is considered sufficient
+func (p *AccountPool) hasSufficientBalance(account string) bool {
+	if status, ok := p.balances[account]; ok {
+		return status.sufficient
+	}
+
+	return true
+}
@@ -0,0 +1,158 @@
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountPoolRoundRobin(t *testing.T) {
+	pool, err := NewAccountPool(StrategyRoundRobin, []string{"a", "b", "c"}, nil)
+	require.NoError(t, err)
+
+	var selected []string
+
+	for i := 0; i < 6; i++ {
+		account, release, err := pool.Acquire()
+		require.NoError(t, err)
+
+		selected = append(selected, account)
+		release()
+	}
+
+	require.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, selected)
+}
+
+func TestAccountPoolLeastInFlight(t *testing.T) {
+	pool, err := NewAccountPool(StrategyLeastInFlight, []string{"a", "b"}, nil)
+	require.NoError(t, err)
+
+	a, releaseA, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "a", a)
+
+	b, releaseB, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "b", b)
+
+	releaseB()
+
+	// b has no tx in flight while a still has one
+	account, release, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "b", account)
+	release()
+
+	releaseA()
+	releaseA()
+	require.Equal(t, 0, pool.InFlight("a"))
+}
+
+func TestAccountPoolMinBalance(t *testing.T) {
+	funded := map[string]bool{"a": false, "b": true}
+
+	pool, err := NewAccountPool(StrategyRoundRobin, []string{"a", "b"}, func(account string) (bool, error) {
+		return funded[account], nil
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(0, 0)
+	pool.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		account, release, err := pool.Acquire()
+		require.NoError(t, err)
+		require.Equal(t, "b", account)
+		release()
+	}
+
+	funded["b"] = false
+
+	// the balances are cached within the check interval
+	account, release, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "b", account)
+	release()
+
+	now = now.Add(DefaultBalanceCheckInterval)
+
+	_, _, err = pool.Acquire()
+	require.Equal(t, ErrNoAccountAvailable, err)
+
+	funded["a"] = true
+	now = now.Add(DefaultBalanceCheckInterval)
+
+	account, _, err = pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "a", account)
+}
+
+func TestAccountPoolBalanceCheckError(t *testing.T) {
+	pool, err := NewAccountPool(StrategyRoundRobin, []string{"a"}, func(string) (bool, error) {
+		return false, errors.New("connection refused")
+	})
+	require.NoError(t, err)
+
+	account, _, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "a", account)
+}
+
+func TestAccountPoolSetAccounts(t *testing.T) {
+	pool, err := NewAccountPool("", []string{"a"}, nil)
+	require.NoError(t, err)
+
+	_, releaseA, err := pool.Acquire()
+	require.NoError(t, err)
+
+	require.Error(t, pool.SetAccounts(nil))
+	require.Error(t, pool.SetAccounts([]string{"b", "b"}))
+	require.NoError(t, pool.SetAccounts([]string{"b", "c"}))
+	require.Equal(t, []string{"b", "c"}, pool.Accounts())
+
+	account, _, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "b", account)
+
+	releaseA()
+	require.Equal(t, 0, pool.InFlight("a"))
+
+	_, err = NewAccountPool("random", []string{"a"}, nil)
+	require.Error(t, err)
+}
+
+func TestAccountPoolCheckOutsideLock(t *testing.T) {
+	checking := make(chan struct{})
+	unblock := make(chan struct{})
+
+	pool, err := NewAccountPool(StrategyRoundRobin, []string{"a", "b"}, func(account string) (bool, error) {
+		if account == "a" {
+			close(checking)
+			<-unblock
+		}
+
+		return true, nil
+	})
+	require.NoError(t, err)
+
+	acquired := make(chan string)
+	go func() {
+		account, _, _ := pool.Acquire()
+		acquired <- account
+	}()
+
+	// the selections go on while a balance check is stuck
+	<-checking
+
+	account, release, err := pool.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, "a", account)
+	release()
+
+	require.Equal(t, []string{"a", "b"}, pool.Accounts())
+
+	close(unblock)
+	require.Equal(t, "b", <-acquired)
+}
@@ -0,0 +1,221 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// default retry policy params
+const (
+	DefaultRetryMaxAttempts = 5
+	DefaultRetryBaseDelay   = 500 * time.Millisecond
+	DefaultRetryMaxDelay    = 30 * time.Second
+	DefaultRetryMultiplier  = 2.0
+)
+
+var (
+	// retryableErrPatterns are the error messages which indicate transient failures,
+	// the only ones retried besides the timeouts
+	retryableErrPatterns = []string{
+		"timeout",
+		"timed out",
+		"deadline exceeded",
+		"connection refused",
+		"connection reset",
+		"broken pipe",
+		"connection closed",
+		"eof",
+		"tx already in mempool",
+		"already exists in cache",
+		"mempool is full",
+		"account sequence mismatch",
+		"nonce too low",
+		"unavailable",
+		"bad gateway",
+		"too many requests",
+	}
+
+	// permanentErrPatterns are the error messages which indicate unrecoverable failures
+	permanentErrPatterns = []string{
+		"invalid signature",
+		"signature verification failed",
+		"unauthorized",
+		"insufficient fee",
+		"insufficient funds",
+		"out of gas",
+		"invalid request",
+		"execution failed",
+		"revert",
+	}
+)
+
+// RetryPolicy defines the policy of the jittered exponential backoff
+type RetryPolicy struct {
+	MaxAttempts int           `json:"max_attempts"` // maximum number of attempts, including the first one
+	BaseDelay   time.Duration `json:"base_delay"`   // delay before the first retry
+	MaxDelay    time.Duration `json:"max_delay"`    // upper bound of the delay
+	Multiplier  float64       `json:"multiplier"`   // growth factor of the delay
+
+	// IsRetryable classifies the error; IsRetryableError is used if nil
+	IsRetryable func(err error) bool `json:"-"`
+}
+
+// DefaultRetryPolicy returns the default retry policy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryMaxAttempts,
+		BaseDelay:   DefaultRetryBaseDelay,
+		MaxDelay:    DefaultRetryMaxDelay,
+		Multiplier:  DefaultRetryMultiplier,
+	}
+}
+
+// normalize fills the unset params with the default values
+func (p RetryPolicy) normalize() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryMaxAttempts
+	}
+
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryBaseDelay
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryMaxDelay
+	}
+
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultRetryMultiplier
+	}
+
+	if p.IsRetryable == nil {
+		p.IsRetryable = IsRetryableError
+	}
+
+	return p
+}
+
+// Delay returns the jittered delay before the given retry, starting from 1
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	p = p.normalize()
+
+	delay := float64(p.BaseDelay) * math.Pow(p.Multiplier, float64(retry-1))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	// pick a random delay in [delay/2, delay)
+	half := delay / 2
+
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// Retry performs the given operation until it succeeds, fails permanently,
+// exhausts the attempts or the context is done
+func Retry(ctx context.Context, op func() error, policy RetryPolicy) error {
+	policy = policy.normalize()
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = op(); err == nil {
+			return nil
+		}
+
+		if !policy.IsRetryable(err) {
+			return err
+		}
+
+		if attempt >= policy.MaxAttempts {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(policy.Delay(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s, last error: %w", ctx.Err(), err)
+
+		case <-timer.C:
+		}
+	}
+}
+
+// PermanentError marks an error as never retryable, whatever its message
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps the error so that it is never retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &PermanentError{Err: err}
+}
+
+// Error implements error
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanentError returns true if the error is marked permanent
+func IsPermanentError(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// IsRetryableError is the default error classifier
+// Only the timeouts and the errors matched as transient are retryable, the unknown ones
+// failing fast instead of being retried on a tx which may never succeed
+func IsRetryableError(err error) bool {
+	if err == nil || IsPermanentError(err) {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, p := range permanentErrPatterns {
+		if strings.Contains(msg, p) {
+			return false
+		}
+	}
+
+	for _, p := range retryableErrPatterns {
+		if strings.Contains(msg, p) {
+			return true
+		}
+	}
+
+	return false
+}
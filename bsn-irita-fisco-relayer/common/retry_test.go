@@ -0,0 +1,114 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		Multiplier:  2,
+	}
+}
+
+func TestRetry(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("tx already in mempool")
+		}
+		return nil
+	}, testRetryPolicy())
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = Retry(context.Background(), func() error {
+		attempts++
+		return errors.New("request timed out")
+	}, testRetryPolicy())
+	require.Error(t, err)
+	require.Equal(t, 4, attempts)
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), func() error {
+		attempts++
+		return errors.New("invalid signature")
+	}, testRetryPolicy())
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetryUnknownError(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), func() error {
+		attempts++
+		return errors.New("unknown service")
+	}, testRetryPolicy())
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	// only the timeouts and the transient failures are retried
+	require.True(t, IsRetryableError(fmt.Errorf("query failed: %w", context.DeadlineExceeded)))
+	require.True(t, IsRetryableError(&net.DNSError{Err: "i/o", IsTimeout: true}))
+	require.True(t, IsRetryableError(errors.New("dial tcp 127.0.0.1:20200: connect: connection refused")))
+	require.True(t, IsRetryableError(errors.New("account sequence mismatch, expected 5, got 4")))
+
+	require.False(t, IsRetryableError(nil))
+	require.False(t, IsRetryableError(context.Canceled))
+	require.False(t, IsRetryableError(errors.New("malformed output")))
+	require.False(t, IsRetryableError(errors.New("execution reverted: request timed out")))
+}
+
+func TestRetryMarkedPermanent(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), func() error {
+		attempts++
+		return Permanent(errors.New("broadcast timed out: account sequence mismatch"))
+	}, testRetryPolicy())
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+	require.False(t, IsSequenceMismatchError(err))
+	require.Nil(t, Permanent(nil))
+}
+
+func TestRetryContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	policy := testRetryPolicy()
+	policy.BaseDelay = time.Hour
+	policy.MaxDelay = time.Hour
+
+	attempts := 0
+	err := Retry(ctx, func() error {
+		attempts++
+		cancel()
+		return errors.New("connection refused")
+	}, policy)
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := testRetryPolicy()
+
+	for retry := 1; retry < 10; retry++ {
+		delay := policy.Delay(retry)
+		require.True(t, delay <= policy.MaxDelay)
+		require.True(t, delay >= policy.BaseDelay/2)
+	}
+}
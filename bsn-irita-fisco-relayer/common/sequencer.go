@@ -0,0 +1,200 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxInFlight is the default maximum number of unconfirmed txs per account
+const DefaultMaxInFlight = 1
+
+// sequenceMismatchErrPatterns are the error messages which indicate a stale sequence
+var sequenceMismatchErrPatterns = []string{
+	"account sequence mismatch",
+	"incorrect account sequence",
+	"invalid sequence",
+	"nonce too low",
+}
+
+// SubmitLimits defines the limits of the tx submissions per signing account
+type SubmitLimits struct {
+	MaxInFlight int           `json:"max_in_flight"` // maximum number of unconfirmed txs
+	MinInterval time.Duration `json:"min_interval"`  // minimum interval between two submissions, disabled if zero
+}
+
+// DefaultSubmitLimits returns the default submission limits
+func DefaultSubmitLimits() SubmitLimits {
+	return SubmitLimits{
+		MaxInFlight: DefaultMaxInFlight,
+	}
+}
+
+// SequenceFetcher fetches the next sequence of the given account from the chain
+type SequenceFetcher func(account string) (uint64, error)
+
+// BroadcastFunc broadcasts a tx with the given sequence
+// The returned confirm func, if not nil, waits for the tx confirmation
+type BroadcastFunc func(sequence uint64) (confirm func() error, err error)
+
+// AccountSequencer serializes the tx submissions per signing account:
+// the txs of one account are broadcast in order, each with the sequence
+// fetched-and-incremented under the account lock, while the txs of
+// different accounts are submitted concurrently
+type AccountSequencer struct {
+	limits SubmitLimits
+	fetch  SequenceFetcher
+
+	mtx      sync.Mutex
+	accounts map[string]*accountState
+}
+
+// accountState is the submission state of an account
+type accountState struct {
+	mtx          sync.Mutex    // held while assigning the sequence and broadcasting
+	slots        chan struct{} // unconfirmed txs
+	sequence     uint64        // next sequence to use
+	synced       bool          // indicates if the sequence is fetched from the chain
+	lastSubmitAt time.Time     // time of the last broadcast
+}
+
+// NewAccountSequencer constructs a new AccountSequencer instance
+// The sequence passed to the broadcast func is always zero if fetch is nil
+func NewAccountSequencer(limits SubmitLimits, fetch SequenceFetcher) *AccountSequencer {
+	if limits.MaxInFlight <= 0 {
+		limits.MaxInFlight = DefaultMaxInFlight
+	}
+
+	return &AccountSequencer{
+		limits:   limits,
+		fetch:    fetch,
+		accounts: make(map[string]*accountState),
+	}
+}
+
+// Submit broadcasts the tx of the given account in order and waits for its confirmation
+// On a sequence mismatch, the sequence is refetched and the tx broadcast once again; the
+// cached sequence is dropped if it still mismatches, so that the next submission refetches it
+func (s *AccountSequencer) Submit(ctx context.Context, account string, broadcast BroadcastFunc) error {
+	state := s.account(account)
+
+	select {
+	case state.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	defer func() { <-state.slots }()
+
+	confirm, err := s.broadcast(ctx, account, state, broadcast)
+	if err != nil || confirm == nil {
+		return err
+	}
+
+	return confirm()
+}
+
+// Invalidate drops the cached sequence of the given account
+func (s *AccountSequencer) Invalidate(account string) {
+	state := s.account(account)
+
+	state.mtx.Lock()
+	defer state.mtx.Unlock()
+
+	state.synced = false
+}
+
+// broadcast assigns the next sequence and broadcasts the tx under the account lock
+func (s *AccountSequencer) broadcast(
+	ctx context.Context,
+	account string,
+	state *accountState,
+	broadcast BroadcastFunc,
+) (func() error, error) {
+	state.mtx.Lock()
+	defer state.mtx.Unlock()
+
+	if wait := s.limits.MinInterval - time.Since(state.lastSubmitAt); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if !state.synced && s.fetch != nil {
+		sequence, err := s.fetch(account)
+		if err != nil {
+			return nil, err
+		}
+
+		state.sequence = sequence
+		state.synced = true
+	}
+
+	confirm, err := broadcast(state.sequence)
+	state.lastSubmitAt = time.Now()
+
+	// the sequence drifted, e.g. by a tx sent out of band; it is refetched under the
+	// account lock, so the resync is done once for all the waiting submissions
+	if IsSequenceMismatchError(err) && s.fetch != nil {
+		sequence, fetchErr := s.fetch(account)
+		if fetchErr != nil {
+			state.synced = false
+			return nil, err
+		}
+
+		state.sequence = sequence
+
+		confirm, err = broadcast(state.sequence)
+		state.lastSubmitAt = time.Now()
+	}
+
+	if err != nil {
+		if IsSequenceMismatchError(err) {
+			state.synced = false
+		}
+
+		return nil, err
+	}
+
+	state.sequence++
+
+	return confirm, nil
+}
+
+// account returns the submission state of the given account
+func (s *AccountSequencer) account(account string) *accountState {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state, ok := s.accounts[account]
+	if !ok {
+		state = &accountState{
+			slots: make(chan struct{}, s.limits.MaxInFlight),
+		}
+
+		s.accounts[account] = state
+	}
+
+	return state
+}
+
+// IsSequenceMismatchError returns true if the error indicates a stale account sequence
+// The permanent errors never do, their tx not to be broadcast at another sequence
+func IsSequenceMismatchError(err error) bool {
+	if err == nil || IsPermanentError(err) {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, pattern := range sequenceMismatchErrPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,221 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockAccountChain accepts the txs of an account only with the expected sequence
+type mockAccountChain struct {
+	mtx        sync.Mutex
+	sequences  map[string]uint64
+	accepted   map[string][]uint64
+	mismatches int
+}
+
+func newMockAccountChain() *mockAccountChain {
+	return &mockAccountChain{
+		sequences: make(map[string]uint64),
+		accepted:  make(map[string][]uint64),
+	}
+}
+
+func (c *mockAccountChain) fetch(account string) (uint64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.sequences[account], nil
+}
+
+func (c *mockAccountChain) broadcast(account string, sequence uint64) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if sequence != c.sequences[account] {
+		c.mismatches++
+		return fmt.Errorf("account sequence mismatch, expected %d, got %d", c.sequences[account], sequence)
+	}
+
+	c.sequences[account]++
+	c.accepted[account] = append(c.accepted[account], sequence)
+
+	return nil
+}
+
+func TestAccountSequencerConcurrentRelays(t *testing.T) {
+	chain := newMockAccountChain()
+	sequencer := NewAccountSequencer(SubmitLimits{MaxInFlight: 4}, chain.fetch)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := sequencer.Submit(context.Background(), "relayer", func(sequence uint64) (func() error, error) {
+				if err := chain.broadcast("relayer", sequence); err != nil {
+					return nil, err
+				}
+
+				return func() error {
+					time.Sleep(time.Millisecond)
+					return nil
+				}, nil
+			})
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	require.Zero(t, chain.mismatches)
+	require.Len(t, chain.accepted["relayer"], 50)
+
+	for i, sequence := range chain.accepted["relayer"] {
+		require.Equal(t, uint64(i), sequence)
+	}
+}
+
+func TestAccountSequencerResync(t *testing.T) {
+	chain := newMockAccountChain()
+	sequencer := NewAccountSequencer(DefaultSubmitLimits(), chain.fetch)
+
+	submit := func() error {
+		return sequencer.Submit(context.Background(), "relayer", func(sequence uint64) (func() error, error) {
+			return nil, chain.broadcast("relayer", sequence)
+		})
+	}
+
+	require.NoError(t, submit())
+
+	// a tx sent out of band by the same account
+	require.NoError(t, chain.broadcast("relayer", 1))
+
+	// the stale sequence is recovered without failing the tx
+	require.NoError(t, submit())
+	require.NoError(t, submit())
+	require.Equal(t, []uint64{0, 1, 2, 3}, chain.accepted["relayer"])
+	require.Equal(t, 1, chain.mismatches)
+}
+
+func TestAccountSequencerResyncOnce(t *testing.T) {
+	chain := newMockAccountChain()
+	sequencer := NewAccountSequencer(DefaultSubmitLimits(), chain.fetch)
+
+	// the tx keeps mismatching, so it is retried only once
+	var attempts []uint64
+	err := sequencer.Submit(context.Background(), "relayer", func(sequence uint64) (func() error, error) {
+		attempts = append(attempts, sequence)
+		return nil, errors.New("incorrect account sequence")
+	})
+	require.True(t, IsSequenceMismatchError(err))
+	require.Len(t, attempts, 2)
+
+	// the waiting submissions share the resync of the first one
+	chain.sequences["relayer"] = 5
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := sequencer.Submit(context.Background(), "relayer", func(sequence uint64) (func() error, error) {
+				return nil, chain.broadcast("relayer", sequence)
+			})
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	require.Len(t, chain.accepted["relayer"], 10)
+	require.Equal(t, uint64(5), chain.accepted["relayer"][0])
+	require.Zero(t, chain.mismatches)
+}
+
+func TestAccountSequencerFailedBroadcast(t *testing.T) {
+	chain := newMockAccountChain()
+	sequencer := NewAccountSequencer(DefaultSubmitLimits(), chain.fetch)
+
+	err := sequencer.Submit(context.Background(), "relayer", func(sequence uint64) (func() error, error) {
+		return nil, errors.New("insufficient fee")
+	})
+	require.Error(t, err)
+
+	// the sequence is not consumed by the rejected tx
+	err = sequencer.Submit(context.Background(), "relayer", func(sequence uint64) (func() error, error) {
+		return nil, chain.broadcast("relayer", sequence)
+	})
+	require.NoError(t, err)
+}
+
+func TestAccountSequencerAccountsConcurrent(t *testing.T) {
+	sequencer := NewAccountSequencer(DefaultSubmitLimits(), nil)
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		_ = sequencer.Submit(context.Background(), "account-a", func(uint64) (func() error, error) {
+			close(blocked)
+			<-release
+			return nil, nil
+		})
+	}()
+
+	<-blocked
+
+	// another account is not blocked by the pending submission
+	done := make(chan struct{})
+	go func() {
+		_ = sequencer.Submit(context.Background(), "account-b", func(uint64) (func() error, error) {
+			return nil, nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("submission of another account is blocked")
+	}
+
+	// the same account is limited by the max in-flight
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := sequencer.Submit(ctx, "account-a", func(uint64) (func() error, error) {
+		return nil, nil
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	close(release)
+}
+
+func TestAccountSequencerMinInterval(t *testing.T) {
+	sequencer := NewAccountSequencer(SubmitLimits{MinInterval: 20 * time.Millisecond}, nil)
+
+	var times []time.Time
+
+	for i := 0; i < 3; i++ {
+		err := sequencer.Submit(context.Background(), "relayer", func(uint64) (func() error, error) {
+			times = append(times, time.Now())
+			return nil, nil
+		})
+		require.NoError(t, err)
+	}
+
+	for i := 1; i < len(times); i++ {
+		require.True(t, times[i].Sub(times[i-1]) >= 20*time.Millisecond)
+	}
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultRequestTimeout is the default deadline of a single RPC call
+const DefaultRequestTimeout = 15 * time.Second
+
+// WithTimeout returns a child context of the given one which is done after the timeout
+// DefaultRequestTimeout is used if the timeout is not positive
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, normalizeTimeout(timeout))
+}
+
+// normalizeTimeout falls back to DefaultRequestTimeout for the non-positive timeout
+func normalizeTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultRequestTimeout
+	}
+
+	return timeout
+}
+
+// CallWithTimeout performs the given call which does not accept a context,
+// returning once it finishes, the timeout elapses or the context is done
+// The call keeps running in the background after the timeout, the result of
+// which is discarded
+func CallWithTimeout(ctx context.Context, timeout time.Duration, call func() error) error {
+	timeout = normalizeTimeout(timeout)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- call()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("request timed out after %s: %w", timeout, ctx.Err())
+		}
+
+		return ctx.Err()
+	}
+}
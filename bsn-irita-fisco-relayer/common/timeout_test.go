@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallWithTimeout(t *testing.T) {
+	err := CallWithTimeout(context.Background(), time.Second, func() error {
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+
+	block := make(chan struct{})
+	defer close(block)
+
+	start := time.Now()
+
+	err = CallWithTimeout(context.Background(), 50*time.Millisecond, func() error {
+		<-block
+		return nil
+	})
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.True(t, IsRetryableError(err))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = CallWithTimeout(ctx, time.Second, func() error {
+		<-block
+		return nil
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestWithTimeoutDefault(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(DefaultRequestTimeout), deadline, time.Second)
+}
@@ -0,0 +1,321 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces the header values when printed
+const redacted = "<redacted>"
+
+// Headers are the HTTP headers added to the RPC requests by name
+// The values usually carry credentials, so they are redacted when printed
+type Headers map[string]string
+
+// String implements fmt.Stringer, redacting the values
+func (h Headers) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+":"+redacted)
+	}
+
+	return "map[" + strings.Join(pairs, " ") + "]"
+}
+
+// GoString implements fmt.GoStringer, redacting the values
+func (h Headers) GoString() string {
+	return h.String()
+}
+
+// TransportConfig defines the HTTP transport options of the RPC endpoints,
+// e.g. to reach the nodes through an authenticated gateway
+type TransportConfig struct {
+	Headers            Headers `json:"headers,omitempty" mapstructure:"headers"`                           // headers added to the requests
+	TLSCAFile          string  `json:"tls_ca_file,omitempty" mapstructure:"tls_ca_file"`                   // PEM CA certificates verifying the endpoints, the system ones if empty
+	InsecureSkipVerify bool    `json:"insecure_skip_verify,omitempty" mapstructure:"insecure_skip_verify"` // skips verifying the endpoint certificates, for testing only
+}
+
+// Enabled returns true if any option is set
+func (c TransportConfig) Enabled() bool {
+	return len(c.Headers) != 0 || c.HasTLS()
+}
+
+// Merge returns the options overridden by those set in the given ones, the headers being merged
+func (c TransportConfig) Merge(override TransportConfig) TransportConfig {
+	if len(override.Headers) != 0 {
+		headers := make(Headers, len(c.Headers)+len(override.Headers))
+
+		for name, value := range c.Headers {
+			headers[name] = value
+		}
+
+		for name, value := range override.Headers {
+			headers[name] = value
+		}
+
+		c.Headers = headers
+	}
+
+	if len(override.TLSCAFile) != 0 {
+		c.TLSCAFile = override.TLSCAFile
+	}
+
+	if override.InsecureSkipVerify {
+		c.InsecureSkipVerify = true
+	}
+
+	return c
+}
+
+// HasTLS returns true if any TLS option is set
+func (c TransportConfig) HasTLS() bool {
+	return len(c.TLSCAFile) != 0 || c.InsecureSkipVerify
+}
+
+// Validate validates the header names and values and the CA file
+func (c TransportConfig) Validate() error {
+	for name, value := range c.Headers {
+		if len(name) == 0 || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+
+		// the value is never printed
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("invalid value of header %s", name)
+		}
+	}
+
+	_, err := c.TLSConfig()
+
+	return err
+}
+
+// TLSConfig builds the TLS client config of the options
+func (c TransportConfig) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if len(c.TLSCAFile) != 0 {
+		pem, err := ioutil.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %s", err)
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the CA file %s", c.TLSCAFile)
+		}
+
+		config.RootCAs = roots
+	}
+
+	return config, nil
+}
+
+// forwarderUser is the user name of the forwarder credentials
+const forwarderUser = "relayer"
+
+// Forwarder is a loopback endpoint forwarding the requests to an RPC endpoint with the transport
+// options applied, for the clients which can not be configured otherwise
+// The endpoint embeds a random secret required by the forwarder, so that the other local users
+// can not reach the RPC endpoint through it. It is never printed
+type Forwarder struct {
+	Endpoint string // loopback endpoint, the target itself if no option is set
+
+	key    string       // key of the forwarder in the running ones
+	server *http.Server // nil if no option is set
+	refs   int          // connections on the forwarder, guarded by forwardersMtx
+}
+
+// forwarders are the running forwarders by target and options, shared by the connections
+// to the same endpoint until all are closed
+var (
+	forwardersMtx sync.Mutex
+	forwarders    = map[string]*Forwarder{}
+)
+
+// ForwardRPC returns the forwarder to the given RPC endpoint with the transport options applied,
+// to be closed along with the connection on it
+// The endpoint is the target unchanged if no option is set. The ws and wss targets are
+// forwarded from a ws endpoint, the others from an http one. A target without scheme
+// is reached over https if a TLS option is set, http otherwise
+func ForwardRPC(target string, config TransportConfig) (*Forwarder, error) {
+	if !config.Enabled() {
+		return &Forwarder{Endpoint: target}, nil
+	}
+
+	fingerprint, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	key := target + "|" + string(fingerprint)
+
+	forwardersMtx.Lock()
+	defer forwardersMtx.Unlock()
+
+	forwarder, ok := forwarders[key]
+	if !ok {
+		forwarder, err = startForwarder(target, config)
+		if err != nil {
+			return nil, err
+		}
+
+		forwarder.key = key
+		forwarders[key] = forwarder
+	}
+
+	forwarder.refs++
+
+	return forwarder, nil
+}
+
+// Close releases the forwarder, which stops once released by all the connections on it
+func (f *Forwarder) Close() error {
+	if f.server == nil {
+		return nil
+	}
+
+	forwardersMtx.Lock()
+	defer forwardersMtx.Unlock()
+
+	if f.refs == 0 {
+		return nil
+	}
+
+	f.refs--
+	if f.refs > 0 {
+		return nil
+	}
+
+	delete(forwarders, f.key)
+
+	// the connections kept alive are closed too
+	return f.server.Close()
+}
+
+// startForwarder forwards the authenticated requests of a new loopback listener to the given
+// target, the WebSocket upgrades included
+// The secret is passed as the password of the endpoint, which the HTTP clients send as
+// basic auth and strip from their errors
+func startForwarder(target string, config TransportConfig) (*Forwarder, error) {
+	if !strings.Contains(target, "://") {
+		if config.HasTLS() {
+			target = "https://" + target
+		} else {
+			target = "http://" + target
+		}
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC endpoint: %s", err)
+	}
+
+	localScheme := "http"
+
+	switch targetURL.Scheme {
+	case "http", "https":
+	case "ws":
+		targetURL.Scheme, localScheme = "http", "ws"
+	case "wss":
+		targetURL.Scheme, localScheme = "https", "ws"
+	default:
+		return nil, fmt.Errorf("invalid RPC endpoint: unsupported scheme %s", targetURL.Scheme)
+	}
+
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// hex encoded, as the FISCO SDK lowers the endpoint
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate the forwarder secret: %s", err)
+	}
+
+	password := hex.EncodeToString(secret)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			req.URL.Path = joinPath(targetURL.Path, req.URL.Path)
+			req.Host = targetURL.Host
+
+			// the forwarder credentials never reach the target
+			req.Header.Del("Authorization")
+
+			for name, value := range config.Headers {
+				req.Header.Set(name, value)
+			}
+		},
+		Transport: transport,
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != forwarderUser || subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		proxy.ServeHTTP(w, req)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for forwarding: %s", err)
+	}
+
+	server := &http.Server{Handler: handler}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	endpoint := url.URL{
+		Scheme: localScheme,
+		User:   url.UserPassword(forwarderUser, password),
+		Host:   listener.Addr().String(),
+	}
+
+	return &Forwarder{Endpoint: endpoint.String(), server: server}, nil
+}
+
+// joinPath joins the target path and the request path
+func joinPath(target, path string) string {
+	if len(path) == 0 || path == "/" {
+		if len(target) == 0 {
+			return "/"
+		}
+
+		return target
+	}
+
+	return strings.TrimSuffix(target, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,198 @@
+package common
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "Bearer s3cr3t"
+
+// echoService is the RPC service of the test gateway
+type echoService struct{}
+
+// Echo returns the given message
+func (echoService) Echo(msg string) string {
+	return msg
+}
+
+// newTestGateway starts a TLS RPC server requiring the test token, over HTTP and WebSocket
+func newTestGateway(t *testing.T) *httptest.Server {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("test", echoService{}))
+
+	ws := server.WebsocketHandler([]string{"*"})
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			ws.ServeHTTP(w, r)
+			return
+		}
+
+		server.ServeHTTP(w, r)
+	}))
+}
+
+// echo calls the echo method of the RPC server
+func echo(client *rpc.Client) error {
+	var result string
+	if err := client.Call(&result, "test_echo", "hello"); err != nil {
+		return err
+	}
+
+	if result != "hello" {
+		return fmt.Errorf("unexpected echo %s", result)
+	}
+
+	return nil
+}
+
+func TestForwardRPC(t *testing.T) {
+	gateway := newTestGateway(t)
+	defer gateway.Close()
+
+	dir, err := ioutil.TempDir("", "transport")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: gateway.Certificate().Raw}), 0600))
+
+	config := TransportConfig{
+		Headers:   Headers{"Authorization": testToken},
+		TLSCAFile: caFile,
+	}
+	require.NoError(t, config.Validate())
+
+	// the headers and the CA are applied to the HTTP requests
+	forwarder, err := ForwardRPC(gateway.URL, config)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(forwarder.Endpoint, "http://relayer:"))
+
+	client, err := rpc.Dial(forwarder.Endpoint)
+	require.NoError(t, err)
+	require.NoError(t, echo(client))
+	client.Close()
+
+	// and to the WebSocket connections
+	wsForwarder, err := ForwardRPC(strings.Replace(gateway.URL, "https://", "wss://", 1), config)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(wsForwarder.Endpoint, "ws://relayer:"))
+
+	client, err = rpc.DialContext(context.Background(), wsForwarder.Endpoint)
+	require.NoError(t, err)
+	require.NoError(t, echo(client))
+	client.Close()
+	require.NoError(t, wsForwarder.Close())
+
+	// the forwarder requires its secret
+	endpoint, err := url.Parse(forwarder.Endpoint)
+	require.NoError(t, err)
+
+	endpoint.User = url.UserPassword("relayer", "guess")
+
+	client, err = rpc.Dial(endpoint.String())
+	require.NoError(t, err)
+	err = echo(client)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), forwarder.Endpoint)
+	client.Close()
+
+	endpoint.User = nil
+
+	resp, err := http.Post(endpoint.String(), "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// the forwarder is shared by the same target and options, until closed by all
+	again, err := ForwardRPC(gateway.URL, config)
+	require.NoError(t, err)
+	require.Equal(t, forwarder.Endpoint, again.Endpoint)
+
+	require.NoError(t, again.Close())
+
+	client, err = rpc.Dial(forwarder.Endpoint)
+	require.NoError(t, err)
+	require.NoError(t, echo(client))
+	client.Close()
+
+	require.NoError(t, forwarder.Close())
+	require.NoError(t, forwarder.Close())
+
+	client, err = rpc.Dial(forwarder.Endpoint)
+	require.NoError(t, err)
+	require.Error(t, echo(client))
+	client.Close()
+
+	again, err = ForwardRPC(gateway.URL, config)
+	require.NoError(t, err)
+	require.NotEqual(t, forwarder.Endpoint, again.Endpoint)
+	require.NoError(t, again.Close())
+
+	// the gateway rejects the requests without the token
+	forwarder, err = ForwardRPC(gateway.URL, TransportConfig{TLSCAFile: caFile})
+	require.NoError(t, err)
+
+	client, err = rpc.Dial(forwarder.Endpoint)
+	require.NoError(t, err)
+	require.Error(t, echo(client))
+	client.Close()
+	require.NoError(t, forwarder.Close())
+
+	// the gateway certificate is not trusted without the CA
+	forwarder, err = ForwardRPC(gateway.URL, TransportConfig{Headers: Headers{"Authorization": testToken}})
+	require.NoError(t, err)
+
+	client, err = rpc.Dial(forwarder.Endpoint)
+	require.NoError(t, err)
+	require.Error(t, echo(client))
+	client.Close()
+	require.NoError(t, forwarder.Close())
+
+	// unless the verification is skipped
+	forwarder, err = ForwardRPC(gateway.URL, TransportConfig{Headers: Headers{"Authorization": testToken}, InsecureSkipVerify: true})
+	require.NoError(t, err)
+
+	client, err = rpc.Dial(forwarder.Endpoint)
+	require.NoError(t, err)
+	require.NoError(t, echo(client))
+	client.Close()
+	require.NoError(t, forwarder.Close())
+
+	// the endpoint is unchanged without options
+	forwarder, err = ForwardRPC(gateway.URL, TransportConfig{})
+	require.NoError(t, err)
+	require.Equal(t, gateway.URL, forwarder.Endpoint)
+	require.NoError(t, forwarder.Close())
+}
+
+func TestTransportConfigRedacted(t *testing.T) {
+	config := TransportConfig{Headers: Headers{"Authorization": testToken}}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		printed := fmt.Sprintf(format, config)
+		require.NotContains(t, printed, "s3cr3t", format)
+		require.Contains(t, printed, "Authorization", format)
+	}
+
+	require.Error(t, TransportConfig{Headers: Headers{"Bad Name": "x"}}.Validate())
+	require.Error(t, TransportConfig{Headers: Headers{"X-Token": "a\r\nb"}}.Validate())
+	require.Error(t, TransportConfig{TLSCAFile: "/nonexistent/ca.pem"}.Validate())
+}
@@ -2,14 +2,57 @@ package common
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// MustGetHomeDir gets the user home directory
+const (
+	// DestIDDelimiter is the delimiter between the segments of a dest ID
+	DestIDDelimiter = "-"
+
+	// MaxChainParamLength is the maximum length of each segment of a dest ID
+	MaxChainParamLength = 64
+
+	// EnvRelayerHome is the environment variable to override the home directory
+	EnvRelayerHome = "RELAYER_HOME"
+)
+
+// GetHomeDir gets the relayer home directory
+// The directory specified by RELAYER_HOME takes precedence over the user home directory
+func GetHomeDir() (string, error) {
+	homeDir := strings.TrimSpace(os.Getenv(EnvRelayerHome))
+	if len(homeDir) == 0 {
+		userHomeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the home directory: %s; set %s to override it", err, EnvRelayerHome)
+		}
+
+		return userHomeDir, nil
+	}
+
+	if homeDir == "~" || strings.HasPrefix(homeDir, "~/") {
+		userHomeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s %q: %s", EnvRelayerHome, homeDir, err)
+		}
+
+		homeDir = filepath.Join(userHomeDir, homeDir[1:])
+	}
+
+	if err := validateWritableDir(homeDir); err != nil {
+		return "", fmt.Errorf("invalid %s %q: %s", EnvRelayerHome, homeDir, err)
+	}
+
+	return homeDir, nil
+}
+
+// MustGetHomeDir gets the relayer home directory
 // Panic if an error occurs
 func MustGetHomeDir() string {
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := GetHomeDir()
 	if err != nil {
 		panic(err)
 	}
@@ -17,22 +60,211 @@ func MustGetHomeDir() string {
 	return homeDir
 }
 
+// validateWritableDir checks if the given path is an existing and writable directory
+func validateWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+
+	f, err := ioutil.TempFile(dir, ".relayer-")
+	if err != nil {
+		return fmt.Errorf("directory not writable: %s", err)
+	}
+
+	f.Close()
+
+	return os.Remove(f.Name())
+}
+
+// Hex2BigInt converts the given hex string to a big integer without truncation
+// The string may carry a "-" sign before an optional "0x" or "0X" prefix
+func Hex2BigInt(hex string) (*big.Int, error) {
+	s := strings.TrimSpace(hex)
+
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+
+	if len(s) == 0 || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		return nil, fmt.Errorf("invalid hex string: %q", hex)
+	}
+
+	i, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		return nil, fmt.Errorf("cannot parse hex string %q to Int", hex)
+	}
+
+	if neg {
+		i.Neg(i)
+	}
+
+	return i, nil
+}
+
+// Hex2TwosComplement converts the given hex word of the given bit width to the signed big integer
+// it encodes in two's complement, e.g. an ABI int256 word
+// The word carries no sign, and is rejected if wider than the bit width
+func Hex2TwosComplement(hex string, bits uint) (*big.Int, error) {
+	if bits == 0 {
+		return nil, fmt.Errorf("invalid bit width 0")
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(hex), "-") {
+		return nil, fmt.Errorf("invalid two's complement word %q: unexpected sign", hex)
+	}
+
+	i, err := Hex2BigInt(hex)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.BitLen() > int(bits) {
+		return nil, fmt.Errorf("hex string %q overflows %d bits", hex, bits)
+	}
+
+	// the words with the top bit set are negative
+	if i.Bit(int(bits)-1) == 1 {
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), bits))
+	}
+
+	return i, nil
+}
+
 // Hex2Decimal converts the given hex string to a decimal number
+// An error is returned if the value does not fit in int64
 func Hex2Decimal(hex string) (int64, error) {
-	i := new(big.Int)
+	i, err := Hex2BigInt(hex)
+	if err != nil {
+		return -1, err
+	}
 
-	i, ok := i.SetString(hex, 16)
-	if !ok {
-		return -1, fmt.Errorf("Cannot parse hex string to Int")
+	if !i.IsInt64() {
+		return -1, fmt.Errorf("hex string %q overflows int64", hex)
 	}
 
 	return i.Int64(), nil
 }
 
+// ToBaseUnits converts the given decimal amount in display units to base units, scaling it by 10^decimals
+// No rounding is performed: an amount with more fractional digits than decimals is rejected,
+// as are the malformed amounts. The amount may carry a sign, e.g. "-1.5"
+func ToBaseUnits(amount string, decimals uint8) (*big.Int, error) {
+	s := strings.TrimSpace(amount)
+
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+
+		if len(fracPart) == 0 {
+			return nil, fmt.Errorf("invalid amount %q: empty fraction", amount)
+		}
+	}
+
+	if len(intPart) == 0 || !isDigits(intPart) || !isDigits(fracPart) {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if len(fracPart) > int(decimals) {
+		return nil, fmt.Errorf("amount %q has more than %d fractional digits", amount, decimals)
+	}
+
+	// right-pad the fraction to the decimals, so that the digits are the base units
+	digits := intPart + fracPart + strings.Repeat("0", int(decimals)-len(fracPart))
+
+	i, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if neg {
+		i.Neg(i)
+	}
+
+	return i, nil
+}
+
+// FromBaseUnits renders the given amount in base units as a decimal amount in display units
+// The conversion is exact; the trailing zeros of the fraction are trimmed, e.g. "1.5" rather
+// than "1.500000", and a whole amount has no fraction
+func FromBaseUnits(amount *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(amount).String()
+
+	// left-pad so that there is at least one integer digit
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	split := len(digits) - int(decimals)
+	intPart, fracPart := digits[:split], strings.TrimRight(digits[split:], "0")
+
+	s := intPart
+	if len(fracPart) != 0 {
+		s += "." + fracPart
+	}
+
+	if amount.Sign() < 0 {
+		s = "-" + s
+	}
+
+	return s
+}
+
+// isDigits returns true if the given string consists of decimal digits only
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
 // GetChainID returns the unique chain id from the specified chain params
 func GetDestID(chainType string, groupID string, chainID string) string {
-	if len(groupID) == 0 {
-		return fmt.Sprintf("%s-%s", chainType, chainID)
+	return newDestID(chainType, groupID, chainID).String()
+}
+
+// GetDestIDChecked is the same as GetDestID except that the chain params are validated first
+func GetDestIDChecked(chainType string, groupID string, chainID string) (string, error) {
+	destID, err := NewDestID(chainType, groupID, chainID)
+	if err != nil {
+		return "", err
 	}
-	return fmt.Sprintf("%s-%s-%s", chainType, groupID, chainID)
+
+	return destID.String(), nil
+}
+
+// NormalizeDestID returns the canonical form of the given dest ID, lowercasing the
+// case-insensitive chain type while preserving the group and chain IDs exactly
+func NormalizeDestID(destID string) string {
+	return DestID(destID).Normalize().String()
+}
+
+// EqualDestID returns true if the given dest IDs identify the same chain
+func EqualDestID(a string, b string) bool {
+	return NormalizeDestID(a) == NormalizeDestID(b)
+}
+
+// ParseDestID splits the given dest ID into the chain params
+// It is the inverse of GetDestID
+func ParseDestID(destID string) (chainType string, groupID string, chainID string, err error) {
+	return DestID(destID).Split()
 }
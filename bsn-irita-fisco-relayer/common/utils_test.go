@@ -0,0 +1,213 @@
+package common
+
+import (
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHex2BigInt(t *testing.T) {
+	// 256-bit token transfer amount
+	expected, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+
+	i, err := Hex2BigInt("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	require.NoError(t, err)
+	require.Equal(t, 0, expected.Cmp(i))
+
+	i, err = Hex2BigInt("  0X1a  ")
+	require.NoError(t, err)
+	require.Equal(t, int64(26), i.Int64())
+
+	i, err = Hex2BigInt("-0x10")
+	require.NoError(t, err)
+	require.Equal(t, int64(-16), i.Int64())
+
+	for _, invalid := range []string{"", "   ", "0x", "-", "0xzz", "0x-1", "--1", "+1"} {
+		_, err = Hex2BigInt(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestHex2TwosComplement(t *testing.T) {
+	// int256 -1
+	i, err := Hex2TwosComplement("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 256)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), i.Int64())
+
+	i, err = Hex2TwosComplement("0x7fffffffffffffff", 64)
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), i.Int64())
+
+	i, err = Hex2TwosComplement("0x8000000000000000", 64)
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MinInt64), i.Int64())
+
+	// the words without the top bit set are positive
+	i, err = Hex2TwosComplement("0xff", 256)
+	require.NoError(t, err)
+	require.Equal(t, int64(255), i.Int64())
+
+	i, err = Hex2TwosComplement(" 0Xfe ", 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2), i.Int64())
+
+	for _, invalid := range []string{"", "0x", "-0x1", "0x1ff", "0xzz"} {
+		_, err = Hex2TwosComplement(invalid, 8)
+		require.Error(t, err, invalid)
+	}
+
+	_, err = Hex2TwosComplement("0x1", 0)
+	require.Error(t, err)
+}
+
+func TestHex2Decimal(t *testing.T) {
+	d, err := Hex2Decimal("7fffffffffffffff")
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), d)
+
+	// math.MaxInt64 + 1
+	_, err = Hex2Decimal("0x8000000000000000")
+	require.Error(t, err)
+
+	d, err = Hex2Decimal("-0x8000000000000000")
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MinInt64), d)
+
+	_, err = Hex2Decimal("")
+	require.Error(t, err)
+}
+
+func TestToBaseUnits(t *testing.T) {
+	testCases := []struct {
+		amount   string
+		decimals uint8
+		expected string
+	}{
+		{"1.5", 6, "1500000"},
+		{"0.000001", 6, "1"},
+		{"1.500000", 6, "1500000"},
+		{"1.50", 6, "1500000"},
+		{"1000", 6, "1000000000"},
+		{"-2.25", 6, "-2250000"},
+		{"12345.678901234567890123", 18, "12345678901234567890123"},
+		{"0.000000000000000001", 18, "1"},
+		{"7", 0, "7"},
+	}
+
+	for _, tc := range testCases {
+		i, err := ToBaseUnits(tc.amount, tc.decimals)
+		require.NoError(t, err, tc.amount)
+		require.Equal(t, tc.expected, i.String(), tc.amount)
+	}
+
+	for _, invalid := range []string{"", "-", ".5", "1.", "1.2.3", "1e6", "0x10", "1,5", "--1", " 1 .5"} {
+		_, err := ToBaseUnits(invalid, 6)
+		require.Error(t, err, invalid)
+	}
+
+	// no rounding of the excess fractional digits, even if zeros
+	_, err := ToBaseUnits("1.0000001", 6)
+	require.Error(t, err)
+
+	_, err = ToBaseUnits("1.5000000", 6)
+	require.Error(t, err)
+
+	_, err = ToBaseUnits("1.5", 0)
+	require.Error(t, err)
+}
+
+func TestFromBaseUnits(t *testing.T) {
+	testCases := []struct {
+		amount   string
+		decimals uint8
+		expected string
+	}{
+		{"1500000", 6, "1.5"},
+		{"1000000", 6, "1"},
+		{"1", 6, "0.000001"},
+		{"0", 6, "0"},
+		{"-2250000", 6, "-2.25"},
+		{"12345678901234567890123", 18, "12345.678901234567890123"},
+		{"1000000000000000000", 18, "1"},
+		{"10", 0, "10"},
+	}
+
+	for _, tc := range testCases {
+		amount, _ := new(big.Int).SetString(tc.amount, 10)
+		require.Equal(t, tc.expected, FromBaseUnits(amount, tc.decimals), tc.amount)
+
+		// the rendered amount converts back to the same base units
+		i, err := ToBaseUnits(tc.expected, tc.decimals)
+		require.NoError(t, err)
+		require.Equal(t, 0, amount.Cmp(i))
+	}
+
+	// an on-chain hex amount of an 18-decimal token rendered for a callback
+	amount, err := Hex2BigInt("0x14d1120d7b160000")
+	require.NoError(t, err)
+	require.Equal(t, "1.5", FromBaseUnits(amount, 18))
+}
+
+func TestParseDestID(t *testing.T) {
+	testCases := []struct {
+		chainType string
+		groupID   string
+		chainID   string
+	}{
+		{"fisco", "1", "5"},
+		{"fisco", "", "5"},
+		{"eth", "", "ropsten"},
+	}
+
+	for _, tc := range testCases {
+		chainType, groupID, chainID, err := ParseDestID(GetDestID(tc.chainType, tc.groupID, tc.chainID))
+		require.NoError(t, err)
+		require.Equal(t, tc.chainType, chainType)
+		require.Equal(t, tc.groupID, groupID)
+		require.Equal(t, tc.chainID, chainID)
+	}
+
+	for _, invalid := range []string{"", "fisco", "a-b-c-d", "fisco-", "-1-5"} {
+		_, _, _, err := ParseDestID(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestGetHomeDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relayer-home")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv(EnvRelayerHome, os.Getenv(EnvRelayerHome))
+
+	os.Setenv(EnvRelayerHome, dir)
+	homeDir, err := GetHomeDir()
+	require.NoError(t, err)
+	require.Equal(t, dir, homeDir)
+
+	os.Setenv(EnvRelayerHome, filepath.Join(dir, "nonexistent"))
+	_, err = GetHomeDir()
+	require.Error(t, err)
+	require.Panics(t, func() { MustGetHomeDir() })
+}
+
+func TestValidateChainParams(t *testing.T) {
+	require.NoError(t, ValidateChainParams("fisco", "1", "5"))
+	require.NoError(t, ValidateChainParams("fisco", "", "5"))
+
+	require.Error(t, ValidateChainParams("", "1", "5"))
+	require.Error(t, ValidateChainParams("fisco", "1", ""))
+	require.Error(t, ValidateChainParams("fisco-bcos", "1", "5"))
+	require.Error(t, ValidateChainParams("fisco", "1-2", "5"))
+	require.Error(t, ValidateChainParams("fisco", "1", strings.Repeat("a", MaxChainParamLength+1)))
+
+	destID, err := GetDestIDChecked("fisco", "1", "5")
+	require.NoError(t, err)
+	require.Equal(t, "fisco-1-5", destID)
+}
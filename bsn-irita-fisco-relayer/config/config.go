@@ -2,8 +2,14 @@ package config
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"relayer/common"
 )
 
 const (
@@ -11,24 +17,115 @@ const (
 
 	ConfigKeyAppChainType = "base.app_chain_type"
 	ConfigKeyStorePath    = "base.store_path"
+	ConfigKeyLogLevel     = "base.log_level"
+	ConfigKeyLogFormat    = "base.log_format"
+	ConfigKeyCheckpoint   = "base.checkpoint_path"
+
+	ConfigKeyStateBackend = "base.state_backend"
+	ConfigKeyStatePath    = "base.state_path"
+
+	ConfigKeyShutdownTimeout = "base.shutdown_timeout"
+	DefaultShutdownTimeout   = 30 * time.Second
+
+	ConfigKeyDedupCapacity = "base.dedup_capacity"
+	ConfigKeyDedupTTL      = "base.dedup_ttl"
+
+	ConfigKeyDeadLetterPath = "base.dead_letter_path"
+
+	ConfigKeyAuditPath       = "base.audit.path"
+	ConfigKeyAuditMaxSize    = "base.audit.max_size"
+	ConfigKeyAuditMaxBackups = "base.audit.max_backups"
+
+	ConfigKeyBreakerThreshold   = "base.circuit_breaker.failure_threshold"
+	ConfigKeyBreakerCooldown    = "base.circuit_breaker.cooldown"
+	ConfigKeyBreakerMaxBuffered = "base.circuit_breaker.max_buffered"
+
+	ConfigKeyQueueCapacity = "base.event_queue.capacity"
+	ConfigKeyQueueLowWater = "base.event_queue.low_water"
+
+	ConfigKeyAsyncEnabled  = "base.async_response.enabled"
+	ConfigKeyAsyncInterval = "base.async_response.interval"
+	ConfigKeyAsyncTimeout  = "base.async_response.timeout"
+
+	ConfigKeyOrderingEnabled  = "base.ordering.enabled"
+	ConfigKeyOrderingKey      = "base.ordering.key"
+	ConfigKeyOrderingServices = "base.ordering.services"
+
+	ConfigKeyHealthStaleness = "health.staleness_threshold"
+	ConfigKeyHealthChains    = "health.chains"
+	ConfigKeyHealthHeartbeat = "health.heartbeat_interval"
+
+	ConfigKeyServiceEncoders     = "service.encoders"
+	ConfigKeyServiceInputSchemas = "service.input_schemas"
+
+	ConfigKeyRoutingChains = "routing.chains"
+	ConfigKeyRoutingRoutes = "routing.routes"
+
+	ConfigKeyMetricsEnabled = "metrics.enabled"
+	ConfigKeyMetricsAddress = "metrics.address"
+
+	ConfigKeyLatencyLogSample = "metrics.latency_log_sample"
+
+	ConfigKeyNotifyWebhookURL    = "notifications.webhook_url"
+	ConfigKeyNotifyHeaders       = "notifications.headers"
+	ConfigKeyNotifyEvents        = "notifications.events"
+	ConfigKeyNotifyTimeout       = "notifications.timeout"
+	ConfigKeyNotifyMaxPending    = "notifications.max_pending"
+	ConfigKeyNotifyCheckInterval = "notifications.check_interval"
 
 	DefaultStorePath = ".db"
+
+	RetryPrefix      = "retry"
+	RetryMaxAttempts = "max_attempts"
+	RetryBaseDelay   = "base_delay"
+	RetryMaxDelay    = "max_delay"
+	RetryMultiplier  = "multiplier"
+
+	SubmitPrefix      = "submission"
+	SubmitMaxInFlight = "max_in_flight"
+	SubmitMinInterval = "min_interval"
+
+	RequestTimeout = "request_timeout"
 )
 
 type BaseConfigI interface {
 	PrintConfig()
+
+	// ChainDestIDs returns the dest IDs of the chains configured, keyed by config key
+	ChainDestIDs() map[string]common.DestID
 }
 
-// LoadYAMLConfig loads the YAML config file
+// LoadYAMLConfig loads the YAML config file merged with the RELAYER_ env vars
+// The precedence is env vars over the config file over the built-in defaults.
+// The merged settings are validated, so that a bad env var fails the loading.
 func LoadYAMLConfig(configFileName string) (*viper.Viper, error) {
+	file := viper.New()
+
+	file.SetConfigFile(configFileName)
+	file.SetConfigType("yaml")
+
+	err := file.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the config file: %s", err)
+	}
+
+	settings := settingsOf(file)
+
+	if err := overlayEnv(settings, environ()); err != nil {
+		return nil, fmt.Errorf("failed to apply the env vars: %s", err)
+	}
+
+	if err := validateSettings(settings); err != nil {
+		return nil, fmt.Errorf("invalid config: %s", err)
+	}
+
 	v := viper.New()
 
 	v.SetConfigFile(configFileName)
 	v.SetConfigType("yaml")
 
-	err := v.ReadInConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read the config file: %s", err)
+	if err := v.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("failed to merge the env vars: %s", err)
 	}
 
 	return v, nil
@@ -38,3 +135,105 @@ func LoadYAMLConfig(configFileName string) (*viper.Viper, error) {
 func GetConfigKey(prefix string, key string) string {
 	return fmt.Sprintf("%s.%s", prefix, key)
 }
+
+// LoadRetryPolicy loads the retry policy under the given prefix
+// The unset params are filled with the default values
+func LoadRetryPolicy(v *viper.Viper, prefix string) common.RetryPolicy {
+	policy := common.DefaultRetryPolicy()
+
+	key := func(k string) string {
+		return GetConfigKey(prefix, GetConfigKey(RetryPrefix, k))
+	}
+
+	if v.IsSet(key(RetryMaxAttempts)) {
+		policy.MaxAttempts = v.GetInt(key(RetryMaxAttempts))
+	}
+
+	if v.IsSet(key(RetryBaseDelay)) {
+		policy.BaseDelay = v.GetDuration(key(RetryBaseDelay))
+	}
+
+	if v.IsSet(key(RetryMaxDelay)) {
+		policy.MaxDelay = v.GetDuration(key(RetryMaxDelay))
+	}
+
+	if v.IsSet(key(RetryMultiplier)) {
+		policy.Multiplier = v.GetFloat64(key(RetryMultiplier))
+	}
+
+	return policy
+}
+
+// LoadSubmitLimits loads the tx submission limits under the given prefix
+// The unset params are filled with the default values
+func LoadSubmitLimits(v *viper.Viper, prefix string) common.SubmitLimits {
+	limits := common.DefaultSubmitLimits()
+
+	key := func(k string) string {
+		return GetConfigKey(prefix, GetConfigKey(SubmitPrefix, k))
+	}
+
+	if v.IsSet(key(SubmitMaxInFlight)) {
+		limits.MaxInFlight = v.GetInt(key(SubmitMaxInFlight))
+	}
+
+	if v.IsSet(key(SubmitMinInterval)) {
+		limits.MinInterval = v.GetDuration(key(SubmitMinInterval))
+	}
+
+	return limits
+}
+
+// LoadRequestTimeout loads the RPC request timeout under the given prefix
+// DefaultRequestTimeout is used if unset
+func LoadRequestTimeout(v *viper.Viper, prefix string) time.Duration {
+	key := GetConfigKey(prefix, RequestTimeout)
+
+	if !v.IsSet(key) {
+		return common.DefaultRequestTimeout
+	}
+
+	return v.GetDuration(key)
+}
+
+// ChangedKeys returns the keys whose values differ between the given configs,
+// excluding the ones under any of the given prefixes
+func ChangedKeys(old *viper.Viper, new *viper.Viper, excludedPrefixes ...string) []string {
+	keys := make(map[string]bool)
+
+	for _, key := range old.AllKeys() {
+		keys[key] = true
+	}
+
+	for _, key := range new.AllKeys() {
+		keys[key] = true
+	}
+
+	changed := make([]string, 0)
+
+	for key := range keys {
+		if hasAnyPrefix(key, excludedPrefixes) || reflect.DeepEqual(old.Get(key), new.Get(key)) {
+			continue
+		}
+
+		changed = append(changed, key)
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// hasAnyPrefix returns true if the key is or is under any of the given prefixes
+// The prefixes are case insensitive like the viper keys
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.ToLower(prefix)
+
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"relayer/common"
+)
+
+const (
+	// EnvPrefix is the prefix of the env vars overriding the config
+	EnvPrefix = "RELAYER_"
+
+	// EnvPathDelimiter separates the path segments of an env var setting a key absent from the file
+	EnvPathDelimiter = "__"
+)
+
+// envReplacer maps the key characters not allowed in the env var names
+var envReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// EnvVarName returns the env var overriding the config key of the given path,
+// e.g. RELAYER_FISCO_CHAINS_FISCO_1_1_CONFIRMATION_DEPTH for fisco.chains.fisco-1-1.confirmation_depth
+func EnvVarName(path ...string) string {
+	return EnvPrefix + strings.ToUpper(envReplacer.Replace(strings.Join(path, "_")))
+}
+
+// overlayEnv merges the given env vars onto the settings of the config file
+// A key present in the file is overridden by the env var named by EnvVarName;
+// a key absent from the file is set by an env var whose path segments are
+// separated by EnvPathDelimiter, e.g. RELAYER_FISCO__CHAINS__FISCO-1-1__CONFIRMATION_DEPTH.
+// An overriding value must be of the type of the file value, and an array is
+// replaced as a whole by a JSON array or a comma separated list of strings.
+// The env vars matching no key, like RELAYER_HOME, are left to their own consumers.
+func overlayEnv(settings map[string]interface{}, environ []string) error {
+	paths := make(map[string][]string)
+	if err := collectPaths(settings, nil, paths); err != nil {
+		return err
+	}
+
+	// the env vars are applied in order to make the result deterministic
+	sort.Strings(environ)
+
+	for _, kv := range environ {
+		pair := strings.SplitN(kv, "=", 2)
+		if len(pair) != 2 || !strings.HasPrefix(pair[0], EnvPrefix) {
+			continue
+		}
+
+		name, value := pair[0], pair[1]
+
+		path, ok := paths[name]
+		if !ok {
+			if !strings.Contains(name, EnvPathDelimiter) {
+				continue
+			}
+
+			path = strings.Split(strings.ToLower(strings.TrimPrefix(name, EnvPrefix)), EnvPathDelimiter)
+		}
+
+		current, _ := lookupPath(settings, path)
+
+		parsed, err := parseEnvValue(current, value)
+		if err != nil {
+			return fmt.Errorf("invalid value of %s from %s: %s", strings.Join(path, "."), name, err)
+		}
+
+		if err := setPath(settings, path, parsed); err != nil {
+			return fmt.Errorf("failed to apply %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// collectPaths indexes the paths of all the leaf keys by the env var names
+// An error is returned if two keys map to the same env var, e.g. fisco-1 and fisco_1,
+// as the env var could not tell which one it overrides
+func collectPaths(settings map[string]interface{}, prefix []string, paths map[string][]string) error {
+	for key, value := range settings {
+		path := append(append([]string{}, prefix...), key)
+
+		if sub, ok := value.(map[string]interface{}); ok && len(sub) != 0 {
+			if err := collectPaths(sub, path, paths); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name := EnvVarName(path...)
+
+		if other, ok := paths[name]; ok {
+			keys := []string{strings.Join(other, "."), strings.Join(path, ".")}
+			sort.Strings(keys)
+
+			return fmt.Errorf("config keys %s and %s both map to the env var %s", keys[0], keys[1], name)
+		}
+
+		paths[name] = path
+	}
+
+	return nil
+}
+
+// lookupPath returns the value of the given path
+func lookupPath(settings map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = settings
+
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// setPath sets the value of the given path, creating the missing parents
+func setPath(settings map[string]interface{}, path []string, value interface{}) error {
+	m := settings
+
+	for i, key := range path[:len(path)-1] {
+		switch sub := m[key].(type) {
+		case map[string]interface{}:
+			m = sub
+
+		case nil:
+			child := make(map[string]interface{})
+			m[key] = child
+			m = child
+
+		default:
+			return fmt.Errorf("%s is not a map", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	m[path[len(path)-1]] = value
+
+	return nil
+}
+
+// parseEnvValue parses the env value to the type of the current value
+// The value of a key absent from the file is kept as a string unless it is a JSON array
+func parseEnvValue(current interface{}, value string) (interface{}, error) {
+	switch c := current.(type) {
+	case []interface{}:
+		return parseEnvArray(value)
+
+	case bool:
+		return strconv.ParseBool(value)
+
+	case int:
+		return strconv.Atoi(value)
+
+	case int64:
+		return strconv.ParseInt(value, 10, 64)
+
+	case float64:
+		return strconv.ParseFloat(value, 64)
+
+	case string:
+		if _, err := time.ParseDuration(c); err == nil {
+			if _, err := time.ParseDuration(value); err != nil {
+				return nil, err
+			}
+		}
+
+		return value, nil
+
+	case nil:
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			return parseEnvArray(value)
+		}
+
+		return value, nil
+
+	default:
+		return nil, fmt.Errorf("%T can not be overridden", current)
+	}
+}
+
+// parseEnvArray parses the env value as a JSON array, or a comma separated list of strings otherwise
+func parseEnvArray(value string) ([]interface{}, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "[") {
+		var array []interface{}
+		if err := json.Unmarshal([]byte(value), &array); err != nil {
+			return nil, err
+		}
+
+		return array, nil
+	}
+
+	array := make([]interface{}, 0)
+
+	for _, elem := range strings.Split(value, ",") {
+		if elem = strings.TrimSpace(elem); len(elem) != 0 {
+			array = append(array, elem)
+		}
+	}
+
+	return array, nil
+}
+
+// requiredKeys are the config keys every command relies on
+var requiredKeys = []string{ConfigKeyAppChainType, "hub.chain_id"}
+
+// validateSettings validates the settings merged with the env vars
+// It checks the required keys, the dest IDs of the chains configured under
+// <app_chain_type>.chains, as returned by BaseConfigI.ChainDestIDs, and the
+// shape of the hub account pool.
+func validateSettings(settings map[string]interface{}) error {
+	for _, key := range requiredKeys {
+		value, _ := lookupPath(settings, strings.Split(key, "."))
+		if len(strings.TrimSpace(fmt.Sprint(valueOrEmpty(value)))) == 0 {
+			return fmt.Errorf("%s is required", key)
+		}
+	}
+
+	appChainType, _ := lookupPath(settings, strings.Split(ConfigKeyAppChainType, "."))
+
+	if chains, ok := lookupPath(settings, []string{fmt.Sprint(appChainType), "chains"}); ok && chains != nil {
+		overrides, ok := chains.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.chains must be a map by dest ID", appChainType)
+		}
+
+		for destID := range overrides {
+			if err := common.DestID(destID).Validate(); err != nil {
+				return fmt.Errorf("invalid chain %s.chains.%s: %s", appChainType, destID, err)
+			}
+		}
+	}
+
+	if accounts, ok := lookupPath(settings, []string{"hub", "accounts"}); ok && accounts != nil {
+		return validateAccounts(accounts)
+	}
+
+	return nil
+}
+
+// validateAccounts checks that the account pool is a list of accounts, each with a key name
+func validateAccounts(accounts interface{}) error {
+	list, ok := accounts.([]interface{})
+	if !ok {
+		return fmt.Errorf("hub.accounts must be a list of accounts")
+	}
+
+	for i, account := range list {
+		var keyName interface{}
+
+		switch fields := account.(type) {
+		case map[string]interface{}:
+			keyName = fields["key_name"]
+		case map[interface{}]interface{}:
+			keyName = fields["key_name"]
+		default:
+			return fmt.Errorf("hub.accounts[%d] must be an account with key_name and passphrase", i)
+		}
+
+		if len(strings.TrimSpace(fmt.Sprint(valueOrEmpty(keyName)))) == 0 {
+			return fmt.Errorf("hub.accounts[%d]: key_name is required", i)
+		}
+	}
+
+	return nil
+}
+
+// valueOrEmpty returns the empty string in place of a missing value
+func valueOrEmpty(value interface{}) interface{} {
+	if value == nil {
+		return ""
+	}
+
+	return value
+}
+
+// settingsOf returns the raw settings of the config
+// The nested maps are retrieved by the top level keys to keep the map keys containing dots
+func settingsOf(v *viper.Viper) map[string]interface{} {
+	settings := make(map[string]interface{})
+
+	for _, key := range v.AllKeys() {
+		top := strings.SplitN(key, ".", 2)[0]
+
+		if _, ok := settings[top]; !ok {
+			settings[top] = v.Get(top)
+		}
+	}
+
+	return settings
+}
+
+// environ returns the env vars with the config prefix
+func environ() []string {
+	vars := make([]string, 0)
+
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, EnvPrefix) {
+			vars = append(vars, kv)
+		}
+	}
+
+	return vars
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+base:
+    app_chain_type: fisco
+    log_level: info
+    shutdown_timeout: 30s
+    dedup_capacity: 10000
+metrics:
+    enabled: true
+hub:
+    chain_id: irita-hub
+    accounts:
+        - key_name: node0
+          passphrase: "12345678"
+fisco:
+    nodes:
+        fisco1.bsnbase.com: 127.0.0.1:20200
+`
+
+func TestLoadYAMLConfigWithEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testConfig), 0600))
+
+	env := map[string]string{
+		"RELAYER_BASE_LOG_LEVEL":                             "debug",
+		"RELAYER_BASE_DEDUP_CAPACITY":                        "20",
+		"RELAYER_METRICS_ENABLED":                            "false",
+		"RELAYER_HUB_ACCOUNTS":                               `[{"key_name":"node1","passphrase":"abc"},{"key_name":"node2","passphrase":"def"}]`,
+		"RELAYER_FISCO_NODES_FISCO1_BSNBASE_COM":             "127.0.0.1:20201",
+		"RELAYER_FISCO__CHAINS__fisco-1-1__SUBSCRIBE_BLOCKS": "true",
+		"RELAYER_KEY_FISCO_1_1":                              "ignored",
+	}
+
+	for name, value := range env {
+		require.NoError(t, os.Setenv(name, value))
+		defer os.Unsetenv(name)
+	}
+
+	v, err := LoadYAMLConfig(path)
+	require.NoError(t, err)
+
+	require.Equal(t, "debug", v.GetString(ConfigKeyLogLevel))
+	require.Equal(t, 20, v.GetInt(ConfigKeyDedupCapacity))
+	require.Equal(t, 30*time.Second, v.GetDuration(ConfigKeyShutdownTimeout))
+	require.False(t, v.GetBool(ConfigKeyMetricsEnabled))
+	require.Equal(t, "irita-hub", v.GetString("hub.chain_id"))
+
+	// the array is replaced as a whole
+	accounts := v.Get("hub.accounts").([]interface{})
+	require.Len(t, accounts, 2)
+	require.Equal(t, "node1", accounts[0].(map[string]interface{})["key_name"])
+
+	// the map keys containing dots are kept
+	require.Equal(t, map[string]string{"fisco1.bsnbase.com": "127.0.0.1:20201"}, v.GetStringMapString("fisco.nodes"))
+
+	require.True(t, v.GetBool("fisco.chains.fisco-1-1.subscribe_blocks"))
+	require.False(t, v.IsSet("key"))
+}
+
+func TestLoadYAMLConfigInvalidEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testConfig), 0600))
+
+	invalid := map[string]string{
+		"RELAYER_BASE_APP_CHAIN_TYPE":             "",
+		"RELAYER_HUB_CHAIN_ID":                    " ",
+		"RELAYER_HUB_ACCOUNTS":                    `[{"passphrase":"abc"}]`,
+		"RELAYER_FISCO__CHAINS__fisco__RPC":       "http://127.0.0.1:8545",
+		"RELAYER_FISCO__CHAINS__fisco-1-1-1__RPC": "http://127.0.0.1:8545",
+	}
+
+	for name, value := range invalid {
+		require.NoError(t, os.Setenv(name, value))
+
+		_, err := LoadYAMLConfig(path)
+		require.Error(t, err, name)
+
+		require.NoError(t, os.Unsetenv(name))
+	}
+
+	_, err = LoadYAMLConfig(path)
+	require.NoError(t, err)
+}
+
+func TestValidateAccounts(t *testing.T) {
+	require.NoError(t, validateAccounts([]interface{}{
+		map[string]interface{}{"key_name": "node1"},
+		map[interface{}]interface{}{"key_name": "node2"},
+	}))
+
+	require.EqualError(t, validateAccounts("node1,node2"), "hub.accounts must be a list of accounts")
+	require.EqualError(t, validateAccounts([]interface{}{"node1"}), "hub.accounts[0] must be an account with key_name and passphrase")
+	require.EqualError(t, validateAccounts([]interface{}{map[string]interface{}{"key_name": ""}}), "hub.accounts[0]: key_name is required")
+}
+
+func TestOverlayEnvInvalidValue(t *testing.T) {
+	settings := map[string]interface{}{
+		"base": map[string]interface{}{
+			"dedup_capacity":   10000,
+			"shutdown_timeout": "30s",
+		},
+	}
+
+	require.Error(t, overlayEnv(settings, []string{"RELAYER_BASE_DEDUP_CAPACITY=many"}))
+	require.Error(t, overlayEnv(settings, []string{"RELAYER_BASE_SHUTDOWN_TIMEOUT=30"}))
+	require.Error(t, overlayEnv(settings, []string{"RELAYER_BASE__DEDUP_CAPACITY__MAX=1"}))
+
+	require.NoError(t, overlayEnv(settings, []string{"RELAYER_BASE_SHUTDOWN_TIMEOUT=1m"}))
+	require.Equal(t, "1m", settings["base"].(map[string]interface{})["shutdown_timeout"])
+}
+
+func TestOverlayEnvCollision(t *testing.T) {
+	settings := map[string]interface{}{
+		"fisco": map[string]interface{}{
+			"chains": map[string]interface{}{
+				"fisco-1-1": map[string]interface{}{"confirmation_depth": 1},
+				"fisco_1_1": map[string]interface{}{"confirmation_depth": 2},
+			},
+		},
+	}
+
+	// the keys differing only in the characters mapped to underscores are ambiguous
+	err := overlayEnv(settings, nil)
+	require.EqualError(t, err, "config keys fisco.chains.fisco-1-1.confirmation_depth and fisco.chains.fisco_1_1.confirmation_depth both map to the env var RELAYER_FISCO_CHAINS_FISCO_1_1_CONFIRMATION_DEPTH")
+}
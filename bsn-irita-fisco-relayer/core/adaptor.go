@@ -3,6 +3,7 @@ package core
 // ResponseAdaptor is the wrapped response struct of Irita-Hub
 type ResponseAdaptor struct {
 	StatusCode  int
+	ServiceName string
 	Result      string
 	Output      string
 }
@@ -21,6 +22,11 @@ func (r ResponseAdaptor) GetErrMsg() string {
 	}
 }
 
+// GetServiceName implements ResponseI
+func (r ResponseAdaptor) GetServiceName() string {
+	return r.ServiceName
+}
+
 // GetOutput implements ResponseI
 func (r ResponseAdaptor) GetOutput() string {
 	switch r.StatusCode {
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"relayer/common"
+)
+
+const (
+	// DefaultAuditFile is the audit file relative to the home directory
+	DefaultAuditFile = ".relayer/audit.jsonl"
+
+	// DefaultAuditMaxSize is the default size in bytes beyond which the audit file is rotated
+	DefaultAuditMaxSize = 100 * 1024 * 1024
+	// DefaultAuditMaxBackups is the default number of the rotated audit files kept
+	DefaultAuditMaxBackups = 10
+)
+
+// relay decisions of the audit records
+const (
+	DecisionAccepted     = "accepted"      // submitted to the Hub, before the checkpoint advances
+	DecisionRelayed      = "relayed"       // response sent to the source app chain
+	DecisionFiltered     = "filtered"      // rejected by the event filters
+	DecisionDropped      = "dropped"       // duplicate, or failed without a dead letter queue
+	DecisionDeadLettered = "dead_lettered" // failed permanently, see the dead letter
+)
+
+// ErrAuditFailed is returned when the decision on an event could not be recorded, the event
+// being read again later
+var ErrAuditFailed = errors.New("failed to write the audit record")
+
+// AuditRecord is an entry of the audit trail of the relay decisions
+type AuditRecord struct {
+	Timestamp    time.Time `json:"timestamp"`
+	RequestID    string    `json:"request_id"`
+	SourceID     string    `json:"source_id"` // dest ID of the source app chain
+	DestID       string    `json:"dest_id"`   // dest ID of the request
+	Decision     string    `json:"decision"`
+	SourceTxHash string    `json:"source_tx_hash,omitempty"` // tx emitting the request
+	TxHash       string    `json:"tx_hash,omitempty"`        // resulting response tx, for the relayed requests
+	Reason       string    `json:"reason,omitempty"`         // cause of the failure, for the dead-lettered requests
+}
+
+// AuditTrail defines the interface to keep the append-only record of the relay decisions
+type AuditTrail interface {
+	// Record appends the record durably
+	Record(record AuditRecord) error
+}
+
+// FileAuditLog is an AuditTrail implementation writing newline-delimited JSON to a file
+// Each record is synced before returning. The file is renamed to <path>.1 once it exceeds
+// the maximum size, the existing backups being shifted and the oldest one removed
+type FileAuditLog struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	mtx        sync.Mutex
+}
+
+var _ AuditTrail = (*FileAuditLog)(nil)
+
+// NewFileAuditLog constructs a new FileAuditLog instance on the given file
+// The defaults are used for the non-positive maximum size and backups
+func NewFileAuditLog(path string, maxSize int64, maxBackups int) (*FileAuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the audit directory: %s", err)
+	}
+
+	if maxSize <= 0 {
+		maxSize = DefaultAuditMaxSize
+	}
+
+	if maxBackups <= 0 {
+		maxBackups = DefaultAuditMaxBackups
+	}
+
+	return &FileAuditLog{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}, nil
+}
+
+// DefaultAuditPath returns the default audit file under the relayer home directory
+func DefaultAuditPath() (string, error) {
+	homeDir, err := common.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, DefaultAuditFile), nil
+}
+
+// Record implements AuditTrail
+func (l *FileAuditLog) Record(record AuditRecord) error {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	bz = append(bz, '\n')
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if err := l.rotateIfNeeded(int64(len(bz))); err != nil {
+		return fmt.Errorf("failed to rotate the audit file: %s", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(bz); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// rotateIfNeeded rotates the file if appending the given size exceeds the maximum size
+// A single record larger than the maximum size is still written to the empty file
+func (l *FileAuditLog) rotateIfNeeded(size int64) error {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Size() == 0 || info.Size()+size <= l.maxSize {
+		return nil
+	}
+
+	if err := os.Remove(l.backupPath(l.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(l.backupPath(i), l.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(l.path, l.backupPath(1))
+}
+
+// backupPath returns the path of the i-th rotated file, the first being the most recent
+func (l *FileAuditLog) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", l.path, i)
+}
+
+// audit appends the decision on the request to the audit trail if it is enabled
+// The records are written synchronously, so that the decisions made while scanning
+// a block are durable before its checkpoint is saved: the failure is returned for the
+// listener to read the event again. Nothing is recorded in the dry run
+func (r *Relayer) audit(chainID string, request InterchainRequest, decision string, txHash string, cause error) error {
+	if r.Audit == nil || r.DryRun {
+		return nil
+	}
+
+	record := AuditRecord{
+		Timestamp:    time.Now().UTC(),
+		RequestID:    request.ID,
+		SourceID:     r.sourceDestID(chainID).String(),
+		DestID:       request.GetDestID().String(),
+		Decision:     decision,
+		SourceTxHash: request.TxHash,
+		TxHash:       txHash,
+	}
+
+	if cause != nil {
+		record.Reason = cause.Error()
+	}
+
+	if err := r.Audit.Record(record); err != nil {
+		r.requestLogger(chainID, request.ID).Errorf("failed to write the audit record: %s", err)
+		return fmt.Errorf("%w: %s", ErrAuditFailed, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readAuditFile reads the audit records of the given file
+func readAuditFile(t *testing.T, path string) []AuditRecord {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []AuditRecord
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+
+		records = append(records, record)
+	}
+
+	require.NoError(t, scanner.Err())
+
+	return records
+}
+
+func TestFileAuditLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+
+	record := AuditRecord{RequestID: "req-0", Decision: DecisionRelayed}
+	bz, err := json.Marshal(record)
+	require.NoError(t, err)
+
+	// two records per file
+	audit, err := NewFileAuditLog(path, int64(2*(len(bz)+1)), 2)
+	require.NoError(t, err)
+
+	for _, id := range []string{"req-1", "req-2", "req-3", "req-4", "req-5", "req-6", "req-7"} {
+		record.RequestID = id
+		require.NoError(t, audit.Record(record))
+	}
+
+	ids := func(records []AuditRecord) (ids []string) {
+		for _, r := range records {
+			ids = append(ids, r.RequestID)
+		}
+
+		return ids
+	}
+
+	require.Equal(t, []string{"req-7"}, ids(readAuditFile(t, path)))
+	require.Equal(t, []string{"req-5", "req-6"}, ids(readAuditFile(t, path+".1")))
+	require.Equal(t, []string{"req-3", "req-4"}, ids(readAuditFile(t, path+".2")))
+
+	// the oldest file beyond the backups is removed
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestHandleAudited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+
+	schema, err := ParseInputSchema([]byte(testInputSchema))
+	require.NoError(t, err)
+
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.Audit, err = NewFileAuditLog(path, 0, 0)
+	require.NoError(t, err)
+	r.DeadLetters, err = NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+	require.NoError(t, err)
+	r.Schemas = NewSchemaRegistry("oracle")
+	r.Schemas.Register("oracle", schema)
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	request := InterchainRequest{ID: "req-1", DestChainType: "eth", DestChainID: "1", CallData: []byte(`{"symbol":"BTC","amount":1}`)}
+
+	r.Dedup.Seen("req-1")
+	require.NoError(t, r.HandleInterchainRequest("1", request, "0x01"))
+
+	request.ID = "req-2"
+	request.CallData = []byte(`{"symbol":"BTC"}`)
+	require.Error(t, r.HandleInterchainRequest("1", request, "0x02"))
+
+	records := readAuditFile(t, path)
+	require.Len(t, records, 2)
+
+	require.Equal(t, "req-1", records[0].RequestID)
+	require.Equal(t, DecisionDropped, records[0].Decision)
+	require.Equal(t, "fisco-1-1", records[0].SourceID)
+	require.Equal(t, "eth-1", records[0].DestID)
+	require.Equal(t, "0x01", records[0].SourceTxHash)
+
+	require.Equal(t, "req-2", records[1].RequestID)
+	require.Equal(t, DecisionDeadLettered, records[1].Decision)
+	require.Contains(t, records[1].Reason, "field $.amount: required")
+
+	// the dry run leaves no audit record
+	r.DryRun = true
+	r.Dedup.Seen("req-3")
+
+	request.ID = "req-3"
+	require.NoError(t, r.HandleInterchainRequest("1", request, "0x03"))
+	require.Len(t, readAuditFile(t, path), 2)
+}
+
+// failingAuditTrail is an AuditTrail failing to write the records
+type failingAuditTrail struct{}
+
+func (failingAuditTrail) Record(AuditRecord) error {
+	return errors.New("no space left on device")
+}
+
+func TestAuditFailureRereadsEvent(t *testing.T) {
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.Audit = failingAuditTrail{}
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	request := InterchainRequest{ID: "req-1", DestChainType: "eth", DestChainID: "1"}
+
+	// the event is read again without being seen
+	err := r.HandleInterchainRequest("1", request, "0x01")
+	require.True(t, errors.Is(err, ErrAuditFailed))
+	require.True(t, IsBackpressure(err))
+	require.False(t, r.Dedup.Contains("req-1"))
+
+	// as is the duplicate
+	r.Dedup.Seen("req-2")
+
+	request.ID = "req-2"
+	require.True(t, IsBackpressure(r.HandleInterchainRequest("1", request, "0x02")))
+}
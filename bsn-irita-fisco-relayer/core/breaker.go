@@ -0,0 +1,338 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"relayer/common"
+	"relayer/logging"
+	"relayer/metrics"
+)
+
+// circuit states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+const (
+	// DefaultBreakerCooldown is the default time a circuit stays open before probing the chain
+	DefaultBreakerCooldown = 30 * time.Second
+
+	// DefaultBreakerMaxBuffered is the default number of responses waiting for a circuit to close
+	DefaultBreakerMaxBuffered = 100
+)
+
+// ErrCircuitOpen is returned when a submission is rejected by an open circuit
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerAlert is called once the circuit of a chain opens
+// It is called with the breaker locked, so it must not block or use the breaker
+type BreakerAlert func(name string, failures int)
+
+// BreakerConfig defines the circuit breaker params
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures opening the circuit, disabled if not positive
+	Cooldown         time.Duration // time the circuit stays open before a probe
+	MaxBuffered      int           // responses waiting for the circuit to close, beyond which they are rejected
+}
+
+// normalize fills the unset params with the default values
+func (c BreakerConfig) normalize() BreakerConfig {
+	if c.Cooldown <= 0 {
+		c.Cooldown = DefaultBreakerCooldown
+	}
+
+	if c.MaxBuffered <= 0 {
+		c.MaxBuffered = DefaultBreakerMaxBuffered
+	}
+
+	return c
+}
+
+// CircuitBreaker stops the submissions to a chain after consecutive failures
+// The circuit opens once the failure threshold is reached, rejecting the submissions
+// fast, and half-opens after the cooldown to let a single probe through: the circuit
+// closes if the probe succeeds, and opens again otherwise.
+// Only the transient failures are counted, the permanent ones proving the node responsive.
+// It is safe for concurrent use
+type CircuitBreaker struct {
+	name   string
+	config BreakerConfig
+
+	mtx      sync.Mutex
+	state    string
+	failures int           // consecutive transient failures
+	openedAt time.Time     // time when the circuit opened
+	probing  bool          // set while the probe of the half-open circuit is in flight
+	waiting  int           // submissions waiting for the circuit to close
+	changed  chan struct{} // closed on every state change
+
+	onOpen BreakerAlert // called once the circuit opens, if set
+	now    func() time.Time
+}
+
+// NewCircuitBreaker constructs a new CircuitBreaker instance for the given chain
+func NewCircuitBreaker(name string, config BreakerConfig) *CircuitBreaker {
+	b := &CircuitBreaker{
+		name:    name,
+		config:  config.normalize(),
+		state:   CircuitClosed,
+		changed: make(chan struct{}),
+		now:     time.Now,
+	}
+
+	metrics.CircuitState.WithLabelValues(name).Set(circuitStateValue(CircuitClosed))
+
+	return b
+}
+
+// Enabled returns true if the breaker is enabled
+func (b *CircuitBreaker) Enabled() bool {
+	return b.config.FailureThreshold > 0
+}
+
+// State returns the current state of the circuit
+func (b *CircuitBreaker) State() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.cool()
+
+	return b.state
+}
+
+// Allow reports whether a submission can be made, returning ErrCircuitOpen otherwise
+// A half-open circuit allows the probe only
+func (b *CircuitBreaker) Allow() error {
+	if !b.Enabled() {
+		return nil
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.allow()
+}
+
+// Wait blocks until a submission is allowed or the context is done
+// ErrCircuitOpen is returned immediately if too many submissions are already waiting
+func (b *CircuitBreaker) Wait(ctx context.Context) error {
+	if !b.Enabled() {
+		return nil
+	}
+
+	b.mtx.Lock()
+
+	if err := b.allow(); err == nil {
+		b.mtx.Unlock()
+		return nil
+	}
+
+	if b.waiting >= b.config.MaxBuffered {
+		b.mtx.Unlock()
+		return ErrCircuitOpen
+	}
+
+	b.waiting++
+	defer func() {
+		b.mtx.Lock()
+		b.waiting--
+		b.mtx.Unlock()
+	}()
+
+	for {
+		changed := b.changed
+
+		// the open circuit is checked again at the end of the cooldown,
+		// and the half-open one once the probe completes
+		delay := b.config.Cooldown
+		if b.state == CircuitOpen {
+			delay = b.openedAt.Add(b.config.Cooldown).Sub(b.now())
+		}
+
+		b.mtx.Unlock()
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+
+		case <-changed:
+			timer.Stop()
+
+		case <-timer.C:
+		}
+
+		b.mtx.Lock()
+
+		if err := b.allow(); err == nil {
+			b.mtx.Unlock()
+			return nil
+		}
+	}
+}
+
+// Record records the result of an allowed submission
+func (b *CircuitBreaker) Record(err error) {
+	if !b.Enabled() {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.probing = false
+
+	if err == nil || errors.Is(err, context.Canceled) || !common.IsRetryableError(err) {
+		b.failures = 0
+		b.setState(CircuitClosed)
+
+		return
+	}
+
+	b.failures++
+
+	if b.state == CircuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// allow implements Allow with the lock held
+func (b *CircuitBreaker) allow() error {
+	b.cool()
+
+	switch b.state {
+	case CircuitClosed:
+		return nil
+
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+
+		b.probing = true
+
+		return nil
+
+	default:
+		return ErrCircuitOpen
+	}
+}
+
+// cool half-opens the circuit if the cooldown elapsed
+func (b *CircuitBreaker) cool() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		b.setState(CircuitHalfOpen)
+	}
+}
+
+// setState transits the circuit to the given state, waking up the waiting submissions
+func (b *CircuitBreaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	if state == CircuitOpen {
+		logging.Logger.Warnf("circuit of %s opened after %d consecutive failures, cooling down for %s", b.name, b.failures, b.config.Cooldown)
+
+		if b.onOpen != nil {
+			b.onOpen(b.name, b.failures)
+		}
+	} else {
+		logging.Logger.Infof("circuit of %s %s", b.name, strings.Replace(state, "_", "-", 1))
+	}
+
+	b.state = state
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+
+	metrics.CircuitState.WithLabelValues(b.name).Set(circuitStateValue(state))
+}
+
+// circuitStateValue returns the gauge value of the given state
+func circuitStateValue(state string) float64 {
+	switch state {
+	case CircuitOpen:
+		return 2
+
+	case CircuitHalfOpen:
+		return 1
+
+	default:
+		return 0
+	}
+}
+
+// BreakerRegistry holds the circuit breakers by dest ID
+type BreakerRegistry struct {
+	config BreakerConfig
+
+	mtx      sync.Mutex
+	breakers map[string]*CircuitBreaker
+
+	alertsMtx sync.Mutex
+	alerts    []BreakerAlert
+}
+
+// NewBreakerRegistry constructs a new BreakerRegistry instance
+// The breakers are disabled if the failure threshold is not positive
+func NewBreakerRegistry(config BreakerConfig) *BreakerRegistry {
+	return &BreakerRegistry{
+		config:   config,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// Get returns the breaker of the given dest ID, creating it on first use
+func (r *BreakerRegistry) Get(destID common.DestID) *CircuitBreaker {
+	key := strings.ToLower(destID.String())
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	breaker, ok := r.breakers[key]
+	if !ok {
+		breaker = NewCircuitBreaker(key, r.config)
+		breaker.onOpen = r.alert
+		r.breakers[key] = breaker
+	}
+
+	return breaker
+}
+
+// OnOpen registers an alert called once the circuit of any chain opens
+func (r *BreakerRegistry) OnOpen(alert BreakerAlert) {
+	r.alertsMtx.Lock()
+	defer r.alertsMtx.Unlock()
+
+	r.alerts = append(r.alerts, alert)
+}
+
+// alert raises the registered alerts of the opened circuit
+func (r *BreakerRegistry) alert(name string, failures int) {
+	r.alertsMtx.Lock()
+	alerts := append([]BreakerAlert(nil), r.alerts...)
+	r.alertsMtx.Unlock()
+
+	for _, alert := range alerts {
+		alert(name, failures)
+	}
+}
+
+// breaker returns the circuit breaker guarding the submissions to the given app chain
+func (r *Relayer) breaker(chainID string) *CircuitBreaker {
+	if chain, ok := r.appChain(chainID); ok {
+		return r.Breakers.Get(chain.GetDestID())
+	}
+
+	return r.Breakers.Get(common.DestID(chainID))
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+
+	b := NewCircuitBreaker("fisco-1-1", BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	b.now = func() time.Time { return now }
+
+	transient := errors.New("connection refused")
+
+	require.NoError(t, b.Allow())
+	b.Record(transient)
+	require.Equal(t, CircuitClosed, b.State())
+
+	// the permanent failures prove the node responsive
+	b.Record(errors.New("execution reverted"))
+	b.Record(transient)
+	require.Equal(t, CircuitClosed, b.State())
+
+	b.Record(transient)
+	require.Equal(t, CircuitOpen, b.State())
+	require.Equal(t, ErrCircuitOpen, b.Allow())
+
+	// a single probe is allowed after the cooldown
+	now = now.Add(time.Minute)
+	require.Equal(t, CircuitHalfOpen, b.State())
+	require.NoError(t, b.Allow())
+	require.Equal(t, ErrCircuitOpen, b.Allow())
+
+	// the failed probe opens the circuit again
+	b.Record(transient)
+	require.Equal(t, CircuitOpen, b.State())
+
+	now = now.Add(time.Minute)
+	require.NoError(t, b.Allow())
+	b.Record(nil)
+	require.Equal(t, CircuitClosed, b.State())
+	require.NoError(t, b.Allow())
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := NewCircuitBreaker("fisco-1-1", BreakerConfig{})
+
+	for i := 0; i < 10; i++ {
+		b.Record(errors.New("timeout"))
+	}
+
+	require.Equal(t, CircuitClosed, b.State())
+	require.NoError(t, b.Wait(context.Background()))
+}
+
+func TestCircuitBreakerWait(t *testing.T) {
+	b := NewCircuitBreaker("fisco-1-1", BreakerConfig{FailureThreshold: 1, Cooldown: 50 * time.Millisecond, MaxBuffered: 1})
+
+	b.Record(errors.New("timeout"))
+	require.Equal(t, CircuitOpen, b.State())
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- b.Wait(context.Background())
+	}()
+
+	// the buffer is full while the first submission waits
+	require.Eventually(t, func() bool {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+
+		return b.waiting == 1
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, ErrCircuitOpen, b.Wait(context.Background()))
+
+	// the waiting submission is let through as the probe after the cooldown
+	select {
+	case err := <-waited:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("submission not let through after the cooldown")
+	}
+
+	require.Equal(t, CircuitHalfOpen, b.State())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.Equal(t, context.Canceled, b.Wait(ctx))
+}
@@ -1,5 +1,12 @@
 package core
 
+import (
+	"context"
+	"time"
+
+	"relayer/common"
+)
+
 // ChainI defines the basic chain interface
 type ChainI interface {
 	GetChainID() string // chain ID getter
@@ -10,7 +17,14 @@ type HubChainI interface {
 	ChainI
 
 	// send the interchain request and handle the response with the given callback
-	SendInterchainRequest(request InterchainRequest, cb ResponseCallback) error
+	// sent is called once the request is initiated on the Hub, before any response is handled
+	SendInterchainRequest(ctx context.Context, request InterchainRequest, sent RequestSentCallback, cb ResponseCallback) error
+
+	// resume handling the response of the request initiated previously
+	ResumeInterchainRequest(reqCtxID string, icRequestID string, cb ResponseCallback) error
+
+	// check the connectivity and the signing key
+	CheckConnection(ctx context.Context) error
 }
 
 // AppChainI defines the interface to interact with the application chain
@@ -26,8 +40,43 @@ type AppChainI interface {
 	// get the current height
 	GetHeight() int64
 
-	// send the response to the application chain
-	SendResponse(requestID string, response ResponseI) error
+	// get the dest ID of the application chain
+	GetDestID() common.DestID
+
+	// check the connectivity of the application chain
+	CheckConnection(ctx context.Context) error
+
+	// get the liveness of the chain monitor
+	GetLiveness() ChainLiveness
+
+	// send the response to the application chain, returning the tx hash
+	SendResponse(ctx context.Context, requestID string, response ResponseI) (string, error)
+}
+
+// ResponseChecker is an application chain able to tell if the response of a request is on chain
+type ResponseChecker interface {
+	// indicate if the response is checked before being sent
+	ResponseCheckEnabled() bool
+
+	// check if the response of the given request already exists on the chain
+	ResponseExists(ctx context.Context, requestID string) (bool, error)
+}
+
+// ReloadableAppChain is an application chain applying the base config changes without a restart
+type ReloadableAppChain interface {
+	AppChainI
+
+	// build the chain on the given base config, which takes over from the current one;
+	// the current chain is returned if nothing changed
+	Reload(baseConfig []byte) (AppChainI, error)
+
+	// release the resources, e.g. the connections, not shared with the other chain; called on
+	// the current chain once replaced, and on the reloaded chain if discarded
+	Release(other AppChainI)
+
+	// resume from the height where the given chain, stopped, left off; called on the reloaded
+	// chain before it replaces the current one
+	TakeOver(previous AppChainI)
 }
 
 // AppChainFactoryI abstracts the application chain operation interface
@@ -38,6 +87,9 @@ type AppChainFactoryI interface {
 	// get the unique chain ID according to the given app chain type and params
 	GetChainID(chainType string, chainParams []byte) (string, error)
 
+	// get the dest ID according to the given app chain type and params
+	GetDestID(chainType string, chainParams []byte) (common.DestID, error)
+
 	// store the base config by the given app chain type
 	StoreBaseConfig(chainType string, baseConfig []byte) error
 
@@ -45,25 +97,40 @@ type AppChainFactoryI interface {
 	DeleteChainConfig(chainType string, chainID string) error
 }
 
+// ChainLiveness defines the liveness of an application chain monitor
+type ChainLiveness struct {
+	Connected  bool      // indicates if the last query to the chain succeeded
+	LastHeight int64     // latest height seen
+	LastSeenAt time.Time // time when the latest height was seen
+}
+
 // InterchainRequest defines the interchain service request
 type InterchainRequest struct {
-	ID              string // request ID
-	SourceChainID   string // source chain ID
-	DestChainID     string // target chain ID
-	DestSubChainID  string // target sub chain ID
-	DestChainType  string // target chain type
-	EndpointAddress string // end point address
-	EndpointType    string // end point type
-	Method          string // method name
-	CallData        []byte // target method name and json string of arguments
-	TxHash          string // source transaction hash
-	Sender          string // message sender
+	ID              string    // request ID
+	SourceChainID   string    // source chain ID
+	DestChainID     string    // target chain ID
+	DestSubChainID  string    // target sub chain ID
+	DestChainType   string    // target chain type
+	EndpointAddress string    // end point address
+	EndpointType    string    // end point type
+	Method          string    // method name
+	CallData        []byte    // target method name and json string of arguments
+	TxHash          string    // source transaction hash
+	Sender          string    // message sender
+	ServiceName     string    // Hub service invoked, the default service if empty
+	EmittedAt       time.Time // time the source block was produced, zero if unknown
+}
+
+// GetDestID returns the dest ID of the target chain
+func (r InterchainRequest) GetDestID() common.DestID {
+	return common.DestID(common.GetDestID(r.DestChainType, r.DestSubChainID, r.DestChainID)).Normalize()
 }
 
 // ResponseI defines the response related interfaces
 type ResponseI interface {
 	GetErrMsg() string              // error msg getter
 	GetOutput() string              // response output getter
+	GetServiceName() string         // service name getter
 }
 
 // KeyManager defines the key management interface
@@ -79,5 +146,10 @@ type KeyManager interface {
 // InterchainRequestHandler defines the interchain request handler interface
 type InterchainRequestHandler func(chainID string, request InterchainRequest, txHash string) error
 
+// RequestSentCallback defines the callback interface on the request initiated on the Hub
+type RequestSentCallback func(reqCtxID string, icRequestID string)
+
 // ResponseCallback defines the response callback interface
-type ResponseCallback func(icRequestID string, response ResponseI)
+// It is called exactly once per request: with the response, or with a nil response and the
+// cause, wrapping ErrNoResponse, if the Hub listener gives up on the request, e.g. expired
+type ResponseCallback func(icRequestID string, response ResponseI, err error)
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"relayer/common"
+	"relayer/logging"
+	"relayer/metrics"
+)
+
+const (
+	// KeyPrefixConfirm is the store key prefix of the response txs awaiting confirmation
+	KeyPrefixConfirm = "confirm:"
+
+	// DefaultConfirmInterval is the default interval between two polls of the pending receipts
+	DefaultConfirmInterval = 5 * time.Second
+	// DefaultConfirmTimeout is the default time a response tx is awaited before being dead-lettered
+	DefaultConfirmTimeout = 10 * time.Minute
+)
+
+// AsyncConfig defines the asynchronous response submission params
+type AsyncConfig struct {
+	Enabled  bool          // broadcasts the responses without waiting for their confirmation
+	Interval time.Duration // interval between two polls of the pending receipts
+	Timeout  time.Duration // time a response tx is awaited before being dead-lettered
+}
+
+// normalize fills the unset params with the default values
+func (c AsyncConfig) normalize() AsyncConfig {
+	if c.Interval <= 0 {
+		c.Interval = DefaultConfirmInterval
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultConfirmTimeout
+	}
+
+	return c
+}
+
+// AsyncResponseSender is an application chain able to confirm the response txs separately
+type AsyncResponseSender interface {
+	// broadcast the response tx without waiting for its confirmation, returning the tx hash;
+	// an empty hash is returned if no tx is needed, e.g. the response being already on chain
+	SubmitResponse(ctx context.Context, requestID string, response ResponseI) (string, error)
+
+	// check the receipt of the response tx of the request: true once it succeeded, false
+	// while it is not on chain or the chain can not tell, and an error if it failed on chain
+	ConfirmResponse(ctx context.Context, requestID string, txHash string) (bool, error)
+}
+
+// PendingConfirmation is a response tx broadcast and awaiting its confirmation
+type PendingConfirmation struct {
+	ChainID     string            `json:"chain_id"`      // source app chain ID
+	Request     InterchainRequest `json:"request"`       // interchain request
+	ICRequestID string            `json:"ic_request_id"` // service request ID on the Hub
+	Response    *ResponseAdaptor  `json:"response"`      // response sent, kept for the dead letter
+	TxHash      string            `json:"tx_hash"`       // response tx
+	ReceivedAt  time.Time         `json:"received_at"`   // time the request was received
+	SubmittedAt time.Time         `json:"submitted_at"`  // time the response tx was handed over for confirmation
+	BroadcastAt time.Time         `json:"broadcast_at"`  // time the response tx was broadcast, zero if not marked
+}
+
+// ConfirmKey returns the store key of the pending confirmation
+func ConfirmKey(requestID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", KeyPrefixConfirm, requestID))
+}
+
+// asyncSender returns the asynchronous sender of the given chain, nil if the responses
+// are confirmed synchronously. The pending txs are only tracked with the store
+func (r *Relayer) asyncSender(chain AppChainI) AsyncResponseSender {
+	if !r.Async.Enabled || r.Store == nil {
+		return nil
+	}
+
+	sender, ok := chain.(AsyncResponseSender)
+	if !ok {
+		return nil
+	}
+
+	return sender
+}
+
+// awaitConfirmation persists the response tx of the request, so that its confirmation is
+// watched across the restarts
+func (r *Relayer) awaitConfirmation(p PendingConfirmation) error {
+	bz, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Store.Set(ConfirmKey(p.Request.ID), bz); err != nil {
+		return err
+	}
+
+	metrics.PendingConfirmations.WithLabelValues(r.sourceDestID(p.ChainID).String()).Inc()
+
+	return nil
+}
+
+// loadConfirmations retrieves all the response txs awaiting confirmation
+func (r *Relayer) loadConfirmations() ([]PendingConfirmation, error) {
+	confirmations := make([]PendingConfirmation, 0)
+
+	if r.Store == nil {
+		return confirmations, nil
+	}
+
+	err := r.Store.Iterate([]byte(KeyPrefixConfirm), func(key, value []byte) error {
+		var p PendingConfirmation
+		if err := json.Unmarshal(value, &p); err != nil {
+			return fmt.Errorf("invalid pending confirmation %s: %s", key, err)
+		}
+
+		confirmations = append(confirmations, p)
+
+		return nil
+	})
+
+	return confirmations, err
+}
+
+// WatchConfirmations polls the receipts of the response txs awaiting confirmation until
+// the context is done. The confirmed requests are marked relayed, and the failed or
+// timed out ones dead-lettered. The txs of the chains not running are left pending
+func (r *Relayer) WatchConfirmations(ctx context.Context) {
+	config := r.Async.normalize()
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.checkConfirmations(ctx, config.Timeout)
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkConfirmations checks the receipts of all the pending response txs once
+func (r *Relayer) checkConfirmations(ctx context.Context, timeout time.Duration) {
+	confirmations, err := r.loadConfirmations()
+	if err != nil {
+		r.Logger.Errorf("failed to load the pending confirmations: %s", err)
+		return
+	}
+
+	pending := make(map[common.DestID]int)
+
+	for _, p := range confirmations {
+		if ctx.Err() != nil {
+			return
+		}
+
+		destID := r.sourceDestID(p.ChainID)
+
+		chain, ok := r.appChain(p.ChainID)
+		if !ok {
+			pending[destID]++
+			continue
+		}
+
+		sender, ok := chain.(AsyncResponseSender)
+		if !ok {
+			pending[destID]++
+			continue
+		}
+
+		confirmed, err := sender.ConfirmResponse(ctx, p.Request.ID, p.TxHash)
+
+		switch {
+		case err != nil:
+			r.failConfirmation(p, err)
+
+		case confirmed:
+			r.confirm(p)
+
+		case time.Since(p.SubmittedAt) > timeout:
+			r.failConfirmation(p, fmt.Errorf("response transaction %s not confirmed within %s", p.TxHash, timeout))
+
+		default:
+			pending[destID]++
+		}
+	}
+
+	metrics.PendingConfirmations.Reset()
+	for destID, count := range pending {
+		metrics.PendingConfirmations.WithLabelValues(destID.String()).Set(float64(count))
+	}
+}
+
+// confirm marks the request of the confirmed response tx relayed
+func (r *Relayer) confirm(p PendingConfirmation) {
+	logger := r.requestLogger(p.ChainID, p.Request.ID)
+	labels := r.metricLabels(p.ChainID, p.Request)
+
+	metrics.RequestsRelayed.WithLabelValues(labels...).Inc()
+	metrics.RelayLatency.WithLabelValues(labels...).Observe(time.Since(p.ReceivedAt).Seconds())
+
+	// the confirmation is timed from the broadcast, falling back to the submission for
+	// the txs whose broadcast was not marked, e.g. by the previous versions
+	broadcastAt := p.BroadcastAt
+	if broadcastAt.IsZero() {
+		broadcastAt = p.SubmittedAt
+	}
+
+	trace := NewRelayTrace()
+	trace.Mark(MilestoneBroadcast, broadcastAt)
+	trace.Mark(MilestoneConfirmed, time.Now())
+	r.observeTrace(p.ChainID, p.Request, trace)
+
+	logger.WithFields(log.Fields{
+		logging.FieldTxHash: p.TxHash,
+		logging.FieldStage:  logging.StageResponseRelayed,
+	}).Info("response transaction confirmed")
+
+	r.markRelayed(p.ChainID, p.Request, p.ICRequestID, p.TxHash)
+
+	if err := r.Store.Delete(ConfirmKey(p.Request.ID)); err != nil {
+		logger.Errorf("failed to delete the pending confirmation: %s", err)
+	}
+}
+
+// failConfirmation dead-letters the request of the failed response tx
+func (r *Relayer) failConfirmation(p PendingConfirmation, cause error) {
+	logger := r.requestLogger(p.ChainID, p.Request.ID)
+
+	metrics.RelayErrors.WithLabelValues(r.metricLabels(p.ChainID, p.Request)...).Inc()
+	logger.Errorf("response transaction %s failed: %s", p.TxHash, cause)
+
+	var response ResponseI
+	if p.Response != nil {
+		response = *p.Response
+	}
+
+	if err := r.deadLetter(p.ChainID, StageResponse, p.Request, response, cause); err != nil {
+		logger.Errorf("failed to audit the dead letter: %s", err)
+	}
+
+	if err := r.Store.Delete(ConfirmKey(p.Request.ID)); err != nil {
+		logger.Errorf("failed to delete the pending confirmation: %s", err)
+	}
+}
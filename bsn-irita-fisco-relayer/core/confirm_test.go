@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/store"
+)
+
+// mockAsyncChain is an AppChainI confirming the response txs asynchronously
+type mockAsyncChain struct {
+	mockAppChain
+	submitted int
+	sent      int
+	receipts  map[string]error // outcome of the confirmed txs by hash
+}
+
+func (m *mockAsyncChain) SendResponse(context.Context, string, ResponseI) (string, error) {
+	m.sent++
+	return "0xsync", nil
+}
+
+func (m *mockAsyncChain) SubmitResponse(ctx context.Context, requestID string, response ResponseI) (string, error) {
+	m.submitted++
+	return "0x" + requestID, nil
+}
+
+func (m *mockAsyncChain) ConfirmResponse(ctx context.Context, requestID string, txHash string) (bool, error) {
+	err, ok := m.receipts[txHash]
+	if !ok {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func TestAsyncConfirmation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confirm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := store.NewStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	checkpoint, err := store.NewFileCheckpoint(filepath.Join(dir, "checkpoints"))
+	require.NoError(t, err)
+
+	newRelayer := func(chain AppChainI) *Relayer {
+		r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, db, nil)
+		r.State = store.NewFileStateStore(checkpoint, db)
+		r.Async = AsyncConfig{Enabled: true}
+		r.AppChains["1"] = chain
+		r.Encoders.Register("oracle", JSONEncoder{})
+
+		r.DeadLetters, err = NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+		require.NoError(t, err)
+
+		return r
+	}
+
+	chain := &mockAsyncChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}, receipts: map[string]error{}}
+	r := newRelayer(chain)
+
+	// the callback returns once the txs are broadcast
+	for _, id := range []string{"req-1", "req-2", "req-3"} {
+		r.track()
+		r.responseCallback("1", InterchainRequest{ID: id}, InterchainRequest{ID: id}, time.Now(), nil)("ic-"+id, ResponseAdaptor{StatusCode: 200, ServiceName: "oracle", Output: "{}"}, nil)
+	}
+
+	require.Equal(t, 3, chain.submitted)
+	require.Equal(t, 0, chain.sent)
+	require.Equal(t, 0, r.Queue.Depth())
+
+	confirmations, err := r.loadConfirmations()
+	require.NoError(t, err)
+	require.Len(t, confirmations, 3)
+
+	status, err := r.GetRequestStatus("req-1", "")
+	require.NoError(t, err)
+	require.NotEqual(t, StatusRelayed, status.Status)
+
+	// the pending txs are watched again after a restart
+	r = newRelayer(chain)
+
+	r.checkConfirmations(context.Background(), time.Minute)
+
+	confirmations, err = r.loadConfirmations()
+	require.NoError(t, err)
+	require.Len(t, confirmations, 3)
+
+	chain.receipts["0xreq-1"] = nil
+	chain.receipts["0xreq-2"] = errors.New("transaction 0xreq-2 execution failed")
+
+	r.checkConfirmations(context.Background(), time.Minute)
+
+	status, err = r.GetRequestStatus("req-1", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusRelayed, status.Status)
+	require.Equal(t, "0xreq-1", status.Record.ResponseTxHash)
+
+	status, err = r.GetRequestStatus("req-2", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusDeadLettered, status.Status)
+	require.Equal(t, StageResponse, status.DeadLetter.Stage)
+
+	confirmations, err = r.loadConfirmations()
+	require.NoError(t, err)
+	require.Len(t, confirmations, 1)
+
+	// the unconfirmed tx is dead-lettered once timed out
+	r.checkConfirmations(context.Background(), 0)
+
+	status, err = r.GetRequestStatus("req-3", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusDeadLettered, status.Status)
+
+	confirmations, err = r.loadConfirmations()
+	require.NoError(t, err)
+	require.Empty(t, confirmations)
+}
+
+func TestAsyncDisabledSendsSynchronously(t *testing.T) {
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.Async = AsyncConfig{Enabled: true}
+
+	chain := &mockAsyncChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}}
+	r.AppChains["1"] = chain
+
+	// no pending tx can be tracked without the store
+	txHash, pending, err := r.dispatchResponse(context.Background(), "1", "req-1", ResponseAdaptor{StatusCode: 200}, true)
+	require.NoError(t, err)
+	require.False(t, pending)
+	require.Equal(t, "0xsync", txHash)
+	require.Equal(t, 0, chain.submitted)
+}
@@ -0,0 +1,367 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"relayer/common"
+	"relayer/metrics"
+)
+
+const (
+	// DefaultDeadLetterFile is the dead letter file relative to the home directory
+	DefaultDeadLetterFile = ".relayer/deadletters.jsonl"
+
+	// StageRequest indicates the failure to invoke the service on the Hub
+	StageRequest = "request"
+
+	// StageResponse indicates the failure to send the response to the source app chain
+	StageResponse = "response"
+)
+
+// DeadLetter is an interchain request which failed to relay permanently
+type DeadLetter struct {
+	ChainID  string            `json:"chain_id"`           // source app chain ID
+	DestID   string            `json:"dest_id"`            // dest ID of the request
+	Stage    string            `json:"stage"`              // relay stage of the failure
+	Request  InterchainRequest `json:"request"`            // interchain request
+	Response *ResponseAdaptor  `json:"response,omitempty"` // response to send, for the response stage
+	Error    string            `json:"error"`              // last error
+	FailedAt time.Time         `json:"failed_at"`
+}
+
+// DeadLetterQueue defines the interface to keep the dead letters for inspection and replay
+type DeadLetterQueue interface {
+	// Add records the dead letter, replacing the one of the same request
+	Add(letter DeadLetter) error
+
+	// List retrieves all the dead letters in the order of failure
+	List() ([]DeadLetter, error)
+
+	// Remove deletes the dead letter of the given request
+	Remove(requestID string) error
+}
+
+// FileDeadLetterQueue is a DeadLetterQueue implementation backed by a JSONL file
+// The letters are appended on failure, and the file is rewritten on removal
+type FileDeadLetterQueue struct {
+	path string
+	mtx  sync.Mutex
+}
+
+var _ DeadLetterQueue = (*FileDeadLetterQueue)(nil)
+
+// NewFileDeadLetterQueue constructs a new FileDeadLetterQueue instance on the given file
+func NewFileDeadLetterQueue(path string) (*FileDeadLetterQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the dead letter directory: %s", err)
+	}
+
+	return &FileDeadLetterQueue{path: path}, nil
+}
+
+// DefaultDeadLetterPath returns the default dead letter file under the relayer home directory
+func DefaultDeadLetterPath() (string, error) {
+	homeDir, err := common.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, DefaultDeadLetterFile), nil
+}
+
+// Add implements DeadLetterQueue
+func (q *FileDeadLetterQueue) Add(letter DeadLetter) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	letters, err := q.load()
+	if err != nil {
+		return err
+	}
+
+	for _, l := range letters {
+		if l.Request.ID == letter.Request.ID {
+			return q.write(replaceLetter(letters, letter))
+		}
+	}
+
+	f, err := os.OpenFile(q.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bz, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(bz, '\n')); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// List implements DeadLetterQueue
+func (q *FileDeadLetterQueue) List() ([]DeadLetter, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return q.load()
+}
+
+// Remove implements DeadLetterQueue
+func (q *FileDeadLetterQueue) Remove(requestID string) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	letters, err := q.load()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]DeadLetter, 0, len(letters))
+	for _, l := range letters {
+		if l.Request.ID != requestID {
+			remaining = append(remaining, l)
+		}
+	}
+
+	if len(remaining) == len(letters) {
+		return fmt.Errorf("dead letter of the request %s not found", requestID)
+	}
+
+	return q.write(remaining)
+}
+
+// load reads all the letters from the file
+func (q *FileDeadLetterQueue) load() ([]DeadLetter, error) {
+	letters := make([]DeadLetter, 0)
+
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return letters, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return nil, fmt.Errorf("invalid dead letter at %s:%d: %s", q.path, line, err)
+		}
+
+		letters = append(letters, letter)
+	}
+
+	return letters, scanner.Err()
+}
+
+// write replaces the file with the given letters atomically
+func (q *FileDeadLetterQueue) write(letters []DeadLetter) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(q.path), filepath.Base(q.path)+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+
+	for _, letter := range letters {
+		bz, err := json.Marshal(letter)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+
+		if _, err := w.Write(append(bz, '\n')); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), q.path)
+}
+
+// replaceLetter replaces the letter of the same request in place
+func replaceLetter(letters []DeadLetter, letter DeadLetter) []DeadLetter {
+	for i, l := range letters {
+		if l.Request.ID == letter.Request.ID {
+			letters[i] = letter
+		}
+	}
+
+	return letters
+}
+
+// deadLetter records the failed request if the dead letter queue is enabled
+// The failure to audit the decision is returned, for the listener to read the event again
+func (r *Relayer) deadLetter(chainID string, stage string, request InterchainRequest, response ResponseI, cause error) error {
+	r.Notify(NotificationEvent{
+		Type:      NotifyDeadLetter,
+		DestID:    request.GetDestID().String(),
+		RequestID: request.ID,
+		Message:   fmt.Sprintf("request failed at the %s stage: %s", stage, cause),
+		Details: map[string]string{
+			"source": r.sourceDestID(chainID).String(),
+			"stage":  stage,
+		},
+	})
+
+	if r.DeadLetters == nil {
+		return r.audit(chainID, request, DecisionDropped, "", cause)
+	}
+
+	letter := DeadLetter{
+		ChainID:  chainID,
+		DestID:   request.GetDestID().String(),
+		Stage:    stage,
+		Request:  request,
+		Error:    cause.Error(),
+		FailedAt: time.Now(),
+	}
+
+	if response != nil {
+		letter.Response = toResponseAdaptor(response)
+	}
+
+	logger := r.requestLogger(chainID, request.ID)
+
+	if err := r.DeadLetters.Add(letter); err != nil {
+		logger.Errorf("failed to record the dead letter: %s", err)
+
+		return r.audit(chainID, request, DecisionDropped, "", cause)
+	}
+
+	metrics.RequestsDeadLettered.WithLabelValues(r.metricLabels(chainID, request)...).Inc()
+	logger.Warnf("request dead-lettered at the %s stage: %s", stage, cause)
+
+	return r.audit(chainID, request, DecisionDeadLettered, "", cause)
+}
+
+// ListDeadLetters retrieves all the dead letters
+func (r *Relayer) ListDeadLetters() ([]DeadLetter, error) {
+	if r.DeadLetters == nil {
+		return nil, fmt.Errorf("dead letter queue not enabled")
+	}
+
+	return r.DeadLetters.List()
+}
+
+// ResubmitDeadLetter relays the dead-lettered request again from the failed stage
+// The letter is only removed once the relay is accepted, and recorded again if it fails.
+// It is kept, and an error returned, while the request is deferred, filtered out or dry run
+func (r *Relayer) ResubmitDeadLetter(requestID string) error {
+	if r.isClosing() {
+		return ErrRelayerClosing
+	}
+
+	letters, err := r.ListDeadLetters()
+	if err != nil {
+		return err
+	}
+
+	for _, letter := range letters {
+		if letter.Request.ID != requestID {
+			continue
+		}
+
+		if letter.Stage == StageResponse && letter.Response != nil {
+			responseTxHash, err := r.sendResponse(r.ctx, letter.ChainID, requestID, *letter.Response)
+			if err != nil {
+				if auditErr := r.deadLetter(letter.ChainID, StageResponse, letter.Request, *letter.Response, err); auditErr != nil {
+					r.requestLogger(letter.ChainID, requestID).Errorf("failed to audit the dead letter: %s", auditErr)
+				}
+
+				return err
+			}
+
+			if err := r.DeadLetters.Remove(requestID); err != nil {
+				r.requestLogger(letter.ChainID, requestID).Errorf("failed to remove the dead letter: %s", err)
+			}
+
+			r.markRelayed(letter.ChainID, letter.Request, "", responseTxHash)
+
+			return nil
+		}
+
+		if _, ok := r.appChain(letter.ChainID); !ok {
+			return fmt.Errorf("chain %s of the request not running", letter.ChainID)
+		}
+
+		r.forget(letter.ChainID, requestID)
+
+		// the letter is removed before the request is sent, so that a failure records it again
+		relayed := false
+
+		err := r.handleInterchainRequest(letter.ChainID, letter.Request, letter.Request.TxHash, func() {
+			relayed = true
+
+			if err := r.DeadLetters.Remove(requestID); err != nil {
+				r.requestLogger(letter.ChainID, requestID).Errorf("failed to remove the dead letter: %s", err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if !relayed {
+			return fmt.Errorf("request %s not relayed: filtered out or dry run", requestID)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("dead letter of the request %s not found", requestID)
+}
+
+// toResponseAdaptor converts the response for persistence
+func toResponseAdaptor(response ResponseI) *ResponseAdaptor {
+	if adaptor, ok := response.(ResponseAdaptor); ok {
+		return &adaptor
+	}
+
+	adaptor := ResponseAdaptor{
+		StatusCode:  200,
+		ServiceName: response.GetServiceName(),
+		Output:      response.GetOutput(),
+	}
+
+	if errMsg := response.GetErrMsg(); len(errMsg) != 0 {
+		adaptor.StatusCode = 500
+		adaptor.Result = errMsg
+	}
+
+	return &adaptor
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/mysql"
+)
+
+func TestFileDeadLetterQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "relayer", "deadletters.jsonl")
+
+	queue, err := NewFileDeadLetterQueue(path)
+	require.NoError(t, err)
+
+	letters, err := queue.List()
+	require.NoError(t, err)
+	require.Empty(t, letters)
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, queue.Add(DeadLetter{ChainID: "1", DestID: "fisco-1-1", Stage: StageRequest, Request: InterchainRequest{ID: "req-1"}, Error: "timeout", FailedAt: now}))
+	require.NoError(t, queue.Add(DeadLetter{
+		ChainID:  "1",
+		DestID:   "fisco-1-1",
+		Stage:    StageResponse,
+		Request:  InterchainRequest{ID: "req-2"},
+		Response: &ResponseAdaptor{StatusCode: 200, Output: `{"a":1}`},
+		Error:    "reverted",
+		FailedAt: now,
+	}))
+
+	// the letter of the same request is replaced in place
+	require.NoError(t, queue.Add(DeadLetter{ChainID: "1", Stage: StageRequest, Request: InterchainRequest{ID: "req-1"}, Error: "out of gas", FailedAt: now}))
+
+	letters, err = queue.List()
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	require.Equal(t, "req-1", letters[0].Request.ID)
+	require.Equal(t, "out of gas", letters[0].Error)
+	require.Equal(t, `{"a":1}`, letters[1].Response.Output)
+	require.True(t, now.Equal(letters[1].FailedAt))
+
+	require.NoError(t, queue.Remove("req-1"))
+	require.Error(t, queue.Remove("req-1"))
+
+	// the letters survive reopening the queue
+	queue, err = NewFileDeadLetterQueue(path)
+	require.NoError(t, err)
+
+	letters, err = queue.List()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, "req-2", letters[0].Request.ID)
+}
+
+func TestResubmitDeadLetterNotFound(t *testing.T) {
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+
+	_, err := r.ListDeadLetters()
+	require.Error(t, err)
+
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+	require.NoError(t, err)
+
+	r.DeadLetters = queue
+
+	require.Error(t, r.ResubmitDeadLetter("req-1"))
+}
+
+func TestDeadLetterNoResponse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+	require.NoError(t, err)
+
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.DeadLetters = queue
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	source := InterchainRequest{ID: "req-1", DestChainType: "eth", DestChainID: "1"}
+	routed := InterchainRequest{ID: "req-1", DestChainType: "fabric", DestChainID: "2"}
+
+	// the request expired on the Hub is dead-lettered as received from the source chain
+	r.track()
+	r.responseCallback("1", source, routed, time.Now(), nil)("ic-req-1", nil, fmt.Errorf("%w: request req-1 expired at height 10", ErrNoResponse))
+
+	letters, err := queue.List()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, StageRequest, letters[0].Stage)
+	require.Equal(t, "eth-1", letters[0].DestID)
+	require.Contains(t, letters[0].Error, "expired at height 10")
+}
+
+func TestResubmitDeadLetterKeptUntilRelayed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+	require.NoError(t, err)
+
+	// the tracking db is unreachable, its failures being logged only
+	db, err := sql.Open("mysql", "relayer@tcp(127.0.0.1:1)/relayer")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mysql.DB = db
+	defer func() { mysql.DB = nil }()
+
+	hub := &mockHubChain{silent: true}
+
+	r := NewRelayer("fisco", hub, nil, nil, nil)
+	r.DeadLetters = queue
+	r.Breakers = NewBreakerRegistry(BreakerConfig{FailureThreshold: 1})
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	request := InterchainRequest{ID: "req-1", DestChainType: "eth", DestChainID: "1", TxHash: "0x01"}
+	require.NoError(t, queue.Add(DeadLetter{ChainID: "1", Stage: StageRequest, Request: request, Error: "connection refused"}))
+
+	requireLetter := func(errMsg string) {
+		letters, err := queue.List()
+		require.NoError(t, err)
+		require.Len(t, letters, 1)
+		require.Equal(t, errMsg, letters[0].Error)
+	}
+
+	// the deferred request is kept
+	breaker := r.breaker("1")
+	require.NoError(t, breaker.Allow())
+	breaker.Record(errors.New("connection refused"))
+
+	require.Equal(t, ErrCircuitOpen, r.ResubmitDeadLetter("req-1"))
+	requireLetter("connection refused")
+
+	r.Breakers = NewBreakerRegistry(BreakerConfig{})
+
+	// so is the dry run
+	r.DryRun = true
+	require.Error(t, r.ResubmitDeadLetter("req-1"))
+	requireLetter("connection refused")
+
+	r.DryRun = false
+
+	// the failed relay is recorded again
+	hub.sendErr = errors.New("insufficient fees")
+	require.EqualError(t, r.ResubmitDeadLetter("req-1"), "insufficient fees")
+	requireLetter("insufficient fees")
+
+	// the letter is removed once relayed
+	hub.sendErr = nil
+	require.NoError(t, r.ResubmitDeadLetter("req-1"))
+
+	letters, err := queue.List()
+	require.NoError(t, err)
+	require.Empty(t, letters)
+}
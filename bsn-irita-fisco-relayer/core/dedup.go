@@ -0,0 +1,196 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDedupCapacity is the default maximum number of request IDs remembered
+	DefaultDedupCapacity = 10000
+	// DefaultDedupTTL is the default window within which a request ID is considered duplicate
+	DefaultDedupTTL = 10 * time.Minute
+)
+
+// DedupCache remembers the recently seen request IDs to drop duplicate events
+// The oldest entry is evicted when the capacity is reached
+// It is safe for concurrent use
+type DedupCache struct {
+	capacity int
+	ttl      time.Duration
+
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // entries from the most to the least recently seen
+
+	now func() time.Time
+}
+
+// dedupEntry is an entry of the dedup cache
+type dedupEntry struct {
+	id     string
+	seenAt time.Time
+}
+
+// NewDedupCache constructs a new DedupCache instance
+// The defaults are used for the non-positive capacity and TTL
+func NewDedupCache(capacity int, ttl time.Duration) *DedupCache {
+	if capacity <= 0 {
+		capacity = DefaultDedupCapacity
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+
+	return &DedupCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Seen reports whether the given ID was seen within the window,
+// and records it as seen otherwise
+func (c *DedupCache) Seen(id string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.now()
+
+	if elem, ok := c.entries[id]; ok {
+		if now.Sub(elem.Value.(*dedupEntry).seenAt) < c.ttl {
+			return true
+		}
+
+		c.remove(elem)
+	}
+
+	c.entries[id] = c.order.PushFront(&dedupEntry{id: id, seenAt: now})
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+
+	return false
+}
+
+// Contains reports whether the given ID was seen within the window, without recording it
+func (c *DedupCache) Contains(id string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	elem, ok := c.entries[id]
+
+	return ok && c.now().Sub(elem.Value.(*dedupEntry).seenAt) < c.ttl
+}
+
+// Forget removes the given ID, so that it is not considered duplicate anymore
+func (c *DedupCache) Forget(id string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+	}
+}
+
+// TTL returns the window within which an ID is considered duplicate
+func (c *DedupCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Len returns the number of the remembered IDs
+func (c *DedupCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.order.Len()
+}
+
+// remove removes the given element
+func (c *DedupCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*dedupEntry).id)
+}
+
+// seen reports whether the request of the given chain was seen within the dedup window,
+// and records it as seen otherwise
+// The seen marks are persisted in the state store if any, so that the duplicates are
+// still dropped after a restart. They are never persisted in the dry run
+func (r *Relayer) seen(chainID string, requestID string) bool {
+	if r.Dedup.Seen(requestID) {
+		return true
+	}
+
+	if r.State == nil || r.DryRun {
+		return false
+	}
+
+	logger := r.requestLogger(chainID, requestID)
+	destID := r.sourceDestID(chainID)
+
+	seenAt, err := r.State.LoadSeen(destID, requestID)
+	if err != nil {
+		logger.Warnf("failed to load the seen mark: %s", err)
+	} else if !seenAt.IsZero() && time.Since(seenAt) < r.Dedup.TTL() {
+		return true
+	}
+
+	if err := r.State.SaveSeen(destID, requestID, time.Now()); err != nil {
+		logger.Warnf("failed to save the seen mark: %s", err)
+	}
+
+	return false
+}
+
+// forget forgets the request of the given chain, so that it is not considered duplicate anymore
+func (r *Relayer) forget(chainID string, requestID string) {
+	r.Dedup.Forget(requestID)
+
+	if r.State == nil {
+		return
+	}
+
+	if err := r.State.DeleteSeen(r.sourceDestID(chainID), requestID); err != nil {
+		r.requestLogger(chainID, requestID).Warnf("failed to delete the seen mark: %s", err)
+	}
+}
+
+// PruneSeenMarks removes the persisted seen marks past the dedup window every window until
+// the context is done, so that the state store does not grow with every request relayed
+func (r *Relayer) PruneSeenMarks(ctx context.Context) {
+	if r.State == nil {
+		return
+	}
+
+	ticker := time.NewTicker(r.Dedup.TTL())
+	defer ticker.Stop()
+
+	for {
+		r.pruneSeenMarks(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pruneSeenMarks removes the persisted seen marks past the dedup window at the given time
+func (r *Relayer) pruneSeenMarks(now time.Time) {
+	pruned, err := r.State.PruneSeen(now.Add(-r.Dedup.TTL()))
+	if err != nil {
+		r.Logger.Warnf("failed to prune the seen marks: %s", err)
+		return
+	}
+
+	if pruned > 0 {
+		r.Logger.Debugf("%d expired seen marks pruned", pruned)
+	}
+}
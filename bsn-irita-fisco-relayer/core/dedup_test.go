@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/logging"
+	"relayer/store"
+)
+
+func TestDedupCacheTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	cache := NewDedupCache(10, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	require.False(t, cache.Seen("req-1"))
+	require.True(t, cache.Seen("req-1"))
+
+	now = now.Add(59 * time.Second)
+	require.True(t, cache.Seen("req-1"))
+
+	now = now.Add(time.Second)
+	require.False(t, cache.Seen("req-1"))
+	require.True(t, cache.Seen("req-1"))
+
+	cache.Forget("req-1")
+	require.False(t, cache.Seen("req-1"))
+}
+
+func TestDedupCacheCapacity(t *testing.T) {
+	cache := NewDedupCache(3, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		require.False(t, cache.Seen(fmt.Sprintf("req-%d", i)))
+	}
+
+	require.Equal(t, 3, cache.Len())
+
+	// the oldest entries are evicted
+	require.True(t, cache.Seen("req-4"))
+	require.False(t, cache.Seen("req-0"))
+	require.Equal(t, 3, cache.Len())
+}
+
+func TestDedupCacheConcurrent(t *testing.T) {
+	cache := NewDedupCache(100, time.Hour)
+
+	var (
+		wg    sync.WaitGroup
+		mtx   sync.Mutex
+		fresh int
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				if !cache.Seen(fmt.Sprintf("req-%d", j)) {
+					mtx.Lock()
+					fresh++
+					mtx.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, 50, fresh)
+}
+
+func TestSeenPersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	state, err := store.NewBoltStateStore(filepath.Join(dir, "state.db"))
+	require.NoError(t, err)
+	defer state.Close()
+
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.State = state
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	require.False(t, r.seen("1", "req-1"))
+	require.True(t, r.seen("1", "req-1"))
+
+	// the duplicates are still dropped after a restart
+	r = NewRelayer("fisco", nil, nil, nil, nil)
+	r.State = state
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	require.True(t, r.seen("1", "req-1"))
+
+	r.forget("1", "req-1")
+	require.False(t, r.seen("1", "req-1"))
+
+	// the dry run leaves no seen mark
+	r = NewRelayer("fisco", nil, nil, nil, nil)
+	r.State = state
+	r.DryRun = true
+
+	require.False(t, r.seen("1", "req-2"))
+
+	seenAt, err := state.LoadSeen("1", "req-2")
+	require.NoError(t, err)
+	require.True(t, seenAt.IsZero())
+}
+
+func TestPruneSeenMarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	state, err := store.NewBoltStateStore(filepath.Join(dir, "state.db"))
+	require.NoError(t, err)
+	defer state.Close()
+
+	now := time.Now()
+
+	r := NewRelayer("fisco", nil, nil, nil, logging.Logger)
+	r.State = state
+	r.Dedup = NewDedupCache(10, time.Minute)
+
+	require.NoError(t, state.SaveSeen("fisco-1-1", "req-1", now.Add(-2*time.Minute)))
+	require.NoError(t, state.SaveSeen("fisco-1-1", "req-2", now.Add(-30*time.Second)))
+
+	// only the marks past the dedup window are removed
+	r.pruneSeenMarks(now)
+
+	seenAt, err := state.LoadSeen("fisco-1-1", "req-1")
+	require.NoError(t, err)
+	require.True(t, seenAt.IsZero())
+
+	seenAt, err = state.LoadSeen("fisco-1-1", "req-2")
+	require.NoError(t, err)
+	require.False(t, seenAt.IsZero())
+}
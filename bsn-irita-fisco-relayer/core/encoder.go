@@ -0,0 +1,110 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EncoderJSON is the name of the JSON passthrough encoder
+const EncoderJSON = "json"
+
+// ResponseEncoder defines the interface to encode the service output
+// into the callback payload of the application chain
+type ResponseEncoder interface {
+	// Encode encodes the output, a valid JSON value of any type
+	Encode(output json.RawMessage) ([]byte, error)
+}
+
+// JSONEncoder is a ResponseEncoder passing the output through verbatim
+type JSONEncoder struct{}
+
+var _ ResponseEncoder = JSONEncoder{}
+
+// Encode implements ResponseEncoder
+func (JSONEncoder) Encode(output json.RawMessage) ([]byte, error) {
+	return output, nil
+}
+
+// BuiltinEncoders are the encoders which can be selected by name in the config
+var BuiltinEncoders = map[string]ResponseEncoder{
+	EncoderJSON: JSONEncoder{},
+}
+
+// EncoderRegistry holds the response encoders by service name
+// It is safe for concurrent use
+type EncoderRegistry struct {
+	mtx      sync.RWMutex
+	encoders map[string]ResponseEncoder
+}
+
+// NewEncoderRegistry constructs a new empty EncoderRegistry instance
+func NewEncoderRegistry() *EncoderRegistry {
+	return &EncoderRegistry{
+		encoders: make(map[string]ResponseEncoder),
+	}
+}
+
+// Register registers the encoder for the given service, replacing the existing one
+func (r *EncoderRegistry) Register(serviceName string, encoder ResponseEncoder) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.encoders[serviceName] = encoder
+}
+
+// RegisterBuiltin registers the builtin encoder of the given name for the service
+func (r *EncoderRegistry) RegisterBuiltin(serviceName string, encoderName string) error {
+	encoder, ok := BuiltinEncoders[strings.ToLower(encoderName)]
+	if !ok {
+		return fmt.Errorf("unknown response encoder %s for service %s", encoderName, serviceName)
+	}
+
+	r.Register(serviceName, encoder)
+
+	return nil
+}
+
+// Get returns the encoder of the given service
+func (r *EncoderRegistry) Get(serviceName string) (ResponseEncoder, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	encoder, ok := r.encoders[serviceName]
+	if !ok {
+		return nil, fmt.Errorf("no response encoder registered for service %q", serviceName)
+	}
+
+	return encoder, nil
+}
+
+// EncodeResponse encodes the output of the given successful response with the encoder of its service
+// The failed responses are returned as is, since they carry no service output
+func (r *EncoderRegistry) EncodeResponse(response ResponseI) (ResponseI, error) {
+	if len(response.GetErrMsg()) != 0 || len(response.GetOutput()) == 0 {
+		return response, nil
+	}
+
+	encoder, err := r.Get(response.GetServiceName())
+	if err != nil {
+		return nil, err
+	}
+
+	// the output is never decoded, so that its numbers and key order are kept as is
+	output := json.RawMessage(response.GetOutput())
+	if !json.Valid(output) {
+		return nil, fmt.Errorf("malformed output of service %s: invalid JSON", response.GetServiceName())
+	}
+
+	payload, err := encoder.Encode(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the output of service %s: %s", response.GetServiceName(), err)
+	}
+
+	return ResponseAdaptor{
+		StatusCode:  200,
+		ServiceName: response.GetServiceName(),
+		Output:      string(payload),
+	}, nil
+}
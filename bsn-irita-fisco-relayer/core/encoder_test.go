@@ -0,0 +1,82 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countEncoder struct{}
+
+func (countEncoder) Encode(output json.RawMessage) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(output, &fields); err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("%d", len(fields))), nil
+}
+
+func TestEncodeResponseJSONPassthrough(t *testing.T) {
+	registry := NewEncoderRegistry()
+	registry.Register("cc-contract-call", JSONEncoder{})
+
+	response := ResponseAdaptor{
+		StatusCode:  200,
+		ServiceName: "cc-contract-call",
+		Output:      `{"result":"0x01","amount":123456789012345678901234567890}`,
+	}
+
+	encoded, err := registry.EncodeResponse(response)
+	require.NoError(t, err)
+	require.Equal(t, response.Output, encoded.GetOutput())
+	require.Empty(t, encoded.GetErrMsg())
+
+	// the outputs other than an object are passed through as well
+	for _, output := range []string{`[1,2]`, `"0x01"`, `1.10`, `true`} {
+		encoded, err = registry.EncodeResponse(ResponseAdaptor{StatusCode: 200, ServiceName: "cc-contract-call", Output: output})
+		require.NoError(t, err)
+		require.Equal(t, output, encoded.GetOutput())
+	}
+}
+
+func TestEncodeResponseByService(t *testing.T) {
+	registry := NewEncoderRegistry()
+	registry.Register("cc-contract-call", JSONEncoder{})
+	registry.Register("price", countEncoder{})
+
+	encoded, err := registry.EncodeResponse(ResponseAdaptor{StatusCode: 200, ServiceName: "price", Output: `{"a":1,"b":2}`})
+	require.NoError(t, err)
+	require.Equal(t, "2", encoded.GetOutput())
+
+	_, err = registry.EncodeResponse(ResponseAdaptor{StatusCode: 200, ServiceName: "price", Output: `[1]`})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to encode")
+}
+
+func TestEncodeResponseUnregistered(t *testing.T) {
+	registry := NewEncoderRegistry()
+
+	_, err := registry.EncodeResponse(ResponseAdaptor{StatusCode: 200, ServiceName: "unknown", Output: `{}`})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no response encoder registered")
+
+	require.Error(t, registry.RegisterBuiltin("unknown", "xml"))
+	require.NoError(t, registry.RegisterBuiltin("unknown", "JSON"))
+
+	_, err = registry.EncodeResponse(ResponseAdaptor{StatusCode: 200, ServiceName: "unknown", Output: `{"a":`})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed output")
+}
+
+func TestEncodeResponseFailed(t *testing.T) {
+	registry := NewEncoderRegistry()
+
+	response := ResponseAdaptor{StatusCode: 500, ServiceName: "unknown", Result: `{"code":500,"message":"timeout"}`}
+
+	encoded, err := registry.EncodeResponse(response)
+	require.NoError(t, err)
+	require.Equal(t, response, encoded)
+}
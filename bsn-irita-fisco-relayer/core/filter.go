@@ -0,0 +1,65 @@
+package core
+
+import (
+	"sync"
+
+	"relayer/common"
+)
+
+// Event is a source event emitted by an app chain to be relayed
+type Event struct {
+	SourceID common.DestID     // dest ID of the source app chain
+	ChainID  string            // chain ID of the source app chain
+	TxHash   string            // hash of the tx emitting the event
+	Request  InterchainRequest // interchain request carried by the event
+}
+
+// EventFilter decides whether to relay the given source event
+type EventFilter func(event Event) bool
+
+// FilterRegistry holds the event filters by the normalized dest ID of the source app chain
+// It is safe for concurrent use
+type FilterRegistry struct {
+	mtx     sync.RWMutex
+	filters map[common.DestID][]EventFilter
+}
+
+// NewFilterRegistry constructs a new empty FilterRegistry instance
+func NewFilterRegistry() *FilterRegistry {
+	return &FilterRegistry{
+		filters: make(map[common.DestID][]EventFilter),
+	}
+}
+
+// Register appends the given filters for the source app chain
+func (r *FilterRegistry) Register(destID common.DestID, filters ...EventFilter) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	destID = destID.Normalize()
+	r.filters[destID] = append(r.filters[destID], filters...)
+}
+
+// Reset removes all the filters of the source app chain
+func (r *FilterRegistry) Reset(destID common.DestID) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	delete(r.filters, destID.Normalize())
+}
+
+// Allow returns true if all the filters of the source app chain accept the event
+// The events of a chain without filters are always relayed
+func (r *FilterRegistry) Allow(event Event) bool {
+	r.mtx.RLock()
+	filters := r.filters[event.SourceID.Normalize()]
+	r.mtx.RUnlock()
+
+	for _, filter := range filters {
+		if !filter(event) {
+			return false
+		}
+	}
+
+	return true
+}
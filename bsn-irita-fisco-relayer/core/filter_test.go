@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+func TestFilterRegistryChaining(t *testing.T) {
+	registry := NewFilterRegistry()
+
+	source := common.DestID("fisco-1-1")
+	event := Event{
+		SourceID: source,
+		Request:  InterchainRequest{ID: "req-1", Method: "transfer", Sender: "0xabc"},
+	}
+
+	require.True(t, registry.Allow(event))
+
+	registry.Register(source, func(e Event) bool { return e.Request.Method == "transfer" })
+	require.True(t, registry.Allow(event))
+
+	registry.Register(source, func(e Event) bool { return e.Request.Sender == "0xdef" })
+	require.False(t, registry.Allow(event))
+
+	// the filters of other chains do not apply
+	event.SourceID = common.DestID("fisco-2-1")
+	require.True(t, registry.Allow(event))
+
+	registry.Reset(source)
+	event.SourceID = source
+	require.True(t, registry.Allow(event))
+}
+
+func TestHandleFilteredRequest(t *testing.T) {
+	source := common.DestID("fisco-1-1")
+
+	relayer := NewRelayer("fisco", nil, nil, nil, nil)
+	relayer.AppChains["chain-1"] = &mockAppChain{destID: source}
+	relayer.RegisterEventFilter(source, func(e Event) bool { return e.Request.Method == "allowed" })
+
+	// the filtered request is acknowledged without reaching the hub
+	err := relayer.HandleInterchainRequest("chain-1", InterchainRequest{ID: "req-1", Method: "denied"}, "0x01")
+	require.NoError(t, err)
+	require.Equal(t, 0, relayer.Dedup.Len())
+}
@@ -1,55 +1,439 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"relayer/common"
+	"relayer/logging"
+	"relayer/metrics"
 	"relayer/mysql"
 )
 
+// ErrRelayerClosing is returned when a request arrives after the relayer starts shutting down
+var ErrRelayerClosing = fmt.Errorf("relayer is shutting down")
+
+// ErrNoResponse is passed to the response callback of a request ended on the Hub without any response
+var ErrNoResponse = errors.New("no response received")
+
 // HandleInterchainRequest handles the interchain request
 func (r *Relayer) HandleInterchainRequest(chainID string, request InterchainRequest, txHash string) error {
-	r.Logger.Infof("got the interchain request on %s: %+v", chainID, request)
+	return r.handleInterchainRequest(chainID, request, txHash, nil)
+}
 
-	mysql.OnInterchainRequestReceived(request.ID, chainID, txHash)
+// handleInterchainRequest handles the interchain request, calling accepted if any once the
+// request is about to be relayed, i.e. not deferred, filtered out, dropped or rejected
+func (r *Relayer) handleInterchainRequest(chainID string, request InterchainRequest, txHash string, accepted func()) error {
+	if r.isClosing() {
+		return ErrRelayerClosing
+	}
+
+	logger := r.requestLogger(chainID, request.ID)
+
+	// the events are rejected while the responses can not be sent back, so that the
+	// chain monitor retries them instead of advancing the checkpoint
+	if r.breaker(chainID).State() == CircuitOpen {
+		logger.Debugf("interchain request from tx %s deferred, circuit open", txHash)
+		return ErrCircuitOpen
+	}
+
+	// the events are rejected while too many requests are in flight, pausing the listener
+	if r.Queue != nil && r.Queue.Paused() {
+		logger.Debugf("interchain request from tx %s deferred, event queue full", txHash)
+		return ErrQueueFull
+	}
+
+	// the filtered events are acknowledged, so the checkpoint still advances
+	if !r.Filters.Allow(r.sourceEvent(chainID, request, txHash)) {
+		metrics.RequestsFiltered.WithLabelValues(r.metricLabels(chainID, request)...).Inc()
+		logger.Debugf("interchain request from tx %s filtered out", txHash)
+
+		request.TxHash = txHash
+
+		return r.audit(chainID, request, DecisionFiltered, "", nil)
+	}
+
+	if r.seen(chainID, request.ID) {
+		metrics.RequestsDuplicated.WithLabelValues(r.metricLabels(chainID, request)...).Inc()
+		logger.Infof("duplicate interchain request from tx %s dropped", txHash)
+
+		request.TxHash = txHash
+
+		return r.audit(chainID, request, DecisionDropped, "", nil)
+	}
+
+	// the unrouted request is dead-lettered, so that it is routed again on resubmission
+	routed, err := r.route(chainID, request)
+	if err == nil {
+		err = r.validateInput(routed)
+	}
+
+	if err != nil {
+		r.forget(chainID, request.ID)
+
+		metrics.RelayErrors.WithLabelValues(r.metricLabels(chainID, request)...).Inc()
+		logger.Errorf("interchain request from tx %s rejected: %s", txHash, err)
+
+		request.TxHash = txHash
+		if auditErr := r.deadLetter(chainID, StageRequest, request, nil, err); auditErr != nil {
+			return auditErr
+		}
+
+		return Rejected(err)
+	}
+
+	source := request
+	request = routed
+
+	logger.Infof("got the interchain request: %+v", request)
+
+	if r.DryRun {
+		logger.Infof("dry run: would relay the request from tx %s to %s", txHash, request.GetDestID())
+		return nil
+	}
 
 	request.TxHash = txHash
 
-	callback := func(icRequestID string, response ResponseI) {
-		r.Logger.Infof(
-			"got the response of the interchain request on %s: %+v",
-			r.HubChain.GetChainID(),
-			response,
-		)
+	// the request is read again, so not seen yet
+	if err := r.audit(chainID, request, DecisionAccepted, "", nil); err != nil {
+		r.forget(chainID, request.ID)
+		return err
+	}
 
-		// TODO
-		mysql.OnInterchainRequestHandled()
+	metrics.RequestsReceived.WithLabelValues(r.metricLabels(chainID, request)...).Inc()
 
-		err := r.AppChains[chainID].SendResponse(request.ID, response)
-		if err != nil {
-			r.Logger.Errorf(
-				"failed to send the response to %s: %s",
-				chainID,
-				err,
-			)
+	mysql.OnInterchainRequestReceived(request.ID, chainID, txHash)
 
-			return
+	r.track()
+
+	// the ticket is taken in the order the requests are received, i.e. emitted on the source chain
+	ticket := r.takeTicket(chainID, request)
+	receivedAt := time.Now()
+
+	source.TxHash = txHash
+
+	pending := PendingRequest{
+		ChainID:    chainID,
+		Request:    request,
+		Source:     &source,
+		ReceivedAt: receivedAt,
+	}
+
+	record := RelayRecord{
+		ChainID: chainID,
+		TxHash:  txHash,
+	}
+
+	if err := r.saveRecord(r.sourceDestID(chainID), request.ID, record); err != nil {
+		logger.Errorf("failed to save the relay record: %s", err)
+	}
+
+	sent := func(reqCtxID string, icRequestID string) {
+		pending.ReqCtxID = reqCtxID
+		pending.ICRequestID = icRequestID
+
+		if err := r.savePending(pending); err != nil {
+			logger.Errorf("failed to save the pending request: %s", err)
 		}
 
-		r.Logger.Infof(
-			"response sent to %s successfully",
-			chainID,
-		)
+		record.ReqCtxID = reqCtxID
+		record.ICRequestID = icRequestID
+
+		if err := r.saveRecord(r.sourceDestID(chainID), request.ID, record); err != nil {
+			logger.Errorf("failed to save the relay record: %s", err)
+		}
+	}
+
+	if accepted != nil {
+		accepted()
 	}
 
-	err := r.HubChain.SendInterchainRequest(request, callback)
+	err = r.HubChain.SendInterchainRequest(r.ctx, request, sent, r.responseCallback(chainID, source, request, receivedAt, ticket))
 	if err != nil {
-		r.Logger.Errorf(
+		r.untrack()
+		r.releaseTicket(ticket)
+
+		// allow the request to be relayed again on redelivery
+		r.forget(chainID, request.ID)
+
+		metrics.RelayErrors.WithLabelValues(r.metricLabels(chainID, request)...).Inc()
+		logger.Errorf(
 			"failed to handle the interchain request %+v on %s: %s",
 			request,
 			r.HubChain.GetChainID(),
 			err,
 		)
 
+		if auditErr := r.deadLetter(chainID, StageRequest, source, nil, err); auditErr != nil {
+			return auditErr
+		}
+
+		return Rejected(err)
+	}
+
+	logger.Debug("interchain request handled")
+
+	return nil
+}
+
+// resume resumes waiting for the response of the given pending request
+func (r *Relayer) resume(p PendingRequest) error {
+	r.track()
+
+	ticket := r.takeTicket(p.ChainID, p.Request)
+
+	receivedAt := p.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	// the records of the previous versions lack the source request
+	source := p.Request
+	if p.Source != nil {
+		source = *p.Source
+	}
+
+	err := r.HubChain.ResumeInterchainRequest(p.ReqCtxID, p.ICRequestID, r.responseCallback(p.ChainID, source, p.Request, receivedAt, ticket))
+	if err != nil {
+		r.untrack()
+		r.releaseTicket(ticket)
+
 		return err
 	}
-	r.Logger.Infof("HandleInterchainRequest is End !!!")
+
 	return nil
 }
+
+// responseCallback returns the callback which relays the response to the source app chain
+// The in-flight counter is released once the response is handled. With a ticket, the response
+// waits for the turn of its shard and is confirmed synchronously before the turn is passed on.
+// The request ended on the Hub without a response is dead-lettered as received from the source,
+// so that it is routed again on resubmission
+func (r *Relayer) responseCallback(chainID string, source InterchainRequest, request InterchainRequest, receivedAt time.Time, ticket *Ticket) ResponseCallback {
+	logger := r.requestLogger(chainID, request.ID)
+	labels := r.metricLabels(chainID, request)
+
+	return func(icRequestID string, response ResponseI, err error) {
+		defer r.untrack()
+
+		respondedAt := time.Now()
+
+		if err != nil {
+			defer r.releaseTicket(ticket)
+
+			metrics.RelayErrors.WithLabelValues(labels...).Inc()
+			logger.WithField(logging.FieldHubRequestID, icRequestID).Errorf("no response of the interchain request on %s: %s", r.HubChain.GetChainID(), err)
+
+			if auditErr := r.deadLetter(chainID, StageRequest, source, nil, err); auditErr != nil {
+				logger.Errorf("failed to audit the dead letter: %s", auditErr)
+			}
+
+			if err := r.deletePending(request.ID); err != nil {
+				logger.Errorf("failed to delete the pending request: %s", err)
+			}
+
+			return
+		}
+
+		if ticket != nil {
+			defer r.releaseTicket(ticket)
+
+			// the request is left pending, so that it is resumed in order on the next start
+			if err := ticket.Wait(r.ctx); err != nil {
+				logger.Warnf("response of the ordered request not relayed: %s", err)
+				return
+			}
+		}
+
+		response = withRoutedService(request, response)
+
+		logger.WithField(logging.FieldHubRequestID, icRequestID).Infof(
+			"got the response of the interchain request on %s: %+v",
+			r.HubChain.GetChainID(),
+			response,
+		)
+
+		// TODO
+		mysql.OnInterchainRequestHandled()
+
+		// the app chain marks the milestones of the response tx on the trace
+		trace := NewRelayTrace()
+		trace.Mark(MilestoneEmitted, request.EmittedAt)
+		trace.Mark(MilestoneEnqueued, receivedAt)
+		trace.Mark(MilestoneResponded, respondedAt)
+
+		responseTxHash, pending, err := r.dispatchResponse(WithRelayTrace(r.ctx, trace), chainID, request.ID, response, ticket == nil)
+
+		// the confirmation of the pending tx is observed by the watcher
+		r.observeTrace(chainID, request, trace)
+
+		if err != nil {
+			metrics.RelayErrors.WithLabelValues(labels...).Inc()
+			logger.Errorf("failed to send the response: %s", err)
+
+			if auditErr := r.deadLetter(chainID, StageResponse, request, response, err); auditErr != nil {
+				logger.Errorf("failed to audit the dead letter: %s", auditErr)
+			}
+		} else if pending {
+			confirmation := PendingConfirmation{
+				ChainID:     chainID,
+				Request:     request,
+				ICRequestID: icRequestID,
+				Response:    toResponseAdaptor(response),
+				TxHash:      responseTxHash,
+				ReceivedAt:  receivedAt,
+				SubmittedAt: time.Now(),
+				BroadcastAt: trace.At(MilestoneBroadcast),
+			}
+
+			// the tx is already broadcast, so the request is considered relayed if it can not be watched
+			if err := r.awaitConfirmation(confirmation); err != nil {
+				logger.Errorf("failed to save the pending confirmation of tx %s, marked relayed unconfirmed: %s", responseTxHash, err)
+				r.markRelayed(chainID, request, icRequestID, responseTxHash)
+			} else {
+				logger.WithField(logging.FieldTxHash, responseTxHash).Info("response transaction submitted, awaiting confirmation")
+			}
+		} else {
+			metrics.RequestsRelayed.WithLabelValues(labels...).Inc()
+			metrics.RelayLatency.WithLabelValues(labels...).Observe(time.Since(receivedAt).Seconds())
+
+			logger.WithField(logging.FieldStage, logging.StageResponseRelayed).Info("response sent successfully")
+
+			r.markRelayed(chainID, request, icRequestID, responseTxHash)
+		}
+
+		if err := r.deletePending(request.ID); err != nil {
+			logger.Errorf("failed to delete the pending request: %s", err)
+		}
+	}
+}
+
+// sendResponse encodes the response for its service and sends it to the source app chain,
+// waiting for the confirmation of the response tx
+// Nothing is sent if the output can not be encoded
+func (r *Relayer) sendResponse(ctx context.Context, chainID string, requestID string, response ResponseI) (string, error) {
+	txHash, _, err := r.dispatchResponse(ctx, chainID, requestID, response, false)
+	return txHash, err
+}
+
+// dispatchResponse is the same as sendResponse except that with async set, the response tx
+// of the chains confirming asynchronously is only broadcast, pending being true if so
+func (r *Relayer) dispatchResponse(ctx context.Context, chainID string, requestID string, response ResponseI, async bool) (txHash string, pending bool, err error) {
+	encoded, err := r.Encoders.EncodeResponse(response)
+	if err != nil {
+		return "", false, err
+	}
+
+	chain, ok := r.appChain(chainID)
+	if !ok {
+		return "", false, fmt.Errorf("chain %s not running", chainID)
+	}
+
+	// the responses wait for the open circuit to close, up to the buffer bound
+	breaker := r.breaker(chainID)
+	if err := breaker.Wait(ctx); err != nil {
+		return "", false, fmt.Errorf("failed to send the response to %s: %w", chain.GetDestID(), err)
+	}
+
+	// the response may have landed before a crash; the check fails safe, sending the
+	// response if the chain can not tell
+	if checker, ok := chain.(ResponseChecker); ok && checker.ResponseCheckEnabled() {
+		exists, err := checker.ResponseExists(ctx, requestID)
+		if err != nil {
+			r.requestLogger(chainID, requestID).Warnf("failed to check if the response exists, sending it: %s", err)
+		} else if exists {
+			r.requestLogger(chainID, requestID).Infof("response already on chain, skipped")
+			breaker.Record(nil)
+
+			return "", false, nil
+		}
+	}
+
+	sender := r.asyncSender(chain)
+
+	if async && sender != nil {
+		txHash, err = sender.SubmitResponse(ctx, requestID, encoded)
+		pending = err == nil && len(txHash) != 0
+	} else {
+		txHash, err = chain.SendResponse(ctx, requestID, encoded)
+	}
+
+	breaker.Record(err)
+
+	return txHash, pending, err
+}
+
+// markRelayed records the response tx of the relayed request
+// The Hub request IDs are kept from the record saved on submission
+func (r *Relayer) markRelayed(chainID string, request InterchainRequest, icRequestID string, responseTxHash string) {
+	logger := r.requestLogger(chainID, request.ID)
+
+	destID := r.sourceDestID(chainID)
+
+	record, err := r.loadRecord(destID, request.ID)
+	if err != nil {
+		logger.Errorf("failed to load the relay record: %s", err)
+	}
+
+	if record == nil {
+		record = &RelayRecord{ChainID: chainID, TxHash: request.TxHash, ICRequestID: icRequestID}
+	}
+
+	record.ResponseTxHash = responseTxHash
+	record.Relayed = true
+
+	r.audit(chainID, request, DecisionRelayed, responseTxHash, nil)
+
+	if err := r.saveRecord(destID, request.ID, *record); err != nil {
+		logger.Errorf("failed to save the relay record: %s", err)
+	}
+}
+
+// sourceEvent builds the source event of the given request for filtering
+func (r *Relayer) sourceEvent(chainID string, request InterchainRequest, txHash string) Event {
+	event := Event{
+		ChainID: chainID,
+		TxHash:  txHash,
+		Request: request,
+	}
+
+	if chain, ok := r.appChain(chainID); ok {
+		event.SourceID = chain.GetDestID()
+	}
+
+	return event
+}
+
+// sourceDestID returns the dest ID of the given app chain, the chain ID if it is not running
+func (r *Relayer) sourceDestID(chainID string) common.DestID {
+	if chain, ok := r.appChain(chainID); ok {
+		return chain.GetDestID()
+	}
+
+	return common.DestID(chainID)
+}
+
+// requestLogger returns the log entry for the given request on the specified app chain
+func (r *Relayer) requestLogger(chainID string, requestID string) *log.Entry {
+	entry := logging.WithRequestID(requestID)
+
+	if chain, ok := r.appChain(chainID); ok {
+		entry = entry.WithField(logging.FieldDestID, chain.GetDestID().String())
+	}
+
+	return entry
+}
+
+// metricLabels returns the source and destination labels of the given request
+func (r *Relayer) metricLabels(chainID string, request InterchainRequest) []string {
+	source := chainID
+	if chain, ok := r.appChain(chainID); ok {
+		source = chain.GetDestID().String()
+	}
+
+	return []string{source, request.GetDestID().String()}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockCheckedChain is an AppChainI checking the existing responses before sending
+type mockCheckedChain struct {
+	mockAppChain
+	enabled  bool
+	exists   bool
+	checkErr error
+	checked  int
+	sent     int
+}
+
+func (m *mockCheckedChain) ResponseCheckEnabled() bool { return m.enabled }
+
+func (m *mockCheckedChain) ResponseExists(context.Context, string) (bool, error) {
+	m.checked++
+	return m.exists, m.checkErr
+}
+
+func (m *mockCheckedChain) SendResponse(context.Context, string, ResponseI) (string, error) {
+	m.sent++
+	return "0x01", nil
+}
+
+func TestSendResponseSkipsExisting(t *testing.T) {
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+
+	chain := &mockCheckedChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}}
+	r.AppChains["1"] = chain
+
+	response := ResponseAdaptor{StatusCode: 500, Result: "service unavailable"}
+
+	// the check is disabled
+	txHash, err := r.sendResponse(context.Background(), "1", "req-1", response)
+	require.NoError(t, err)
+	require.Equal(t, "0x01", txHash)
+	require.Equal(t, 0, chain.checked)
+
+	chain.enabled = true
+	chain.exists = true
+
+	txHash, err = r.sendResponse(context.Background(), "1", "req-1", response)
+	require.NoError(t, err)
+	require.Empty(t, txHash)
+	require.Equal(t, 1, chain.sent)
+
+	// the response is sent if the check fails
+	chain.exists = false
+	chain.checkErr = errors.New("connection refused")
+
+	txHash, err = r.sendResponse(context.Background(), "1", "req-1", response)
+	require.NoError(t, err)
+	require.Equal(t, "0x01", txHash)
+	require.Equal(t, 2, chain.sent)
+	require.Equal(t, 2, chain.checked)
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"relayer/common"
+	"relayer/logging"
+)
+
+const (
+	// DefaultStalenessThreshold is the default maximum age of the latest block seen on a chain
+	DefaultStalenessThreshold = 60 * time.Second
+
+	// DefaultHeartbeatInterval is the default interval between two heartbeats of the main loop
+	DefaultHeartbeatInterval = 10 * time.Second
+
+	// heartbeatMisses is the number of heartbeats missed before the relayer is reported not alive
+	heartbeatMisses = 3
+)
+
+// HealthConfig defines the health check config
+type HealthConfig struct {
+	StalenessThreshold time.Duration            // default staleness threshold
+	ChainThresholds    map[string]time.Duration // staleness thresholds by dest ID or chain type
+	HeartbeatInterval  time.Duration            // interval between two heartbeats of the main loop
+}
+
+// Interval returns the heartbeat interval, the default one if unset
+func (c HealthConfig) Interval() time.Duration {
+	if c.HeartbeatInterval > 0 {
+		return c.HeartbeatInterval
+	}
+
+	return DefaultHeartbeatInterval
+}
+
+// Threshold returns the staleness threshold of the given chain
+// The dest ID takes precedence over the chain type
+func (c HealthConfig) Threshold(destID common.DestID) time.Duration {
+	if t, ok := c.ChainThresholds[strings.ToLower(destID.String())]; ok && t > 0 {
+		return t
+	}
+
+	if t, ok := c.ChainThresholds[strings.ToLower(destID.ChainType())]; ok && t > 0 {
+		return t
+	}
+
+	if c.StalenessThreshold > 0 {
+		return c.StalenessThreshold
+	}
+
+	return DefaultStalenessThreshold
+}
+
+// ChainHealth defines the health of a chain monitor
+type ChainHealth struct {
+	DestID     string  `json:"dest_id"`
+	Connected  bool    `json:"connected"`
+	LastHeight int64   `json:"last_height"`
+	Age        float64 `json:"age_seconds"`       // seconds since the latest height was seen
+	Threshold  float64 `json:"threshold_seconds"` // staleness threshold in seconds
+	Circuit    string  `json:"circuit"`           // circuit breaker state
+	Healthy    bool    `json:"healthy"`
+}
+
+// HealthReport defines the health of the relayer
+type HealthReport struct {
+	Healthy bool          `json:"healthy"`
+	Chains  []ChainHealth `json:"chains"`
+}
+
+// CheckHealth reports the health of the running app chain monitors
+// The relayer is healthy only if every monitor is connected, has seen a new
+// block within the staleness threshold of the chain, and its circuit is not open
+func (r *Relayer) CheckHealth() HealthReport {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	report := HealthReport{
+		Healthy: true,
+		Chains:  make([]ChainHealth, 0, len(r.AppChains)),
+	}
+
+	now := time.Now()
+
+	for chainID, state := range r.AppChainStates {
+		if !state {
+			continue
+		}
+
+		chain := r.AppChains[chainID]
+		liveness := chain.GetLiveness()
+		threshold := r.Health.Threshold(chain.GetDestID())
+		age := now.Sub(liveness.LastSeenAt)
+		circuit := r.Breakers.Get(chain.GetDestID()).State()
+
+		health := ChainHealth{
+			DestID:     chain.GetDestID().String(),
+			Connected:  liveness.Connected,
+			LastHeight: liveness.LastHeight,
+			Age:        age.Seconds(),
+			Threshold:  threshold.Seconds(),
+			Circuit:    circuit,
+			Healthy:    liveness.Connected && age <= threshold && circuit != CircuitOpen,
+		}
+
+		report.Healthy = report.Healthy && health.Healthy
+		report.Chains = append(report.Chains, health)
+	}
+
+	sort.Slice(report.Chains, func(i, j int) bool {
+		return report.Chains[i].DestID < report.Chains[j].DestID
+	})
+
+	return report
+}
+
+// LivenessReport defines the liveness of the relayer main loop
+type LivenessReport struct {
+	Alive bool    `json:"alive"`
+	Age   float64 `json:"age_seconds"` // seconds since the last heartbeat
+}
+
+// Heartbeat records that the main loop is running
+func (r *Relayer) Heartbeat() {
+	atomic.StoreInt64(&r.heartbeat, time.Now().UnixNano())
+}
+
+// CheckLiveness reports whether the main loop is running
+// The relayer is alive until it misses a few heartbeats in a row; the
+// construction counts as the first heartbeat
+func (r *Relayer) CheckLiveness() LivenessReport {
+	age := time.Since(time.Unix(0, atomic.LoadInt64(&r.heartbeat)))
+
+	return LivenessReport{
+		Alive: age <= heartbeatMisses*r.Health.Interval(),
+		Age:   age.Seconds(),
+	}
+}
+
+// listenerWatch tracks the outages of the chain listeners between two checks
+type listenerWatch struct {
+	downSince map[common.DestID]time.Time // time the listeners were first seen down
+	notified  map[common.DestID]bool      // listeners whose outage is notified
+}
+
+// newListenerWatch constructs a new listenerWatch instance
+func newListenerWatch() *listenerWatch {
+	return &listenerWatch{
+		downSince: make(map[common.DestID]time.Time),
+		notified:  make(map[common.DestID]bool),
+	}
+}
+
+// WatchListeners checks the running chain listeners every interval until the context is done
+// A listener is down while disconnected or without a new block, and is notified once it stays
+// down past the staleness threshold of its chain. The notification is not repeated until it recovers
+func (r *Relayer) WatchListeners(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultListenerCheckInterval
+	}
+
+	watch := newListenerWatch()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.checkListeners(watch, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkListeners notifies the listeners down past their threshold at the given time
+func (r *Relayer) checkListeners(watch *listenerWatch, now time.Time) {
+	r.mtx.Lock()
+
+	running := make(map[common.DestID]ChainLiveness)
+	for chainID, state := range r.AppChainStates {
+		if state {
+			running[r.AppChains[chainID].GetDestID()] = r.AppChains[chainID].GetLiveness()
+		}
+	}
+
+	r.mtx.Unlock()
+
+	// the stopped chains are no longer watched
+	for destID := range watch.downSince {
+		if _, ok := running[destID]; !ok {
+			delete(watch.downSince, destID)
+			delete(watch.notified, destID)
+		}
+	}
+
+	for destID, liveness := range running {
+		threshold := r.Health.Threshold(destID)
+		stale := now.Sub(liveness.LastSeenAt) > threshold
+
+		if liveness.Connected && !stale {
+			if watch.notified[destID] {
+				logging.WithChain(destID).Info("chain listener recovered")
+			}
+
+			delete(watch.downSince, destID)
+			delete(watch.notified, destID)
+
+			continue
+		}
+
+		since, ok := watch.downSince[destID]
+		if !ok {
+			since = now
+			watch.downSince[destID] = since
+		}
+
+		if watch.notified[destID] || !stale && now.Sub(since) < threshold {
+			continue
+		}
+
+		watch.notified[destID] = true
+
+		logging.WithChain(destID).Warnf("chain listener down, last block %d seen at %s", liveness.LastHeight, liveness.LastSeenAt.Format(time.RFC3339))
+
+		r.Notify(NotificationEvent{
+			Type:    NotifyListenerDown,
+			DestID:  destID.String(),
+			Message: fmt.Sprintf("chain listener down past the threshold %s", threshold),
+			Details: map[string]string{
+				"connected":    fmt.Sprintf("%t", liveness.Connected),
+				"last_height":  fmt.Sprintf("%d", liveness.LastHeight),
+				"last_seen_at": liveness.LastSeenAt.UTC().Format(time.RFC3339),
+			},
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+// mockAppChain is an AppChainI reporting the given liveness
+type mockAppChain struct {
+	destID   common.DestID
+	liveness ChainLiveness
+}
+
+func (m *mockAppChain) GetChainID() string                           { return m.destID.ChainID() }
+func (m *mockAppChain) Start(handler InterchainRequestHandler) error { return nil }
+func (m *mockAppChain) Stop() error                                  { return nil }
+func (m *mockAppChain) GetHeight() int64                             { return m.liveness.LastHeight }
+func (m *mockAppChain) GetDestID() common.DestID                     { return m.destID }
+func (m *mockAppChain) CheckConnection(ctx context.Context) error    { return nil }
+func (m *mockAppChain) GetLiveness() ChainLiveness                   { return m.liveness }
+func (m *mockAppChain) SendResponse(context.Context, string, ResponseI) (string, error) {
+	return "", nil
+}
+
+func TestHealthConfigThreshold(t *testing.T) {
+	config := HealthConfig{
+		StalenessThreshold: time.Minute,
+		ChainThresholds: map[string]time.Duration{
+			"fisco":     30 * time.Second,
+			"fisco-1-2": 2 * time.Minute,
+		},
+	}
+
+	require.Equal(t, 30*time.Second, config.Threshold("fisco-1-1"))
+	require.Equal(t, 2*time.Minute, config.Threshold("fisco-1-2"))
+	require.Equal(t, time.Minute, config.Threshold("eth-1"))
+	require.Equal(t, DefaultStalenessThreshold, HealthConfig{}.Threshold("eth-1"))
+}
+
+func TestCheckHealth(t *testing.T) {
+	now := time.Now()
+
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.Health = HealthConfig{StalenessThreshold: time.Minute}
+
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1", liveness: ChainLiveness{Connected: true, LastHeight: 10, LastSeenAt: now}}
+	r.AppChains["2"] = &mockAppChain{destID: "fisco-1-2", liveness: ChainLiveness{Connected: true, LastHeight: 20, LastSeenAt: now.Add(-2 * time.Minute)}}
+	r.AppChainStates["1"] = true
+	r.AppChainStates["2"] = false
+
+	report := r.CheckHealth()
+	require.True(t, report.Healthy)
+	require.Len(t, report.Chains, 1)
+	require.Equal(t, "fisco-1-1", report.Chains[0].DestID)
+	require.Equal(t, int64(10), report.Chains[0].LastHeight)
+
+	// the stale chain fails the check once started
+	r.AppChainStates["2"] = true
+
+	report = r.CheckHealth()
+	require.False(t, report.Healthy)
+	require.Len(t, report.Chains, 2)
+	require.True(t, report.Chains[0].Healthy)
+	require.False(t, report.Chains[1].Healthy)
+	require.True(t, report.Chains[1].Age >= 120)
+
+	// a disconnected chain is unhealthy even with a recent block
+	r.AppChains["2"].(*mockAppChain).liveness = ChainLiveness{LastHeight: 20, LastSeenAt: now}
+
+	report = r.CheckHealth()
+	require.False(t, report.Healthy)
+	require.False(t, report.Chains[1].Connected)
+}
+
+func TestCheckLiveness(t *testing.T) {
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.Health = HealthConfig{HeartbeatInterval: time.Second}
+
+	// the construction counts as the first heartbeat
+	require.True(t, r.CheckLiveness().Alive)
+
+	// the main loop missing its heartbeats is not alive
+	r.heartbeat = time.Now().Add(-4 * time.Second).UnixNano()
+
+	report := r.CheckLiveness()
+	require.False(t, report.Alive)
+	require.True(t, report.Age >= 4)
+
+	r.Heartbeat()
+	require.True(t, r.CheckLiveness().Alive)
+}
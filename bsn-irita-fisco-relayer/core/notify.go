@@ -0,0 +1,248 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"relayer/logging"
+)
+
+// notification event types
+const (
+	NotifyDeadLetter   = "dead_letter"   // request failed permanently
+	NotifyListenerDown = "listener_down" // chain listener down past the staleness threshold
+	NotifyLowBalance   = "low_balance"   // signing account below the balance threshold
+	NotifyCircuitOpen  = "circuit_open"  // circuit of a chain opened
+)
+
+const (
+	// DefaultNotifyTimeout is the default delivery timeout of a notification
+	DefaultNotifyTimeout = 5 * time.Second
+
+	// DefaultNotifyMaxPending is the default number of notifications being delivered,
+	// beyond which the new ones are dropped
+	DefaultNotifyMaxPending = 100
+
+	// DefaultListenerCheckInterval is the default interval between two checks of the listeners
+	DefaultListenerCheckInterval = 15 * time.Second
+)
+
+// NotificationEvent is a critical relay failure notified to the operators
+type NotificationEvent struct {
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	DestID    string            `json:"dest_id,omitempty"`    // chain of the failure
+	RequestID string            `json:"request_id,omitempty"` // failed request, if any
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Notifier defines the interface to deliver the notifications, e.g. to the alerting
+type Notifier interface {
+	// Notify delivers the event within the context deadline
+	Notify(ctx context.Context, event NotificationEvent) error
+}
+
+// NopNotifier is a Notifier discarding the events
+type NopNotifier struct{}
+
+var _ Notifier = NopNotifier{}
+
+// Notify implements Notifier
+func (NopNotifier) Notify(context.Context, NotificationEvent) error {
+	return nil
+}
+
+// WebhookNotifier is a Notifier POSTing the events as JSON to a URL
+// The headers, e.g. carrying an auth token, are never logged
+type WebhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+var _ Notifier = (*WebhookNotifier)(nil)
+
+// NewWebhookNotifier constructs a new WebhookNotifier instance posting to the given http(s) URL
+// The URL is left out of the errors, as it may embed a token
+func NewWebhookNotifier(webhookURL string, headers map[string]string) (*WebhookNotifier, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %s", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid webhook URL: expected an http or https URL")
+	}
+
+	return &WebhookNotifier{
+		url:     webhookURL,
+		headers: headers,
+		client:  &http.Client{},
+	}, nil
+}
+
+// Notify implements Notifier
+// The responses other than 2xx are reported as failures
+func (w *WebhookNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	bz, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(bz))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+
+	// the URL is stripped from the transport errors
+	resp, err := w.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// drain the body so that the connection is reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// NotifyConfig defines the notification params
+type NotifyConfig struct {
+	Events     []string      // event types notified, all if empty
+	Timeout    time.Duration // delivery timeout of a notification
+	MaxPending int           // notifications being delivered, beyond which the new ones are dropped
+}
+
+// normalize fills the unset params with the default values
+func (c NotifyConfig) normalize() NotifyConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultNotifyTimeout
+	}
+
+	if c.MaxPending <= 0 {
+		c.MaxPending = DefaultNotifyMaxPending
+	}
+
+	return c
+}
+
+// Validate validates the event types
+func (c NotifyConfig) Validate() error {
+	for _, event := range c.Events {
+		switch strings.ToLower(event) {
+		case NotifyDeadLetter, NotifyListenerDown, NotifyLowBalance, NotifyCircuitOpen:
+		default:
+			return fmt.Errorf(
+				"invalid notification event %s: expected %s, %s, %s or %s",
+				event, NotifyDeadLetter, NotifyListenerDown, NotifyLowBalance, NotifyCircuitOpen,
+			)
+		}
+	}
+
+	return nil
+}
+
+// Notifications delivers the notifications of the configured events in the background
+// Publish never blocks the relay path: the delivery failures are logged and dropped,
+// as are the notifications published while too many are being delivered
+type Notifications struct {
+	notifier Notifier
+	config   NotifyConfig
+	events   map[string]bool // notified event types, all if empty
+	slots    chan struct{}   // notifications being delivered
+}
+
+// NewNotifications constructs a new Notifications instance on the given notifier
+func NewNotifications(notifier Notifier, config NotifyConfig) *Notifications {
+	config = config.normalize()
+
+	events := make(map[string]bool, len(config.Events))
+	for _, event := range config.Events {
+		events[strings.ToLower(event)] = true
+	}
+
+	return &Notifications{
+		notifier: notifier,
+		config:   config,
+		events:   events,
+		slots:    make(chan struct{}, config.MaxPending),
+	}
+}
+
+// Enabled returns true if the given event type is notified
+func (n *Notifications) Enabled(eventType string) bool {
+	if _, ok := n.notifier.(NopNotifier); ok {
+		return false
+	}
+
+	return len(n.events) == 0 || n.events[eventType]
+}
+
+// Publish delivers the event asynchronously if its type is notified
+func (n *Notifications) Publish(event NotificationEvent) {
+	if !n.Enabled(event.Type) {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	select {
+	case n.slots <- struct{}{}:
+	default:
+		logging.Logger.Warnf("%s notification dropped, %d notifications being delivered", event.Type, n.config.MaxPending)
+		return
+	}
+
+	go func() {
+		defer func() { <-n.slots }()
+
+		// a failing notifier never takes the relayer down
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Logger.Errorf("notifier panicked on the %s notification: %v", event.Type, r)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), n.config.Timeout)
+		defer cancel()
+
+		if err := n.notifier.Notify(ctx, event); err != nil {
+			logging.Logger.Warnf("failed to deliver the %s notification: %s", event.Type, err)
+		}
+	}()
+}
+
+// Notify publishes the event to the notifications of the relayer
+func (r *Relayer) Notify(event NotificationEvent) {
+	if r.Notifications != nil {
+		r.Notifications.Publish(event)
+	}
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockNotifier is a Notifier forwarding the events to a channel, blocking while block is open
+type mockNotifier struct {
+	events chan NotificationEvent
+	block  chan struct{}
+	err    error
+}
+
+func newMockNotifier() *mockNotifier {
+	return &mockNotifier{events: make(chan NotificationEvent, 10)}
+}
+
+func (m *mockNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	if m.block != nil {
+		<-m.block
+	}
+
+	m.events <- event
+
+	return m.err
+}
+
+// next returns the next event notified, failing the test if none arrives in time
+func (m *mockNotifier) next(t *testing.T) NotificationEvent {
+	select {
+	case event := <-m.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no notification delivered")
+		return NotificationEvent{}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan NotificationEvent, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var event NotificationEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, map[string]string{"authorization": "Bearer token"})
+	require.NoError(t, err)
+
+	event := NotificationEvent{Type: NotifyCircuitOpen, DestID: "fisco-1-1", Message: "circuit opened"}
+	require.NoError(t, notifier.Notify(context.Background(), event))
+	require.Equal(t, event, <-received)
+
+	notifier, err = NewWebhookNotifier(server.URL, nil)
+	require.NoError(t, err)
+	require.EqualError(t, notifier.Notify(context.Background(), event), "webhook responded 401 Unauthorized")
+
+	_, err = NewWebhookNotifier("ftp://alerts", nil)
+	require.Error(t, err)
+
+	// the URL embedding a token is left out of the delivery errors
+	secretURL := server.URL + "/hooks/s3cr3t-token"
+	server.Close()
+
+	notifier, err = NewWebhookNotifier(secretURL, nil)
+	require.NoError(t, err)
+
+	err = notifier.Notify(context.Background(), event)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "s3cr3t-token")
+}
+
+func TestNotifications(t *testing.T) {
+	notifier := newMockNotifier()
+	notifier.err = errors.New("connection refused")
+
+	n := NewNotifications(notifier, NotifyConfig{Events: []string{"Dead_Letter"}})
+	require.False(t, n.Enabled(NotifyLowBalance))
+
+	// the failed delivery is dropped
+	n.Publish(NotificationEvent{Type: NotifyDeadLetter, RequestID: "req-1"})
+	event := notifier.next(t)
+	require.Equal(t, "req-1", event.RequestID)
+	require.False(t, event.Time.IsZero())
+
+	n.Publish(NotificationEvent{Type: NotifyLowBalance})
+
+	// the publication never blocks on a stuck notifier
+	notifier.block = make(chan struct{})
+	n = NewNotifications(notifier, NotifyConfig{MaxPending: 1})
+
+	n.Publish(NotificationEvent{Type: NotifyCircuitOpen, DestID: "fisco-1-1"})
+	n.Publish(NotificationEvent{Type: NotifyCircuitOpen, DestID: "fisco-1-2"})
+	close(notifier.block)
+
+	require.Equal(t, "fisco-1-1", notifier.next(t).DestID)
+
+	select {
+	case event := <-notifier.events:
+		t.Fatalf("unexpected notification %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.False(t, NewNotifications(NopNotifier{}, NotifyConfig{}).Enabled(NotifyDeadLetter))
+	require.Error(t, NotifyConfig{Events: []string{"chain_down"}}.Validate())
+}
+
+func TestNotifyCircuitOpen(t *testing.T) {
+	registry := NewBreakerRegistry(BreakerConfig{FailureThreshold: 1})
+
+	opened := make(chan string, 1)
+	registry.OnOpen(func(name string, failures int) {
+		opened <- name
+	})
+
+	breaker := registry.Get("FISCO-1-1")
+	require.NoError(t, breaker.Allow())
+	breaker.Record(errors.New("connection refused"))
+
+	require.Equal(t, "fisco-1-1", <-opened)
+}
+
+func TestNotifyListenerDown(t *testing.T) {
+	notifier := newMockNotifier()
+	now := time.Now()
+
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.Health = HealthConfig{StalenessThreshold: time.Minute}
+	r.Notifications = NewNotifications(notifier, NotifyConfig{})
+
+	chain := &mockAppChain{destID: "fisco-1-1", liveness: ChainLiveness{Connected: false, LastHeight: 10, LastSeenAt: now}}
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = true
+
+	watch := newListenerWatch()
+
+	// the disconnected listener is notified once down past the threshold
+	r.checkListeners(watch, now)
+	r.checkListeners(watch, now.Add(30*time.Second))
+	require.Len(t, notifier.events, 0)
+
+	r.checkListeners(watch, now.Add(61*time.Second))
+	event := notifier.next(t)
+	require.Equal(t, NotifyListenerDown, event.Type)
+	require.Equal(t, "fisco-1-1", event.DestID)
+
+	r.checkListeners(watch, now.Add(90*time.Second))
+	require.Len(t, notifier.events, 0)
+
+	// the stale listener is notified again after recovering
+	chain.liveness = ChainLiveness{Connected: true, LastHeight: 11, LastSeenAt: now.Add(90 * time.Second)}
+	r.checkListeners(watch, now.Add(100*time.Second))
+
+	r.checkListeners(watch, now.Add(200*time.Second))
+	require.Equal(t, "fisco-1-1", notifier.next(t).DestID)
+}
+
+func TestNotifyDeadLetter(t *testing.T) {
+	notifier := newMockNotifier()
+
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.Notifications = NewNotifications(notifier, NotifyConfig{Events: []string{NotifyDeadLetter}})
+
+	request := InterchainRequest{ID: "req-1", DestChainType: "eth", DestChainID: "1"}
+	r.deadLetter("1", StageResponse, request, nil, errors.New("execution reverted"))
+
+	event := notifier.next(t)
+	require.Equal(t, NotifyDeadLetter, event.Type)
+	require.Equal(t, "eth-1", event.DestID)
+	require.Equal(t, "req-1", event.RequestID)
+	require.Equal(t, StageResponse, event.Details["stage"])
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ordering keys of the requests
+const (
+	OrderKeySender   = "sender"   // requester on the source chain
+	OrderKeyEndpoint = "endpoint" // contract called on the destination chain
+	OrderKeyChain    = "chain"    // source chain, serializing all its requests
+)
+
+// OrderingConfig defines the ordered relaying params
+// The responses of the requests sharing an ordering key on a source chain are relayed in
+// the order the requests were received, each once the prior one is confirmed or dead-lettered.
+// The requests of different keys are still relayed concurrently
+type OrderingConfig struct {
+	Enabled  bool
+	Key      string   // ordering key of the requests, sender by default
+	Services []string // services relayed in order, all if empty
+}
+
+// normalize fills the unset params with the default values
+func (c OrderingConfig) normalize() OrderingConfig {
+	if len(c.Key) == 0 {
+		c.Key = OrderKeySender
+	}
+
+	c.Key = strings.ToLower(c.Key)
+
+	return c
+}
+
+// Validate validates the ordering key
+func (c OrderingConfig) Validate() error {
+	switch c.normalize().Key {
+	case OrderKeySender, OrderKeyEndpoint, OrderKeyChain:
+		return nil
+	default:
+		return fmt.Errorf("invalid ordering key %s: expected %s, %s or %s", c.Key, OrderKeySender, OrderKeyEndpoint, OrderKeyChain)
+	}
+}
+
+// shardKey returns the shard of the request received from the given source chain,
+// false if the request is not relayed in order
+func (c OrderingConfig) shardKey(source string, request InterchainRequest) (string, bool) {
+	if !c.Enabled {
+		return "", false
+	}
+
+	config := c.normalize()
+
+	if len(config.Services) != 0 && !containsString(config.Services, request.ServiceName) {
+		return "", false
+	}
+
+	switch config.Key {
+	case OrderKeyEndpoint:
+		return source + "/" + strings.ToLower(request.EndpointAddress), true
+	case OrderKeyChain:
+		return source, true
+	default:
+		return source + "/" + strings.ToLower(request.Sender), true
+	}
+}
+
+// Sequencer serializes the relays of the same shard in the order their tickets are taken
+// It is safe for concurrent use
+type Sequencer struct {
+	mtx    sync.Mutex
+	shards map[string][]*Ticket // tickets not yet released by shard, the head holding the turn
+}
+
+// Ticket is the place of a relay in its shard
+type Ticket struct {
+	shard string
+	turn  chan struct{} // closed once the ticket is at the head of its shard
+}
+
+// NewSequencer constructs a new Sequencer instance
+func NewSequencer() *Sequencer {
+	return &Sequencer{
+		shards: make(map[string][]*Ticket),
+	}
+}
+
+// Take appends a ticket to the given shard
+func (s *Sequencer) Take(shard string) *Ticket {
+	t := &Ticket{
+		shard: shard,
+		turn:  make(chan struct{}),
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.shards[shard] = append(s.shards[shard], t)
+	if len(s.shards[shard]) == 1 {
+		close(t.turn)
+	}
+
+	return t
+}
+
+// Release removes the ticket from its shard, giving the turn to the next one if it held it
+// Releasing a ticket before its turn, e.g. for a request failed on the Hub, keeps the order
+// of the others. Releasing the ticket again is a no-op
+func (s *Sequencer) Release(t *Ticket) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	tickets := s.shards[t.shard]
+
+	for i, ticket := range tickets {
+		if ticket != t {
+			continue
+		}
+
+		tickets = append(tickets[:i:i], tickets[i+1:]...)
+		if len(tickets) == 0 {
+			delete(s.shards, t.shard)
+			return
+		}
+
+		s.shards[t.shard] = tickets
+
+		if i == 0 {
+			close(tickets[0].turn)
+		}
+
+		return
+	}
+}
+
+// Depth returns the number of the tickets not yet released in the given shard
+func (s *Sequencer) Depth(shard string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.shards[shard])
+}
+
+// Wait blocks until the ticket holds the turn of its shard or the context is done
+func (t *Ticket) Wait(ctx context.Context) error {
+	select {
+	case <-t.turn:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// takeTicket takes the ticket of the request in its shard, nil if it is not relayed in order
+func (r *Relayer) takeTicket(chainID string, request InterchainRequest) *Ticket {
+	shard, ok := r.Ordering.shardKey(r.sourceDestID(chainID).String(), request)
+	if !ok {
+		return nil
+	}
+
+	return r.sequencer.Take(shard)
+}
+
+// releaseTicket gives the turn of the shard of the ticket to the next request, if any
+func (r *Relayer) releaseTicket(t *Ticket) {
+	if t != nil {
+		r.sequencer.Release(t)
+	}
+}
+
+// containsString returns true if the given string is in the list
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
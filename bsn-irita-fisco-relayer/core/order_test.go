@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockOrderedChain is an AppChainI recording the order the responses are submitted in
+type mockOrderedChain struct {
+	mockAppChain
+	mtx       sync.Mutex
+	submitted []string
+}
+
+func (m *mockOrderedChain) SendResponse(ctx context.Context, requestID string, response ResponseI) (string, error) {
+	// the later responses would overtake the slow confirmations if not serialized
+	time.Sleep(10 * time.Millisecond)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.submitted = append(m.submitted, requestID)
+
+	return "0x" + requestID, nil
+}
+
+func TestSequencer(t *testing.T) {
+	s := NewSequencer()
+
+	first := s.Take("a")
+	second := s.Take("a")
+	third := s.Take("a")
+	other := s.Take("b")
+
+	require.NoError(t, first.Wait(context.Background()))
+	require.NoError(t, other.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, second.Wait(ctx))
+
+	// releasing a ticket before its turn keeps the order of the others
+	s.Release(second)
+	s.Release(first)
+	require.NoError(t, third.Wait(context.Background()))
+	require.Equal(t, 1, s.Depth("a"))
+
+	s.Release(third)
+	s.Release(third)
+	require.Equal(t, 0, s.Depth("a"))
+}
+
+func TestOrderingShardKey(t *testing.T) {
+	request := InterchainRequest{Sender: "0xAB", EndpointAddress: "0xCD", ServiceName: "oracle"}
+
+	shard, ok := OrderingConfig{Enabled: true}.shardKey("fisco-1-1", request)
+	require.True(t, ok)
+	require.Equal(t, "fisco-1-1/0xab", shard)
+
+	shard, _ = OrderingConfig{Enabled: true, Key: OrderKeyEndpoint}.shardKey("fisco-1-1", request)
+	require.Equal(t, "fisco-1-1/0xcd", shard)
+
+	shard, _ = OrderingConfig{Enabled: true, Key: OrderKeyChain}.shardKey("fisco-1-1", request)
+	require.Equal(t, "fisco-1-1", shard)
+
+	_, ok = OrderingConfig{Enabled: true, Services: []string{"price"}}.shardKey("fisco-1-1", request)
+	require.False(t, ok)
+
+	_, ok = OrderingConfig{}.shardKey("fisco-1-1", request)
+	require.False(t, ok)
+
+	require.Error(t, OrderingConfig{Key: "contract"}.Validate())
+	require.NoError(t, OrderingConfig{Key: "Endpoint"}.Validate())
+}
+
+func TestOrderedRelay(t *testing.T) {
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.Encoders.Register("oracle", JSONEncoder{})
+	r.Ordering = OrderingConfig{Enabled: true, Key: OrderKeySender}
+
+	chain := &mockOrderedChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}}
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = true
+
+	// the tickets are taken as the requests are emitted, the way the handler does
+	ids := []string{"req-1", "req-2", "req-3"}
+	callbacks := make([]ResponseCallback, len(ids))
+
+	for i, id := range ids {
+		request := InterchainRequest{ID: id, Sender: "0xab", ServiceName: "oracle"}
+
+		r.track()
+		callbacks[i] = r.responseCallback("1", request, request, time.Now(), r.takeTicket("1", request))
+	}
+
+	// the Hub responds in the reverse order
+	var wg sync.WaitGroup
+	for i := len(ids) - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			callbacks[i]("ic-"+ids[i], ResponseAdaptor{StatusCode: 200, ServiceName: "oracle", Output: "{}"}, nil)
+		}(i)
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	wg.Wait()
+
+	require.Equal(t, ids, chain.submitted)
+	require.Equal(t, 0, r.sequencer.Depth("fisco-1-1/0xab"))
+
+	// the requests of the other services are not sequenced
+	r.Ordering.Services = []string{"price"}
+	require.Nil(t, r.takeTicket("1", InterchainRequest{ID: "req-4", Sender: "0xab", ServiceName: "oracle"}))
+}
+
+func TestOrderedRelayNoResponse(t *testing.T) {
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.Encoders.Register("oracle", JSONEncoder{})
+	r.Ordering = OrderingConfig{Enabled: true, Key: OrderKeySender}
+
+	chain := &mockOrderedChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}}
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = true
+
+	ids := []string{"req-1", "req-2", "req-3"}
+	callbacks := make([]ResponseCallback, len(ids))
+
+	for i, id := range ids {
+		request := InterchainRequest{ID: id, Sender: "0xab", ServiceName: "oracle"}
+
+		r.track()
+		callbacks[i] = r.responseCallback("1", request, request, time.Now(), r.takeTicket("1", request))
+	}
+
+	// the request behind the turn ended on the Hub does not wait for it
+	callbacks[1]("ic-req-2", nil, fmt.Errorf("%w: request req-2 expired at height 10", ErrNoResponse))
+	require.Equal(t, 2, r.sequencer.Depth("fisco-1-1/0xab"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		callbacks[2]("ic-req-3", ResponseAdaptor{StatusCode: 200, ServiceName: "oracle", Output: "{}"}, nil)
+	}()
+
+	// the turn held by the request ended on the Hub is passed on
+	time.Sleep(20 * time.Millisecond)
+	require.Empty(t, chain.submitted)
+
+	callbacks[0]("ic-req-1", nil, fmt.Errorf("%w: request context completed", ErrNoResponse))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the turn is not passed on")
+	}
+
+	require.Equal(t, []string{"req-3"}, chain.submitted)
+	require.Equal(t, 0, r.sequencer.Depth("fisco-1-1/0xab"))
+}
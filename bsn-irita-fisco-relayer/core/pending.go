@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// KeyPrefixPending is the store key prefix of the requests awaiting responses
+	KeyPrefixPending = "pending:"
+)
+
+// PendingRequest is an interchain request initiated on the Hub and awaiting the response
+type PendingRequest struct {
+	ChainID     string             `json:"chain_id"`         // source app chain ID
+	Request     InterchainRequest  `json:"request"`          // interchain request, as routed
+	Source      *InterchainRequest `json:"source,omitempty"` // interchain request as received from the source chain
+	ReqCtxID    string             `json:"req_ctx_id"`       // request context ID on the Hub
+	ICRequestID string             `json:"ic_request_id"`    // service request ID on the Hub
+	ReceivedAt  time.Time          `json:"received_at"`      // time the request was received, ordering the resumption
+}
+
+// PendingKey returns the store key of the pending request
+func PendingKey(requestID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", KeyPrefixPending, requestID))
+}
+
+// savePending persists the pending request so that it can be resumed after restart
+func (r *Relayer) savePending(p PendingRequest) error {
+	if r.Store == nil {
+		return nil
+	}
+
+	bz, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return r.Store.Set(PendingKey(p.Request.ID), bz)
+}
+
+// deletePending removes the pending request once its response is handled
+func (r *Relayer) deletePending(requestID string) error {
+	if r.Store == nil {
+		return nil
+	}
+
+	return r.Store.Delete(PendingKey(requestID))
+}
+
+// loadPending retrieves all pending requests
+func (r *Relayer) loadPending() ([]PendingRequest, error) {
+	pendings := make([]PendingRequest, 0)
+
+	if r.Store == nil {
+		return pendings, nil
+	}
+
+	err := r.Store.Iterate([]byte(KeyPrefixPending), func(key, value []byte) error {
+		var p PendingRequest
+		if err := json.Unmarshal(value, &p); err != nil {
+			return fmt.Errorf("invalid pending request %s: %s", key, err)
+		}
+
+		pendings = append(pendings, p)
+
+		return nil
+	})
+
+	return pendings, err
+}
+
+// ResumePending resumes handling the responses of the requests of the given app chain left
+// pending by the last run. It should be called after the app chain is built
+func (r *Relayer) ResumePending(chainID string) error {
+	all, err := r.loadPending()
+	if err != nil {
+		return err
+	}
+
+	pendings := make([]PendingRequest, 0, len(all))
+	for _, p := range all {
+		if p.ChainID == chainID {
+			pendings = append(pendings, p)
+		}
+	}
+
+	// the ordered requests take their tickets in the order they were received
+	sort.SliceStable(pendings, func(i, j int) bool {
+		return pendings[i].ReceivedAt.Before(pendings[j].ReceivedAt)
+	})
+
+	if r.DryRun {
+		r.Logger.Infof("dry run: would resume %d pending request(s) of chain %s", len(pendings), chainID)
+		return nil
+	}
+
+	for _, p := range pendings {
+		if _, ok := r.appChain(p.ChainID); !ok {
+			r.Logger.Warnf("chain ID %s of the pending request %s does not exist", p.ChainID, p.Request.ID)
+			continue
+		}
+
+		if err := r.resume(p); err != nil {
+			r.Logger.Errorf("failed to resume the pending request %s: %s", p.Request.ID, err)
+			continue
+		}
+
+		r.Logger.Infof("pending request %s resumed", p.Request.ID)
+	}
+
+	return nil
+}
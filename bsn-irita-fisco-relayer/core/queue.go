@@ -0,0 +1,204 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"relayer/logging"
+	"relayer/metrics"
+)
+
+// DefaultQueueCapacity is the default number of requests in flight beyond which the listeners pause
+const DefaultQueueCapacity = 1000
+
+// ErrQueueFull is returned when an event is rejected by the full event queue
+var ErrQueueFull = errors.New("event queue full")
+
+// QueueConfig defines the event queue params
+type QueueConfig struct {
+	Capacity int // requests in flight pausing the listeners once reached
+	LowWater int // requests in flight below which the paused listeners resume, half of the capacity if not positive
+}
+
+// normalize fills the unset params with the default values
+func (c QueueConfig) normalize() QueueConfig {
+	if c.Capacity <= 0 {
+		c.Capacity = DefaultQueueCapacity
+	}
+
+	if c.LowWater <= 0 || c.LowWater >= c.Capacity {
+		c.LowWater = c.Capacity / 2
+	}
+
+	return c
+}
+
+// EventQueue bounds the requests accepted from the source chains and not yet responded
+// Once the capacity is reached the queue pauses, rejecting the events so that the
+// listeners stop ingesting, and resumes when the depth drains below the low-water mark.
+// It is safe for concurrent use
+type EventQueue struct {
+	config QueueConfig
+
+	mtx     sync.Mutex
+	depth   int           // requests in flight
+	paused  bool          // set from reaching the capacity until drained below the low-water mark
+	resumed chan struct{} // closed when the queue resumes
+}
+
+// NewEventQueue constructs a new EventQueue instance
+func NewEventQueue(config QueueConfig) *EventQueue {
+	config = config.normalize()
+
+	metrics.EventQueueCapacity.Set(float64(config.Capacity))
+	metrics.EventQueueDepth.Set(0)
+
+	return &EventQueue{
+		config:  config,
+		resumed: make(chan struct{}),
+	}
+}
+
+// Config returns the normalized params
+func (q *EventQueue) Config() QueueConfig {
+	return q.config
+}
+
+// Depth returns the number of requests in flight
+func (q *EventQueue) Depth() int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return q.depth
+}
+
+// Paused returns true if the events are rejected
+func (q *EventQueue) Paused() bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return q.paused
+}
+
+// Add counts a request accepted, pausing the queue once the capacity is reached
+// The requests resumed from the store are counted even beyond the capacity
+func (q *EventQueue) Add() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.depth++
+	metrics.EventQueueDepth.Set(float64(q.depth))
+
+	if !q.paused && q.depth >= q.config.Capacity {
+		q.paused = true
+		logging.Logger.Warnf("event queue full with %d requests in flight, pausing the listeners", q.depth)
+	}
+}
+
+// Done counts a request responded, resuming the paused queue below the low-water mark
+func (q *EventQueue) Done() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.depth > 0 {
+		q.depth--
+	}
+
+	metrics.EventQueueDepth.Set(float64(q.depth))
+
+	if q.paused && q.depth < q.config.LowWater {
+		q.paused = false
+
+		close(q.resumed)
+		q.resumed = make(chan struct{})
+
+		logging.Logger.Infof("event queue drained to %d requests in flight, resuming the listeners", q.depth)
+	}
+}
+
+// Wait blocks until the queue is not paused or the context is done
+func (q *EventQueue) Wait(ctx context.Context) error {
+	q.mtx.Lock()
+	if !q.paused {
+		q.mtx.Unlock()
+		return nil
+	}
+
+	resumed := q.resumed
+	q.mtx.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track counts a request in flight until its response is handled
+func (r *Relayer) track() {
+	r.inflight.Add(1)
+
+	if r.Queue != nil {
+		r.Queue.Add()
+	}
+}
+
+// untrack releases a request counted by track
+func (r *Relayer) untrack() {
+	if r.Queue != nil {
+		r.Queue.Done()
+	}
+
+	r.inflight.Done()
+}
+
+// QueueListener is an application chain pausing its listener while the event queue is full,
+// instead of reading the events only to get them rejected
+type QueueListener interface {
+	// SetEventQueue sets the queue waited for before reading the events
+	SetEventQueue(queue *EventQueue)
+}
+
+// RejectedError marks the error of an event rejected for good, e.g. dead-lettered, after
+// which the listener moves on to the next events
+type RejectedError struct {
+	Err error
+}
+
+// Rejected wraps the error so that the listener advances past the event
+func Rejected(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &RejectedError{Err: err}
+}
+
+// Error implements error
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// IsRejected returns true if the event was rejected for good, the listener advancing
+// its checkpoint past it. The listener reads again the events failing for any other reason
+func IsRejected(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected)
+}
+
+// IsBackpressure returns true if the event was rejected to be read again later,
+// the listener pausing without advancing its checkpoint. The events rejected by the
+// shutdown are read again on the next start, as are the ones whose decision could not
+// be audited
+func IsBackpressure(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrQueueFull) || errors.Is(err, ErrRelayerClosing) ||
+		errors.Is(err, ErrAuditFailed)
+}
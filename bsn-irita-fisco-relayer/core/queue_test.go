@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/logging"
+)
+
+func TestEventQueue(t *testing.T) {
+	queue := NewEventQueue(QueueConfig{Capacity: 3, LowWater: 2})
+
+	queue.Add()
+	queue.Add()
+	require.False(t, queue.Paused())
+
+	queue.Add()
+	require.True(t, queue.Paused())
+	require.Equal(t, 3, queue.Depth())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, queue.Wait(ctx))
+
+	// paused until drained below the low-water mark, not just below the capacity
+	queue.Done()
+	require.True(t, queue.Paused())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Wait(context.Background())
+	}()
+
+	queue.Done()
+	require.NoError(t, <-done)
+	require.False(t, queue.Paused())
+	require.Equal(t, 1, queue.Depth())
+
+	require.NoError(t, queue.Wait(context.Background()))
+}
+
+func TestEventQueueDefaults(t *testing.T) {
+	config := NewEventQueue(QueueConfig{}).Config()
+	require.Equal(t, DefaultQueueCapacity, config.Capacity)
+	require.Equal(t, DefaultQueueCapacity/2, config.LowWater)
+
+	config = NewEventQueue(QueueConfig{Capacity: 10, LowWater: 10}).Config()
+	require.Equal(t, 5, config.LowWater)
+}
+
+func TestHandlerRejectsOnFullQueue(t *testing.T) {
+	r := NewRelayer("fisco", nil, nil, nil, nil)
+	r.Queue = NewEventQueue(QueueConfig{Capacity: 1})
+	r.Queue.Add()
+
+	err := r.HandleInterchainRequest("chain-1", InterchainRequest{ID: "req-1"}, "0x01")
+	require.Equal(t, ErrQueueFull, err)
+	require.True(t, IsBackpressure(err))
+
+	// rejected before being marked seen, so that it is read again on resume
+	require.False(t, r.Dedup.Seen("req-1"))
+}
+
+func TestQueueDrainsOnNoResponse(t *testing.T) {
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.Queue = NewEventQueue(QueueConfig{Capacity: 2, LowWater: 1})
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	callbacks := make([]ResponseCallback, 2)
+	for i := range callbacks {
+		request := InterchainRequest{ID: fmt.Sprintf("req-%d", i)}
+
+		r.track()
+		callbacks[i] = r.responseCallback("1", request, request, time.Now(), nil)
+	}
+
+	require.True(t, r.Queue.Paused())
+
+	// the requests never responded on the Hub release their place in the queue
+	for i, cb := range callbacks {
+		cb(fmt.Sprintf("ic-req-%d", i), nil, fmt.Errorf("%w: request context completed", ErrNoResponse))
+	}
+
+	require.Equal(t, 0, r.Queue.Depth())
+	require.False(t, r.Queue.Paused())
+}
+
+func TestHandlerRejectsOnShutdown(t *testing.T) {
+	r := NewRelayer("fisco", &mockHubChain{}, nil, nil, logging.Logger)
+	require.NoError(t, r.Shutdown(context.Background()))
+
+	// the block scanned while the monitors stop is not checkpointed
+	err := r.HandleInterchainRequest("chain-1", InterchainRequest{ID: "req-1"}, "0x01")
+	require.Equal(t, ErrRelayerClosing, err)
+	require.True(t, IsBackpressure(err))
+	require.False(t, r.Dedup.Seen("req-1"))
+}
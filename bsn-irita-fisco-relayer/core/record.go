@@ -0,0 +1,163 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"relayer/common"
+	"relayer/store"
+)
+
+// relay statuses of a request
+const (
+	StatusUnknown      = "unknown"       // never seen by the relayer
+	StatusSeen         = "seen"          // received from the source app chain, not yet on the Hub
+	StatusPending      = "pending"       // initiated on the Hub and awaiting the response
+	StatusRelayed      = "relayed"       // responded to the source app chain
+	StatusDeadLettered = "dead_lettered" // failed permanently, see the dead letter
+)
+
+// RelayRecord tracks the relay progress of an interchain request
+type RelayRecord struct {
+	ChainID        string    `json:"chain_id"`                   // source app chain ID
+	TxHash         string    `json:"tx_hash"`                    // source tx hash
+	ReqCtxID       string    `json:"req_ctx_id,omitempty"`       // request context ID on the Hub
+	ICRequestID    string    `json:"ic_request_id,omitempty"`    // service request ID on the Hub
+	ResponseTxHash string    `json:"response_tx_hash,omitempty"` // response tx hash on the source app chain
+	Relayed        bool      `json:"relayed"`                    // indicates if the response is sent
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// RequestStatus is the relay status of an interchain request
+type RequestStatus struct {
+	RequestID  string       `json:"request_id"`
+	Status     string       `json:"status"`
+	Record     *RelayRecord `json:"record,omitempty"`
+	DeadLetter *DeadLetter  `json:"dead_letter,omitempty"`
+}
+
+// saveRecord persists the relay record of the request of the given source chain
+func (r *Relayer) saveRecord(destID common.DestID, requestID string, record RelayRecord) error {
+	if r.State == nil {
+		return nil
+	}
+
+	record.UpdatedAt = time.Now()
+
+	bz, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return r.State.SaveStatus(destID.Normalize(), requestID, bz)
+}
+
+// loadRecord retrieves the relay record of the request of the given source chain, nil if not found
+func (r *Relayer) loadRecord(destID common.DestID, requestID string) (*RelayRecord, error) {
+	if r.State == nil {
+		return nil, nil
+	}
+
+	bz, err := r.State.LoadStatus(destID.Normalize(), requestID)
+	if err == store.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeRecord(requestID, bz)
+}
+
+// lookupRecord retrieves the relay record of the given request, nil if not found
+// The dest ID of the source chain is required if the request ID is relayed from several chains
+func (r *Relayer) lookupRecord(destID common.DestID, requestID string) (*RelayRecord, error) {
+	if r.State == nil {
+		return nil, nil
+	}
+
+	if len(destID) != 0 {
+		return r.loadRecord(destID, requestID)
+	}
+
+	statuses, err := r.State.LookupStatus(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(statuses) {
+	case 0:
+		return nil, nil
+
+	case 1:
+		for _, bz := range statuses {
+			return decodeRecord(requestID, bz)
+		}
+	}
+
+	destIDs := make([]string, 0, len(statuses))
+	for id := range statuses {
+		destIDs = append(destIDs, id.String())
+	}
+	sort.Strings(destIDs)
+
+	return nil, fmt.Errorf("request %s relayed from several chains: %s, expected the dest ID", requestID, strings.Join(destIDs, ", "))
+}
+
+// decodeRecord decodes the relay record of the given request
+func decodeRecord(requestID string, bz []byte) (*RelayRecord, error) {
+	var record RelayRecord
+	if err := json.Unmarshal(bz, &record); err != nil {
+		return nil, fmt.Errorf("invalid relay record of %s: %s", requestID, err)
+	}
+
+	return &record, nil
+}
+
+// GetRequestStatus reports the relay status of the given request from the relay records,
+// the pending requests, the dedup cache and the dead letters
+// The dest ID of the source chain may be empty, unless the request ID is relayed from several chains
+func (r *Relayer) GetRequestStatus(requestID string, destID common.DestID) (RequestStatus, error) {
+	status := RequestStatus{
+		RequestID: requestID,
+		Status:    StatusUnknown,
+	}
+
+	record, err := r.lookupRecord(destID, requestID)
+	if err != nil {
+		return status, err
+	}
+
+	status.Record = record
+
+	if r.DeadLetters != nil {
+		letters, err := r.DeadLetters.List()
+		if err != nil {
+			return status, err
+		}
+
+		for i := range letters {
+			if letters[i].Request.ID == requestID {
+				status.Status = StatusDeadLettered
+				status.DeadLetter = &letters[i]
+
+				return status, nil
+			}
+		}
+	}
+
+	switch {
+	case record != nil && record.Relayed:
+		status.Status = StatusRelayed
+
+	case record != nil && len(record.ReqCtxID) != 0:
+		status.Status = StatusPending
+
+	case record != nil || r.Dedup.Contains(requestID):
+		status.Status = StatusSeen
+	}
+
+	return status, nil
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/store"
+)
+
+func TestGetRequestStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := store.NewStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	checkpoint, err := store.NewFileCheckpoint(filepath.Join(dir, "checkpoints"))
+	require.NoError(t, err)
+
+	r := NewRelayer("fisco", nil, nil, db, nil)
+	r.State = store.NewFileStateStore(checkpoint, db)
+
+	r.DeadLetters, err = NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+	require.NoError(t, err)
+
+	status, err := r.GetRequestStatus("req-1", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusUnknown, status.Status)
+
+	// filtered or in-flight requests are only known to the dedup cache
+	r.Dedup.Seen("req-1")
+
+	status, err = r.GetRequestStatus("req-1", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusSeen, status.Status)
+	require.Nil(t, status.Record)
+
+	record := RelayRecord{ChainID: "1", TxHash: "0x01", ReqCtxID: "ctx-1", ICRequestID: "ic-1"}
+	require.NoError(t, r.saveRecord("1", "req-1", record))
+
+	status, err = r.GetRequestStatus("req-1", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusPending, status.Status)
+	require.Equal(t, "ctx-1", status.Record.ReqCtxID)
+
+	r.markRelayed("1", InterchainRequest{ID: "req-1"}, "ic-1", "0x02")
+
+	status, err = r.GetRequestStatus("req-1", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusRelayed, status.Status)
+	require.Equal(t, "0x01", status.Record.TxHash)
+	require.Equal(t, "0x02", status.Record.ResponseTxHash)
+
+	require.NoError(t, r.DeadLetters.Add(DeadLetter{ChainID: "1", Stage: StageRequest, Request: InterchainRequest{ID: "req-2"}, Error: "timeout"}))
+
+	status, err = r.GetRequestStatus("req-2", "")
+	require.NoError(t, err)
+	require.Equal(t, StatusDeadLettered, status.Status)
+	require.Equal(t, "timeout", status.DeadLetter.Error)
+
+	// the same request ID relayed from another chain requires the dest ID
+	require.NoError(t, r.saveRecord("fisco-1-6", "req-1", RelayRecord{ChainID: "6", TxHash: "0x06"}))
+
+	_, err = r.GetRequestStatus("req-1", "")
+	require.EqualError(t, err, "request req-1 relayed from several chains: 1, fisco-1-6, expected the dest ID")
+
+	status, err = r.GetRequestStatus("req-1", "FISCO-1-6")
+	require.NoError(t, err)
+	require.Equal(t, "0x06", status.Record.TxHash)
+
+	status, err = r.GetRequestStatus("req-1", "1")
+	require.NoError(t, err)
+	require.Equal(t, "0x01", status.Record.TxHash)
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"relayer/common"
+)
+
+// relayResult is the result of relaying a response
+type relayResult struct {
+	txHash string
+	err    error
+}
+
+// RelayOnce relays the given request through the Hub and sends its response to the
+// running app chain of the given dest ID, returning the hash of the response tx
+// It goes through the same invocation, fee estimation, signing and response encoding
+// as the request handler, but bypasses the filters, deduplication and persistence,
+// so that a single relay can be driven by the integration tests and embedding programs
+func (r *Relayer) RelayOnce(ctx context.Context, request InterchainRequest, dest common.DestID) (string, error) {
+	if r.isClosing() {
+		return "", ErrRelayerClosing
+	}
+
+	chainID, ok := r.chainIDByDestID(dest)
+	if !ok {
+		return "", fmt.Errorf("chain %s not running", dest)
+	}
+
+	return r.relay(ctx, chainID, request)
+}
+
+// relay relays the given request through the Hub and sends its response to the given app chain
+func (r *Relayer) relay(ctx context.Context, chainID string, request InterchainRequest) (string, error) {
+	request, err := r.route(chainID, request)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.validateInput(request); err != nil {
+		return "", err
+	}
+
+	done := make(chan relayResult, 1)
+
+	err = r.HubChain.SendInterchainRequest(ctx, request, nil, func(icRequestID string, response ResponseI, err error) {
+		if err != nil {
+			done <- relayResult{err: fmt.Errorf("failed to get the response from %s: %w", r.HubChain.GetChainID(), err)}
+			return
+		}
+
+		txHash, err := r.sendResponse(ctx, chainID, request.ID, withRoutedService(request, response))
+		if err != nil {
+			err = fmt.Errorf("failed to send the response to chain %s: %s", chainID, err)
+		}
+
+		done <- relayResult{txHash: txHash, err: err}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send the interchain request to %s: %s", r.HubChain.GetChainID(), err)
+	}
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return "", res.err
+		}
+
+		return res.txHash, nil
+
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// chainIDByDestID returns the chain ID of the running app chain of the given dest ID
+func (r *Relayer) chainIDByDestID(destID common.DestID) (string, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for chainID, chain := range r.AppChains {
+		if r.AppChainStates[chainID] && strings.EqualFold(chain.GetDestID().String(), destID.String()) {
+			return chainID, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockHubChain is a HubChainI responding to the requests asynchronously
+type mockHubChain struct {
+	response ResponseI
+	sendErr  error
+	silent   bool // never responds if set
+}
+
+func (m *mockHubChain) GetChainID() string                        { return "irita-hub" }
+func (m *mockHubChain) CheckConnection(ctx context.Context) error { return nil }
+
+func (m *mockHubChain) SendInterchainRequest(ctx context.Context, request InterchainRequest, sent RequestSentCallback, cb ResponseCallback) error {
+	if m.sendErr != nil {
+		return m.sendErr
+	}
+
+	if !m.silent {
+		go cb("ic-"+request.ID, m.response, nil)
+	}
+
+	return nil
+}
+
+func (m *mockHubChain) ResumeInterchainRequest(reqCtxID string, icRequestID string, cb ResponseCallback) error {
+	return nil
+}
+
+// mockRespondingChain is an AppChainI returning the tx hash of the response
+type mockRespondingChain struct {
+	mockAppChain
+	outputs map[string]string
+}
+
+func (m *mockRespondingChain) SendResponse(ctx context.Context, requestID string, response ResponseI) (string, error) {
+	m.outputs[requestID] = response.GetOutput()
+	return "0xabc", nil
+}
+
+func TestRelayOnce(t *testing.T) {
+	hub := &mockHubChain{response: ResponseAdaptor{StatusCode: 200, ServiceName: "oracle", Output: `{"price":1}`}}
+	r := NewRelayer("fisco", hub, nil, nil, nil)
+	r.Encoders.Register("oracle", JSONEncoder{})
+
+	chain := &mockRespondingChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}, outputs: map[string]string{}}
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	txHash, err := r.RelayOnce(ctx, InterchainRequest{ID: "req-1"}, "FISCO-1-1")
+	require.NoError(t, err)
+	require.Equal(t, "0xabc", txHash)
+	require.Equal(t, `{"price":1}`, chain.outputs["req-1"])
+
+	_, err = r.RelayOnce(ctx, InterchainRequest{ID: "req-2"}, "fisco-2-1")
+	require.Error(t, err)
+
+	hub.sendErr = errors.New("insufficient funds")
+	_, err = r.RelayOnce(ctx, InterchainRequest{ID: "req-3"}, "fisco-1-1")
+	require.Error(t, err)
+
+	// the relay gives up once the context is done
+	hub.sendErr = nil
+	hub.silent = true
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = r.RelayOnce(ctx, InterchainRequest{ID: "req-4"}, "fisco-1-1")
+	require.Equal(t, context.DeadlineExceeded, err)
+}
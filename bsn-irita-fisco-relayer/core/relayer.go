@@ -1,42 +1,102 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+
+	"relayer/common"
+	"relayer/store"
 )
 
 // Relayer represents a relayer transmitting msgs
 // from app chains with the same architecture
 // to the Hub chain
 type Relayer struct {
-	AppChainType    string
-	HubChain        HubChainI
-	AppChains       map[string]AppChainI
-	AppChainStates  map[string]bool
-	AppChainFactory AppChainFactoryI
-	Logger          *log.Logger
-	mtx             sync.Mutex
+	AppChainType     string
+	HubChain         HubChainI
+	AppChains        map[string]AppChainI
+	AppChainStates   map[string]bool
+	AppChainFactory  AppChainFactoryI
+	Store            *store.Store // store for the pending requests
+	Logger           *log.Logger
+	DryRun           bool        // logs the requests instead of relaying them if set
+	Dedup            *DedupCache // drops the requests seen recently
+	Health           HealthConfig
+	Encoders         *EncoderRegistry // response encoders by service name
+	Filters          *FilterRegistry  // source event filters by dest ID
+	DeadLetters      DeadLetterQueue  // permanently failed requests, disabled if nil
+	Breakers         *BreakerRegistry // circuit breakers of the app chains by dest ID
+	Routes           *RoutingTable    // routes of the requests to the destinations, unchanged if nil
+	Schemas          *SchemaRegistry  // input schemas by service name, no validation if nil
+	State            store.StateStore // persistent seen marks and relay records, the records kept in Store if nil
+	Audit            AuditTrail       // append-only record of the relay decisions, disabled if nil
+	Queue            *EventQueue      // bounds the requests in flight, unbounded if nil
+	Async            AsyncConfig      // asynchronous response confirmation, disabled by default
+	Ordering         OrderingConfig   // ordered relaying of the requests sharing a key, disabled by default
+	LatencyLogSample float64          // fraction of the requests whose stage durations are logged at debug level
+	Notifications    *Notifications   // critical failure notifications, none delivered by default
+	mtx              sync.Mutex
+
+	// chainsMtx guards AppChains against the reads outside mtx, e.g. on the relay path; it is
+	// never held while a chain starts or stops, which may wait for the listener in the handler
+	chainsMtx sync.RWMutex
+
+	sequencer *Sequencer // turns of the ordered requests by shard
+
+	ctx      context.Context    // relayer context, canceled when shut down
+	cancel   context.CancelFunc // cancels the relayer context
+	closing  int32              // set when the relayer starts shutting down
+	inflight sync.WaitGroup     // requests not yet responded to the app chains
+
+	heartbeat int64 // unix nanoseconds of the last heartbeat of the main loop, accessed atomically
 }
 
 // NewRelayer constructs a new Relayer instance
-func NewRelayer(appChainType string, hub HubChainI, appChainFactory AppChainFactoryI, logger *log.Logger) *Relayer {
+func NewRelayer(appChainType string, hub HubChainI, appChainFactory AppChainFactoryI, store *store.Store, logger *log.Logger) *Relayer {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Relayer{
 		AppChainType:    appChainType,
 		HubChain:        hub,
 		AppChainFactory: appChainFactory,
+		Store:           store,
 		Logger:          logger,
 		AppChains:       map[string]AppChainI{},
 		AppChainStates:  map[string]bool{},
+		Dedup:           NewDedupCache(DefaultDedupCapacity, DefaultDedupTTL),
+		Encoders:        NewEncoderRegistry(),
+		Filters:         NewFilterRegistry(),
+		Breakers:        NewBreakerRegistry(BreakerConfig{}),
+		Queue:           NewEventQueue(QueueConfig{}),
+		Notifications:   NewNotifications(NopNotifier{}, NotifyConfig{}),
+		sequencer:       NewSequencer(),
+		ctx:             ctx,
+		cancel:          cancel,
+		heartbeat:       time.Now().UnixNano(),
 	}
 }
 
+// RegisterEventFilter registers the filters consulted before relaying the events of the given app chain
+// An event is relayed only if all the filters of the chain accept it
+func (r *Relayer) RegisterEventFilter(destID common.DestID, filters ...EventFilter) {
+	r.Filters.Register(destID, filters...)
+}
+
 // AddChain adds an app chain with the specified app chain params
 func (r *Relayer) AddChain(appChainParams []byte) (chainID string, err error) {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
+	if r.isClosing() {
+		return "", ErrRelayerClosing
+	}
+
 	chainID, err = r.AppChainFactory.GetChainID(r.AppChainType, appChainParams)
 	if err != nil {
 		return "", err
@@ -52,16 +112,147 @@ func (r *Relayer) AddChain(appChainParams []byte) (chainID string, err error) {
 		return "", err
 	}
 
-	if err := chain.Start(r.HandleInterchainRequest); err != nil {
+	if err := r.startChain(chain); err != nil {
 		return "", err
 	}
 
-	r.AppChains[chainID] = chain
+	r.setAppChain(chainID, chain)
 	r.AppChainStates[chainID] = true
 
 	return chainID, nil
 }
 
+// startChain starts the monitor of the given app chain, pausing it on the event queue
+func (r *Relayer) startChain(chain AppChainI) error {
+	if listener, ok := chain.(QueueListener); ok && r.Queue != nil {
+		listener.SetEventQueue(r.Queue)
+	}
+
+	return chain.Start(r.HandleInterchainRequest)
+}
+
+// LoadChain builds the app chain of the given params without starting its monitor
+// The chain is added in the stopped state, e.g. to be replayed
+func (r *Relayer) LoadChain(chainParams []byte) (chainID string, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	chainID, err = r.AppChainFactory.GetChainID(r.AppChainType, chainParams)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := r.AppChains[chainID]; ok {
+		return "", fmt.Errorf("chain ID %s already exists", chainID)
+	}
+
+	chain, err := r.AppChainFactory.BuildAppChain(r.AppChainType, chainParams)
+	if err != nil {
+		return "", err
+	}
+
+	r.setAppChain(chainID, chain)
+	r.AppChainStates[chainID] = false
+
+	return chainID, nil
+}
+
+// RestoreChain builds and starts the app chain of the stored params, and resumes its pending requests
+// The invalid params and the store failures are unrecoverable, while the other failures, e.g.
+// the chain being unreachable, can be retried
+func (r *Relayer) RestoreChain(chainParams []byte) error {
+	chainID, err := r.AppChainFactory.GetChainID(r.AppChainType, chainParams)
+	if err != nil {
+		return Unrecoverable(fmt.Errorf("invalid chain params: %s", err))
+	}
+
+	if _, err := r.AddChain(chainParams); err != nil {
+		return err
+	}
+
+	if err := r.ResumePending(chainID); err != nil {
+		return Unrecoverable(fmt.Errorf("failed to resume the pending requests of chain %s: %s", chainID, err))
+	}
+
+	return nil
+}
+
+// appChainEntry is an app chain validated before any monitor starts
+type appChainEntry struct {
+	name    string // name of the chain in the errors
+	chainID string
+	destID  common.DestID
+}
+
+// ValidateAppChains fails on the colliding app chains, naming them, before any monitor starts
+// The restored chains are given by their params, the configured ones by their dest IDs keyed by
+// name, e.g. the config key of their overrides. Two chains collide if they resolve to the same
+// dest ID once normalized, or share a chain ID, as the app chains are keyed by chain ID: two
+// groups of a chain can not run side by side. The configured chain of the same dest ID as a
+// restored one is its override
+func (r *Relayer) ValidateAppChains(restored [][]byte, configured map[string]common.DestID) error {
+	entries := make([]appChainEntry, 0, len(restored)+len(configured))
+	overridable := make(map[common.DestID]bool, len(restored))
+
+	for _, params := range restored {
+		chainID, err := r.AppChainFactory.GetChainID(r.AppChainType, params)
+		if err != nil {
+			return fmt.Errorf("invalid params of a restored chain: %s", err)
+		}
+
+		destID, err := r.AppChainFactory.GetDestID(r.AppChainType, params)
+		if err != nil {
+			return fmt.Errorf("invalid params of the restored chain %s: %s", chainID, err)
+		}
+
+		destID = destID.Normalize()
+		overridable[destID] = true
+
+		entries = append(entries, appChainEntry{name: fmt.Sprintf("restored chain %s", destID), chainID: chainID, destID: destID})
+	}
+
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		destID := configured[name]
+		if err := destID.Validate(); err != nil {
+			return fmt.Errorf("invalid app chain %s: %s", name, err)
+		}
+
+		destID = destID.Normalize()
+
+		// a single override applies to a restored chain
+		if overridable[destID] {
+			delete(overridable, destID)
+			continue
+		}
+
+		entries = append(entries, appChainEntry{name: name, chainID: destID.ChainID(), destID: destID})
+	}
+
+	byDestID := make(map[common.DestID]int, len(entries))
+	byChainID := make(map[string]int, len(entries))
+
+	for i, entry := range entries {
+		if j, ok := byDestID[entry.destID]; ok {
+			return fmt.Errorf("app chains %s and %s resolve to the same dest ID %s", entries[j].name, entry.name, entry.destID)
+		}
+
+		if j, ok := byChainID[entry.chainID]; ok {
+			return fmt.Errorf("app chains %s and %s share the chain ID %s", entries[j].name, entry.name, entry.chainID)
+		}
+
+		byDestID[entry.destID] = i
+		byChainID[entry.chainID] = i
+	}
+
+	return nil
+}
+
 // DeleteChain delete a app chain for the relayer
 func (r *Relayer) DeleteChain(chainID string) error {
 	r.mtx.Lock()
@@ -80,7 +271,7 @@ func (r *Relayer) DeleteChain(chainID string) error {
 	if err := chain.Stop(); err != nil {
 		return err
 	}
-	delete(r.AppChains, chainID)
+	r.setAppChain(chainID, nil)
 	delete(r.AppChainStates, chainID)
 	r.AppChainFactory.DeleteChainConfig(r.AppChainType, chainID)
 
@@ -92,6 +283,10 @@ func (r *Relayer) StartChain(chainID string) error {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
+	if r.isClosing() {
+		return ErrRelayerClosing
+	}
+
 	state, ok := r.AppChainStates[chainID]
 	if !ok {
 		return fmt.Errorf("chain ID %s does not exist", chainID)
@@ -102,7 +297,7 @@ func (r *Relayer) StartChain(chainID string) error {
 	}
 
 	chain := r.AppChains[chainID]
-	if err := chain.Start(r.HandleInterchainRequest); err != nil {
+	if err := r.startChain(chain); err != nil {
 		return err
 	}
 
@@ -137,7 +332,7 @@ func (r *Relayer) StopChain(chainID string) error {
 
 // GetChain gets the specified app chain
 func (r *Relayer) GetChain(chainID string) (appChain AppChainI, err error) {
-	appChain, ok := r.AppChains[chainID]
+	appChain, ok := r.appChain(chainID)
 	if !ok {
 		return nil, fmt.Errorf("chain ID %s does not exist", chainID)
 	}
@@ -145,6 +340,29 @@ func (r *Relayer) GetChain(chainID string) (appChain AppChainI, err error) {
 	return appChain, nil
 }
 
+// appChain returns the given app chain, safe to call without mtx
+func (r *Relayer) appChain(chainID string) (AppChainI, bool) {
+	r.chainsMtx.RLock()
+	defer r.chainsMtx.RUnlock()
+
+	chain, ok := r.AppChains[chainID]
+
+	return chain, ok
+}
+
+// setAppChain sets the given app chain under mtx, removing it if nil
+func (r *Relayer) setAppChain(chainID string, chain AppChainI) {
+	r.chainsMtx.Lock()
+	defer r.chainsMtx.Unlock()
+
+	if chain == nil {
+		delete(r.AppChains, chainID)
+		return
+	}
+
+	r.AppChains[chainID] = chain
+}
+
 // GetChains retrieves the current active app chains
 func (r *Relayer) GetChains() []string {
 	chains := make([]string, 0)
@@ -172,3 +390,80 @@ func (r *Relayer) GetChainStatus(chainID string) (state bool, height int64, err
 
 	return state, height, nil
 }
+
+// Shutdown stops all app chain monitors and waits for the in-flight requests
+// to be responded until the context is done. The checkpoints are written on
+// each handled block, so they are up to date once the monitors stop. The
+// requests still awaiting responses on return are kept in the store and
+// resumed on the next start
+func (r *Relayer) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&r.closing, 0, 1) {
+		return fmt.Errorf("relayer is already shut down")
+	}
+
+	defer r.cancel()
+
+	r.mtx.Lock()
+	for chainID, state := range r.AppChainStates {
+		if !state {
+			continue
+		}
+
+		if err := r.AppChains[chainID].Stop(); err != nil {
+			r.Logger.Errorf("failed to stop chain %s: %s", chainID, err)
+			continue
+		}
+
+		r.AppChainStates[chainID] = false
+	}
+	r.mtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.Logger.Infof("relayer shut down")
+		return nil
+
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight requests left pending for the next start: %s", ctx.Err())
+	}
+}
+
+// isClosing returns true if the relayer is shutting down
+func (r *Relayer) isClosing() bool {
+	return atomic.LoadInt32(&r.closing) == 1
+}
+
+// CheckChains checks the connectivity of the Hub and all app chains
+func (r *Relayer) CheckChains(ctx context.Context) error {
+	failed := 0
+
+	if err := r.HubChain.CheckConnection(ctx); err != nil {
+		r.Logger.Errorf("hub chain check failed: %s", err)
+		failed++
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for chainID, chain := range r.AppChains {
+		if err := chain.CheckConnection(ctx); err != nil {
+			r.Logger.Errorf("chain %s check failed: %s", chainID, err)
+			failed++
+			continue
+		}
+
+		r.Logger.Infof("chain %s is reachable", chainID)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d chain check(s) failed", failed)
+	}
+
+	return nil
+}
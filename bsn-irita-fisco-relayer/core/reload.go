@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+)
+
+// ReloadAppChains applies the given base config to the app chains
+// All the chains are rebuilt before any is replaced, so that the current chains are
+// kept if the config fails on any of them. A replaced chain resumes from the checkpoint
+// where the previous one stopped, and the new config applies to the chains added later
+func (r *Relayer) ReloadAppChains(baseConfig []byte) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.isClosing() {
+		return ErrRelayerClosing
+	}
+
+	reloaded := make(map[string]AppChainI)
+
+	for chainID, chain := range r.AppChains {
+		reloadable, ok := chain.(ReloadableAppChain)
+		if !ok {
+			continue
+		}
+
+		newChain, err := reloadable.Reload(baseConfig)
+		if err != nil {
+			r.discardReloaded(reloaded)
+			return fmt.Errorf("failed to reload chain %s: %s", chainID, err)
+		}
+
+		if newChain != chain {
+			reloaded[chainID] = newChain
+		}
+	}
+
+	if err := r.AppChainFactory.StoreBaseConfig(r.AppChainType, baseConfig); err != nil {
+		r.discardReloaded(reloaded)
+		return err
+	}
+
+	for chainID, newChain := range reloaded {
+		chain := r.AppChains[chainID]
+
+		if r.AppChainStates[chainID] {
+			if err := chain.Stop(); err != nil {
+				r.Logger.Errorf("failed to stop chain %s for reload: %s", chainID, err)
+				releaseChain(newChain, chain)
+				continue
+			}
+		}
+
+		// the height is handed over once the current monitor is stopped, so that no block is scanned twice
+		if reloadable, ok := newChain.(ReloadableAppChain); ok {
+			reloadable.TakeOver(chain)
+		}
+
+		if r.AppChainStates[chainID] {
+			if err := r.startChain(newChain); err != nil {
+				r.Logger.Errorf("failed to start chain %s on the new config, keeping the current one: %s", chainID, err)
+				releaseChain(newChain, chain)
+
+				if err := r.startChain(chain); err != nil {
+					r.Logger.Errorf("failed to restart chain %s: %s", chainID, err)
+					r.AppChainStates[chainID] = false
+				}
+
+				continue
+			}
+		}
+
+		r.setAppChain(chainID, newChain)
+		releaseChain(chain, newChain)
+
+		r.Logger.Infof("chain %s reloaded", chainID)
+	}
+
+	return nil
+}
+
+// discardReloaded releases the chains built by a reload which is given up
+func (r *Relayer) discardReloaded(reloaded map[string]AppChainI) {
+	for chainID, newChain := range reloaded {
+		releaseChain(newChain, r.AppChains[chainID])
+	}
+}
+
+// releaseChain releases the resources of the chain not shared with the other one
+func releaseChain(chain AppChainI, other AppChainI) {
+	if reloadable, ok := chain.(ReloadableAppChain); ok {
+		reloadable.Release(other)
+	}
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+	"relayer/logging"
+)
+
+// mockReloadableChain is an AppChainI rebuilt on the base config of a different value
+type mockReloadableChain struct {
+	mockAppChain
+	baseConfig string
+	started    bool
+	released   bool
+	scanning   int64 // blocks scanned until stopped
+}
+
+func (m *mockReloadableChain) Start(handler InterchainRequestHandler) error {
+	m.started = true
+	return nil
+}
+
+func (m *mockReloadableChain) Stop() error {
+	m.started = false
+	m.liveness.LastHeight += m.scanning
+	return nil
+}
+
+func (m *mockReloadableChain) Release(other AppChainI) {
+	if other != AppChainI(m) {
+		m.released = true
+	}
+}
+
+func (m *mockReloadableChain) TakeOver(previous AppChainI) {
+	m.liveness.LastHeight = previous.GetHeight()
+}
+
+func (m *mockReloadableChain) Reload(baseConfig []byte) (AppChainI, error) {
+	if string(baseConfig) == "invalid" {
+		return nil, errors.New("invalid base config")
+	}
+
+	if string(baseConfig) == m.baseConfig {
+		return m, nil
+	}
+
+	return &mockReloadableChain{mockAppChain: m.mockAppChain, baseConfig: string(baseConfig)}, nil
+}
+
+// mockAppChainFactory is an AppChainFactoryI recording the stored base config
+type mockAppChainFactory struct {
+	baseConfig string
+}
+
+func (f *mockAppChainFactory) BuildAppChain(string, []byte) (AppChainI, error) { return nil, nil }
+
+// the params of the mock chains are their dest IDs
+func (f *mockAppChainFactory) GetChainID(_ string, params []byte) (string, error) {
+	return common.DestID(params).ChainID(), nil
+}
+
+func (f *mockAppChainFactory) GetDestID(_ string, params []byte) (common.DestID, error) {
+	return common.DestID(params), nil
+}
+
+func (f *mockAppChainFactory) DeleteChainConfig(string, string) error { return nil }
+func (f *mockAppChainFactory) StoreBaseConfig(chainType string, baseConfig []byte) error {
+	f.baseConfig = string(baseConfig)
+	return nil
+}
+
+func TestReloadAppChains(t *testing.T) {
+	factory := &mockAppChainFactory{}
+
+	r := NewRelayer("fisco", nil, factory, nil, logging.Logger)
+
+	running := &mockReloadableChain{mockAppChain: mockAppChain{destID: "fisco-1-1", liveness: ChainLiveness{LastHeight: 10}}, baseConfig: "v1", started: true, scanning: 2}
+	stopped := &mockReloadableChain{mockAppChain: mockAppChain{destID: "fisco-1-2"}, baseConfig: "v1"}
+
+	r.AppChains["1"] = running
+	r.AppChains["2"] = stopped
+	r.AppChainStates["1"] = true
+	r.AppChainStates["2"] = false
+
+	// the chains are kept if the config fails on any of them
+	require.Error(t, r.ReloadAppChains([]byte("invalid")))
+	require.True(t, r.AppChains["1"] == running)
+	require.Empty(t, factory.baseConfig)
+
+	// nothing is replaced if the config is unchanged
+	require.NoError(t, r.ReloadAppChains([]byte("v1")))
+	require.True(t, r.AppChains["1"] == running)
+	require.True(t, running.started)
+
+	require.NoError(t, r.ReloadAppChains([]byte("v2")))
+	require.Equal(t, "v2", factory.baseConfig)
+
+	reloaded := r.AppChains["1"].(*mockReloadableChain)
+	require.Equal(t, "v2", reloaded.baseConfig)
+	require.True(t, reloaded.started)
+
+	// the reloaded chain resumes from the height the current one stopped at
+	require.Equal(t, int64(12), reloaded.GetHeight())
+	require.False(t, running.started)
+	require.True(t, running.released)
+	require.False(t, reloaded.released)
+
+	// the stopped chains are replaced without being started
+	reloaded = r.AppChains["2"].(*mockReloadableChain)
+	require.Equal(t, "v2", reloaded.baseConfig)
+	require.False(t, reloaded.started)
+	require.False(t, r.AppChainStates["2"])
+}
+
+func TestReloadAppChainsConcurrentRelay(t *testing.T) {
+	r := NewRelayer("fisco", nil, &mockAppChainFactory{}, nil, logging.Logger)
+
+	r.AppChains["1"] = &mockReloadableChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}, baseConfig: "v0", started: true}
+	r.AppChainStates["1"] = true
+
+	done := make(chan struct{})
+
+	// the relay path reads the chains while they are replaced
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				require.Equal(t, "fisco-1-1", r.sourceDestID("1").String())
+				r.breaker("1")
+			}
+		}
+	}()
+
+	for i := 1; i <= 20; i++ {
+		require.NoError(t, r.ReloadAppChains([]byte(fmt.Sprintf("v%d", i))))
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func TestValidateAppChains(t *testing.T) {
+	r := NewRelayer("fisco", nil, &mockAppChainFactory{}, nil, logging.Logger)
+
+	// the override of a restored chain is not a duplicate
+	require.NoError(t, r.ValidateAppChains(
+		[][]byte{[]byte("fisco-1-1"), []byte("fisco-1-2")},
+		map[string]common.DestID{"fisco.chains.FISCO-1-1": "FISCO-1-1", "fisco.chains.fisco-1-3": "fisco-1-3"},
+	))
+
+	// the groups of a chain collide, as the chains are keyed by chain ID
+	err := r.ValidateAppChains(nil, map[string]common.DestID{
+		"fisco.chains.fisco-1-1": "fisco-1-1",
+		"fisco.chains.fisco-2-1": "fisco-2-1",
+	})
+	require.EqualError(t, err, "app chains fisco.chains.fisco-1-1 and fisco.chains.fisco-2-1 share the chain ID 1")
+
+	err = r.ValidateAppChains([][]byte{[]byte("fisco-1-1")}, map[string]common.DestID{"fisco.chains.fisco-2-1": "fisco-2-1"})
+	require.EqualError(t, err, "app chains restored chain fisco-1-1 and fisco.chains.fisco-2-1 share the chain ID 1")
+
+	// the dest IDs only differing in the case of the chain type are the same
+	err = r.ValidateAppChains(nil, map[string]common.DestID{
+		"fisco.chains.FISCO-1-1": "FISCO-1-1",
+		"fisco.chains.fisco-1-1": "fisco-1-1",
+	})
+	require.EqualError(t, err, "app chains fisco.chains.FISCO-1-1 and fisco.chains.fisco-1-1 resolve to the same dest ID fisco-1-1")
+
+	require.Error(t, r.ValidateAppChains(nil, map[string]common.DestID{"fisco.chains.fisco": "fisco"}))
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// RangeScanner is an application chain able to scan a closed height range for the interchain requests
+type RangeScanner interface {
+	// scan the blocks of the closed range, calling fn with each request found and its tx hash;
+	// the scan stops at the first error returned by fn. The checkpoint is left untouched
+	ScanRange(ctx context.Context, startHeight int64, endHeight int64, fn func(request InterchainRequest, txHash string) error) error
+}
+
+// ReplaySummary counts the requests handled by a replay
+type ReplaySummary struct {
+	Found    int // requests found in the range
+	Relayed  int // requests whose responses are sent
+	Skipped  int // requests whose responses already exist on chain
+	Filtered int // requests rejected by the event filters
+	Failed   int // requests failed to relay
+}
+
+// Replay relays again the requests emitted by the given app chain in the closed height range
+// The chain must be loaded, but need not be running. The requests go through the filters
+// and are relayed like RelayOnce, while the ones already responded on chain are skipped,
+// so that a range of blocks can be reprocessed independently of the checkpoint
+func (r *Relayer) Replay(ctx context.Context, chainID string, startHeight int64, endHeight int64) (ReplaySummary, error) {
+	var summary ReplaySummary
+
+	if startHeight <= 0 || endHeight < startHeight {
+		return summary, fmt.Errorf("invalid height range [%d, %d]", startHeight, endHeight)
+	}
+
+	chain, err := r.GetChain(chainID)
+	if err != nil {
+		return summary, err
+	}
+
+	scanner, ok := chain.(RangeScanner)
+	if !ok {
+		return summary, fmt.Errorf("chain %s does not support replaying", chain.GetDestID())
+	}
+
+	err = scanner.ScanRange(ctx, startHeight, endHeight, func(request InterchainRequest, txHash string) error {
+		summary.Found++
+
+		logger := r.requestLogger(chainID, request.ID)
+
+		if !r.Filters.Allow(r.sourceEvent(chainID, request, txHash)) {
+			logger.Debugf("interchain request from tx %s filtered out", txHash)
+			summary.Filtered++
+
+			request.TxHash = txHash
+			r.audit(chainID, request, DecisionFiltered, "", nil)
+
+			return nil
+		}
+
+		// the replayed requests are always checked, since most of them are likely relayed already
+		if checker, ok := chain.(ResponseChecker); ok {
+			exists, err := checker.ResponseExists(ctx, request.ID)
+			if err != nil {
+				logger.Errorf("failed to check if the response exists: %s", err)
+				summary.Failed++
+
+				return nil
+			}
+
+			if exists {
+				logger.Infof("response already on chain, skipped")
+				summary.Skipped++
+
+				return nil
+			}
+		}
+
+		request.TxHash = txHash
+
+		responseTxHash, err := r.relay(ctx, chainID, request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logger.Errorf("failed to replay the interchain request from tx %s: %s", txHash, err)
+			summary.Failed++
+
+			return nil
+		}
+
+		// the response may have landed in the meantime
+		if len(responseTxHash) == 0 {
+			summary.Skipped++
+			return nil
+		}
+
+		logger.Infof("interchain request from tx %s replayed, response tx %s", txHash, responseTxHash)
+		summary.Relayed++
+
+		r.audit(chainID, request, DecisionRelayed, responseTxHash, nil)
+
+		return nil
+	})
+
+	return summary, err
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockReplayChain is an AppChainI holding the request IDs by height, some already responded
+type mockReplayChain struct {
+	mockRespondingChain
+	requests  map[int64][]string
+	responded map[string]bool
+}
+
+func (m *mockReplayChain) ScanRange(ctx context.Context, startHeight int64, endHeight int64, fn func(request InterchainRequest, txHash string) error) error {
+	for h := startHeight; h <= endHeight; h++ {
+		for _, id := range m.requests[h] {
+			if err := fn(InterchainRequest{ID: id}, fmt.Sprintf("0x%d", h)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *mockReplayChain) ResponseCheckEnabled() bool { return false }
+
+func (m *mockReplayChain) ResponseExists(ctx context.Context, requestID string) (bool, error) {
+	return m.responded[requestID], nil
+}
+
+func TestReplay(t *testing.T) {
+	hub := &mockHubChain{response: ResponseAdaptor{StatusCode: 200, ServiceName: "oracle", Output: `{"price":1}`}}
+	r := NewRelayer("fisco", hub, nil, nil, nil)
+	r.Encoders.Register("oracle", JSONEncoder{})
+
+	chain := &mockReplayChain{
+		mockRespondingChain: mockRespondingChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}, outputs: map[string]string{}},
+		requests: map[int64][]string{
+			1: {"req-1"},
+			2: {"req-2", "req-3"},
+			4: {"req-4"},
+			5: {"req-5"},
+		},
+		responded: map[string]bool{"req-3": true},
+	}
+
+	// the chain is replayed while stopped
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = false
+
+	r.RegisterEventFilter("fisco-1-1", func(event Event) bool {
+		return event.Request.ID != "req-4"
+	})
+
+	summary, err := r.Replay(context.Background(), "1", 2, 4)
+	require.NoError(t, err)
+	require.Equal(t, ReplaySummary{Found: 3, Relayed: 1, Skipped: 1, Filtered: 1}, summary)
+
+	require.Len(t, chain.outputs, 1)
+	require.Contains(t, chain.outputs, "req-2")
+
+	_, err = r.Replay(context.Background(), "1", 4, 2)
+	require.Error(t, err)
+
+	_, err = r.Replay(context.Background(), "2", 1, 2)
+	require.Error(t, err)
+
+	// the chains unable to scan a range are not replayed
+	r.AppChains["2"] = &mockAppChain{destID: "fisco-1-2"}
+
+	_, err = r.Replay(context.Background(), "2", 1, 2)
+	require.Error(t, err)
+}
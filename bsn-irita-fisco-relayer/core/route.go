@@ -0,0 +1,227 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"relayer/common"
+)
+
+// RouteWildcard matches any source or service of a route
+const RouteWildcard = "*"
+
+// ErrNoRoute is returned if no route matches a request
+var ErrNoRoute = errors.New("no route matched")
+
+// Route maps the requests of a source service to a destination target
+type Route struct {
+	Source  string      `mapstructure:"source"`  // dest ID of the source chain, or * for any
+	Service string      `mapstructure:"service"` // service requested by the source, i.e. the request method, or * for any
+	Target  RouteTarget `mapstructure:"target"`
+}
+
+// RouteTarget defines the destination of the routed requests
+// The empty fields keep the ones of the request
+type RouteTarget struct {
+	DestID      string `mapstructure:"dest_id"`      // dest ID of the destination chain
+	Contract    string `mapstructure:"contract"`     // endpoint address on the destination chain
+	Method      string `mapstructure:"method"`       // method called on the endpoint
+	ServiceName string `mapstructure:"service_name"` // Hub service invoked, the default service if empty
+}
+
+// Apply returns the request routed to the target
+func (t RouteTarget) Apply(request InterchainRequest) (InterchainRequest, error) {
+	chainType, groupID, chainID, err := common.DestID(t.DestID).Split()
+	if err != nil {
+		return request, err
+	}
+
+	request.DestChainType = chainType
+	request.DestSubChainID = groupID
+	request.DestChainID = chainID
+
+	if len(t.Contract) != 0 {
+		request.EndpointAddress = t.Contract
+	}
+
+	if len(t.Method) != 0 {
+		request.Method = t.Method
+	}
+
+	if len(t.ServiceName) != 0 {
+		request.ServiceName = t.ServiceName
+	}
+
+	return request, nil
+}
+
+// RouteChain is a destination chain the routes may target
+type RouteChain struct {
+	Name      string `mapstructure:"name"` // optional label of the chain
+	ChainType string `mapstructure:"chain_type"`
+	GroupID   string `mapstructure:"group_id"` // optional
+	ChainID   string `mapstructure:"chain_id"`
+}
+
+// DestID returns the normalized dest ID of the chain
+func (c RouteChain) DestID() (common.DestID, error) {
+	destID, err := common.NewDestID(c.ChainType, c.GroupID, c.ChainID)
+	if err != nil {
+		return "", err
+	}
+
+	return destID.Normalize(), nil
+}
+
+// label returns the label of the chain in the error messages, by index and name if any
+func (c RouteChain) label(index int) string {
+	if len(c.Name) == 0 {
+		return fmt.Sprintf("%d", index)
+	}
+
+	return fmt.Sprintf("%d (%s)", index, c.Name)
+}
+
+// RouteChainDestIDs returns the dest IDs of the given destination chains
+// An error naming the entries is returned if a chain is malformed or if several chains
+// resolve to the same dest ID, e.g. only differing in name or in the case of the chain type
+func RouteChainDestIDs(chains []RouteChain) ([]common.DestID, error) {
+	destIDs := make([]common.DestID, 0, len(chains))
+	seen := make(map[common.DestID]int, len(chains))
+
+	for i, chain := range chains {
+		destID, err := chain.DestID()
+		if err != nil {
+			return nil, fmt.Errorf("invalid routing chain %s: %s", chain.label(i), err)
+		}
+
+		if j, ok := seen[destID]; ok {
+			return nil, fmt.Errorf("routing chains %s and %s resolve to the same dest ID %s", chains[j].label(j), chain.label(i), destID)
+		}
+
+		seen[destID] = i
+		destIDs = append(destIDs, destID)
+	}
+
+	return destIDs, nil
+}
+
+// routeKey identifies the routes by source and service
+type routeKey struct {
+	source  string
+	service string
+}
+
+// RoutingTable looks up the routes of the requests
+// The exact route of the source and service is preferred over the source
+// wildcard route, then the service wildcard route and the default route.
+// The dest IDs are normalized, so that the chain types match regardless of case
+type RoutingTable struct {
+	routes map[routeKey]RouteTarget
+}
+
+// NewRoutingTable constructs a new RoutingTable instance from the given routes
+// An error is returned if a route is malformed, duplicated, or targets a chain
+// not among the given destination chains
+func NewRoutingTable(routes []Route, chains []common.DestID) (*RoutingTable, error) {
+	known := make(map[common.DestID]bool, len(chains))
+	for _, destID := range chains {
+		known[destID.Normalize()] = true
+	}
+
+	table := &RoutingTable{routes: make(map[routeKey]RouteTarget, len(routes))}
+
+	for i, route := range routes {
+		key := routeKey{source: route.Source, service: route.Service}
+
+		if len(key.source) == 0 || len(key.service) == 0 {
+			return nil, fmt.Errorf("route %d: source and service required, %s for any", i, RouteWildcard)
+		}
+
+		if key.source != RouteWildcard {
+			if err := common.DestID(key.source).Validate(); err != nil {
+				return nil, fmt.Errorf("route %d: invalid source: %s", i, err)
+			}
+
+			key.source = common.NormalizeDestID(key.source)
+		}
+
+		if err := common.DestID(route.Target.DestID).Validate(); err != nil {
+			return nil, fmt.Errorf("route %d: invalid target: %s", i, err)
+		}
+
+		route.Target.DestID = common.NormalizeDestID(route.Target.DestID)
+
+		if !known[common.DestID(route.Target.DestID)] {
+			return nil, fmt.Errorf("route %d: target chain %s not configured", i, route.Target.DestID)
+		}
+
+		if _, ok := table.routes[key]; ok {
+			return nil, fmt.Errorf("route %d: duplicate route of service %s from %s", i, key.service, key.source)
+		}
+
+		table.routes[key] = route.Target
+	}
+
+	return table, nil
+}
+
+// Lookup returns the target of the requests of the given source and service
+func (t *RoutingTable) Lookup(source common.DestID, service string) (RouteTarget, error) {
+	source = source.Normalize()
+
+	keys := []routeKey{
+		{source: source.String(), service: service},
+		{source: source.String(), service: RouteWildcard},
+		{source: RouteWildcard, service: service},
+		{source: RouteWildcard, service: RouteWildcard},
+	}
+
+	for _, key := range keys {
+		if target, ok := t.routes[key]; ok {
+			return target, nil
+		}
+	}
+
+	return RouteTarget{}, fmt.Errorf("%w for service %s from %s", ErrNoRoute, service, source)
+}
+
+// route returns the request routed by the routing table, unchanged if no table is set
+func (r *Relayer) route(chainID string, request InterchainRequest) (InterchainRequest, error) {
+	if r.Routes == nil {
+		return request, nil
+	}
+
+	target, err := r.Routes.Lookup(r.sourceDestID(chainID), request.Method)
+	if err != nil {
+		return request, err
+	}
+
+	return target.Apply(request)
+}
+
+// routedResponse is a response of a request routed to another Hub service
+type routedResponse struct {
+	ResponseI
+	serviceName string
+}
+
+// GetServiceName implements ResponseI
+func (r routedResponse) GetServiceName() string {
+	return r.serviceName
+}
+
+// withRoutedService labels the response with the Hub service the request is routed to,
+// so that it is encoded for that service
+func withRoutedService(request InterchainRequest, response ResponseI) ResponseI {
+	if len(request.ServiceName) == 0 {
+		return response
+	}
+
+	if adaptor, ok := response.(ResponseAdaptor); ok {
+		adaptor.ServiceName = request.ServiceName
+		return adaptor
+	}
+
+	return routedResponse{ResponseI: response, serviceName: request.ServiceName}
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+func TestRoutingTable(t *testing.T) {
+	chains := []common.DestID{"eth-1", "cosmos-1", "fabric-1-1"}
+
+	table, err := NewRoutingTable([]Route{
+		{Source: "fisco-1-1", Service: "price", Target: RouteTarget{DestID: "eth-1", Contract: "0xabc", Method: "getPrice"}},
+		{Source: "fisco-1-1", Service: RouteWildcard, Target: RouteTarget{DestID: "cosmos-1", ServiceName: "oracle"}},
+		{Source: RouteWildcard, Service: "price", Target: RouteTarget{DestID: "fabric-1-1"}},
+	}, chains)
+	require.NoError(t, err)
+
+	target, err := table.Lookup("fisco-1-1", "price")
+	require.NoError(t, err)
+	require.Equal(t, "eth-1", target.DestID)
+
+	target, err = table.Lookup("fisco-1-1", "weather")
+	require.NoError(t, err)
+	require.Equal(t, "cosmos-1", target.DestID)
+
+	target, err = table.Lookup("fisco-1-2", "price")
+	require.NoError(t, err)
+	require.Equal(t, "fabric-1-1", target.DestID)
+
+	_, err = table.Lookup("fisco-1-2", "weather")
+	require.True(t, errors.Is(err, ErrNoRoute))
+
+	// the default route matches the rest
+	table, err = NewRoutingTable([]Route{
+		{Source: RouteWildcard, Service: RouteWildcard, Target: RouteTarget{DestID: "eth-1"}},
+	}, chains)
+	require.NoError(t, err)
+
+	target, err = table.Lookup("fisco-1-2", "weather")
+	require.NoError(t, err)
+	require.Equal(t, "eth-1", target.DestID)
+
+	// the chain types match regardless of case
+	table, err = NewRoutingTable([]Route{
+		{Source: "FISCO-1-1", Service: "price", Target: RouteTarget{DestID: "Eth-1"}},
+	}, []common.DestID{"ETH-1"})
+	require.NoError(t, err)
+
+	target, err = table.Lookup("fisco-1-1", "price")
+	require.NoError(t, err)
+	require.Equal(t, "eth-1", target.DestID)
+
+	target, err = table.Lookup("Fisco-1-1", "price")
+	require.NoError(t, err)
+	require.Equal(t, "eth-1", target.DestID)
+
+	// the routes targeting unknown chains are rejected on load
+	_, err = NewRoutingTable([]Route{
+		{Source: RouteWildcard, Service: RouteWildcard, Target: RouteTarget{DestID: "eth-2"}},
+	}, chains)
+	require.Error(t, err)
+
+	_, err = NewRoutingTable([]Route{
+		{Source: "fisco", Service: RouteWildcard, Target: RouteTarget{DestID: "eth-1"}},
+	}, chains)
+	require.Error(t, err)
+
+	_, err = NewRoutingTable([]Route{
+		{Source: RouteWildcard, Service: "price", Target: RouteTarget{DestID: "eth-1"}},
+		{Source: RouteWildcard, Service: "price", Target: RouteTarget{DestID: "cosmos-1"}},
+	}, chains)
+	require.Error(t, err)
+}
+
+func TestRouteChainDestIDs(t *testing.T) {
+	destIDs, err := RouteChainDestIDs([]RouteChain{
+		{Name: "eth-mainnet", ChainType: "ETH", ChainID: "1"},
+		{ChainType: "fabric", GroupID: "2", ChainID: "3"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []common.DestID{"eth-1", "fabric-2-3"}, destIDs)
+
+	// the entries only differing in name resolve to the same dest ID
+	_, err = RouteChainDestIDs([]RouteChain{
+		{Name: "cosmos-hub", ChainType: "cosmos", ChainID: "7"},
+		{Name: "eth-mainnet", ChainType: "eth", ChainID: "1"},
+		{Name: "eth-main", ChainType: "Eth", ChainID: "1"},
+	})
+	require.EqualError(t, err, "routing chains 1 (eth-mainnet) and 2 (eth-main) resolve to the same dest ID eth-1")
+
+	_, err = RouteChainDestIDs([]RouteChain{{Name: "eth-mainnet", ChainType: "eth"}})
+	require.Error(t, err)
+}
+
+func TestRouteTargetApply(t *testing.T) {
+	request := InterchainRequest{
+		ID:              "req-1",
+		DestChainType:   "eth",
+		DestChainID:     "1",
+		EndpointAddress: "0x01",
+		Method:          "price",
+	}
+
+	routed, err := RouteTarget{DestID: "fabric-2-3", Method: "getPrice", ServiceName: "oracle"}.Apply(request)
+	require.NoError(t, err)
+	require.Equal(t, common.DestID("fabric-2-3"), routed.GetDestID())
+	require.Equal(t, "0x01", routed.EndpointAddress)
+	require.Equal(t, "getPrice", routed.Method)
+	require.Equal(t, "oracle", routed.ServiceName)
+}
+
+func TestRelayOnceRouted(t *testing.T) {
+	hub := &mockHubChain{response: ResponseAdaptor{StatusCode: 200, ServiceName: "cc-contract-call", Output: `{"price":1}`}}
+	r := NewRelayer("fisco", hub, nil, nil, nil)
+	r.Encoders.Register("oracle", JSONEncoder{})
+
+	chain := &mockRespondingChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}, outputs: map[string]string{}}
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = true
+
+	var err error
+	r.Routes, err = NewRoutingTable([]Route{
+		{Source: "fisco-1-1", Service: "price", Target: RouteTarget{DestID: "eth-1", ServiceName: "oracle"}},
+	}, []common.DestID{"eth-1"})
+	require.NoError(t, err)
+
+	// the response is encoded for the service the request is routed to
+	_, err = r.RelayOnce(context.Background(), InterchainRequest{ID: "req-1", Method: "price"}, "fisco-1-1")
+	require.NoError(t, err)
+	require.Equal(t, `{"price":1}`, chain.outputs["req-1"])
+
+	_, err = r.RelayOnce(context.Background(), InterchainRequest{ID: "req-2", Method: "weather"}, "fisco-1-1")
+	require.True(t, errors.Is(err, ErrNoRoute))
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidInput is returned if the input of a request fails the schema of its service
+var ErrInvalidInput = errors.New("invalid service input")
+
+// InputSchema is the JSON schema subset validating the service inputs
+// Only type, required, properties and items are supported; the fields
+// not among the properties are allowed
+type InputSchema struct {
+	Type       string                  `json:"type,omitempty"` // object, array, string, number, integer, boolean or null; any if empty
+	Required   []string                `json:"required,omitempty"`
+	Properties map[string]*InputSchema `json:"properties,omitempty"`
+	Items      *InputSchema            `json:"items,omitempty"`
+}
+
+// ParseInputSchema parses the JSON schema, checking the types it refers to
+func ParseInputSchema(bz []byte) (*InputSchema, error) {
+	var schema InputSchema
+	if err := json.Unmarshal(bz, &schema); err != nil {
+		return nil, fmt.Errorf("invalid input schema: %s", err)
+	}
+
+	if err := schema.check("$"); err != nil {
+		return nil, fmt.Errorf("invalid input schema: %s", err)
+	}
+
+	return &schema, nil
+}
+
+// check checks the types of the schema and its subschemas
+func (s *InputSchema) check(path string) error {
+	switch s.Type {
+	case "", "object", "array", "string", "number", "integer", "boolean", "null":
+	default:
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s.%s: empty schema", path, name)
+		}
+
+		if err := property.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+
+	return nil
+}
+
+// Validate validates the JSON input against the schema
+// The error names the first offending field
+func (s *InputSchema) Validate(input []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%w: malformed JSON: %s", ErrInvalidInput, err)
+	}
+
+	if decoder.More() {
+		return fmt.Errorf("%w: malformed JSON: trailing data", ErrInvalidInput)
+	}
+
+	if err := s.validate("$", value); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err)
+	}
+
+	return nil
+}
+
+// validate validates the decoded value at the given path
+func (s *InputSchema) validate(path string, value interface{}) error {
+	if len(s.Type) != 0 && !matchesType(s.Type, value) {
+		return fmt.Errorf("field %s: expected %s, got %s", path, s.Type, typeOf(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("field %s.%s: required", path, name)
+			}
+		}
+
+		// the properties are validated in order, so that the error is deterministic
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if field, ok := v[name]; ok {
+				if err := s.Properties[name].validate(path+"."+name, field); err != nil {
+					return err
+				}
+			}
+		}
+
+	case []interface{}:
+		if s.Items == nil {
+			return nil
+		}
+
+		for i, item := range v {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchesType returns true if the decoded value is of the given schema type
+func matchesType(schemaType string, value interface{}) bool {
+	actual := typeOf(value)
+
+	if schemaType == "number" && actual == "integer" {
+		return true
+	}
+
+	return schemaType == actual
+}
+
+// typeOf returns the schema type of the decoded value
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+
+	case []interface{}:
+		return "array"
+
+	case string:
+		return "string"
+
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return "number"
+		}
+
+		return "integer"
+
+	case bool:
+		return "boolean"
+
+	default:
+		return "null"
+	}
+}
+
+// SchemaRegistry holds the input schemas by service name
+// It is safe for concurrent use
+type SchemaRegistry struct {
+	defaultService string // service of the requests not routed to another one
+
+	mtx     sync.RWMutex
+	schemas map[string]*InputSchema
+}
+
+// NewSchemaRegistry constructs a new empty SchemaRegistry instance
+// The requests naming no service are validated against the schema of the default service
+func NewSchemaRegistry(defaultService string) *SchemaRegistry {
+	return &SchemaRegistry{
+		defaultService: defaultService,
+		schemas:        make(map[string]*InputSchema),
+	}
+}
+
+// Register registers the input schema of the given service, replacing the existing one
+func (r *SchemaRegistry) Register(serviceName string, schema *InputSchema) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.schemas[serviceName] = schema
+}
+
+// Validate validates the input of the request against the schema of its service
+// The requests of the services without a schema are valid
+func (r *SchemaRegistry) Validate(request InterchainRequest) error {
+	serviceName := request.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = r.defaultService
+	}
+
+	r.mtx.RLock()
+	schema, ok := r.schemas[serviceName]
+	r.mtx.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := schema.Validate(request.CallData); err != nil {
+		return fmt.Errorf("service %s: %w", serviceName, err)
+	}
+
+	return nil
+}
+
+// validateInput validates the input of the request, valid if no schema is registered
+func (r *Relayer) validateInput(request InterchainRequest) error {
+	if r.Schemas == nil {
+		return nil
+	}
+
+	return r.Schemas.Validate(request)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testInputSchema = `{
+	"type": "object",
+	"required": ["symbol", "amount"],
+	"properties": {
+		"symbol": {"type": "string"},
+		"amount": {"type": "integer"},
+		"rate": {"type": "number"},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	}
+}`
+
+func TestInputSchema(t *testing.T) {
+	schema, err := ParseInputSchema([]byte(testInputSchema))
+	require.NoError(t, err)
+
+	require.NoError(t, schema.Validate([]byte(`{"symbol":"BTC","amount":1,"rate":1,"tags":["a"]}`)))
+
+	// the unexpected fields are allowed
+	require.NoError(t, schema.Validate([]byte(`{"symbol":"BTC","amount":1,"memo":{"any":true}}`)))
+
+	err = schema.Validate([]byte(`{"symbol":"BTC"}`))
+	require.True(t, errors.Is(err, ErrInvalidInput))
+	require.Contains(t, err.Error(), "field $.amount: required")
+
+	err = schema.Validate([]byte(`{"symbol":"BTC","amount":"1"}`))
+	require.True(t, errors.Is(err, ErrInvalidInput))
+	require.Contains(t, err.Error(), "field $.amount: expected integer, got string")
+
+	err = schema.Validate([]byte(`{"symbol":"BTC","amount":1.5}`))
+	require.Contains(t, err.Error(), "field $.amount: expected integer, got number")
+
+	err = schema.Validate([]byte(`{"symbol":"BTC","amount":1,"tags":["a",2]}`))
+	require.Contains(t, err.Error(), "field $.tags[1]: expected string, got integer")
+
+	err = schema.Validate([]byte(`{"symbol":"BTC",`))
+	require.True(t, errors.Is(err, ErrInvalidInput))
+
+	_, err = ParseInputSchema([]byte(`{"type":"decimal"}`))
+	require.Error(t, err)
+}
+
+func TestHandleInvalidInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := NewFileDeadLetterQueue(filepath.Join(dir, "deadletters.jsonl"))
+	require.NoError(t, err)
+
+	schema, err := ParseInputSchema([]byte(testInputSchema))
+	require.NoError(t, err)
+
+	hub := &mockHubChain{silent: true}
+	r := NewRelayer("fisco", hub, nil, nil, nil)
+	r.DeadLetters = queue
+	r.Schemas = NewSchemaRegistry("oracle")
+	r.Schemas.Register("oracle", schema)
+	r.AppChains["1"] = &mockAppChain{destID: "fisco-1-1"}
+
+	request := InterchainRequest{ID: "req-1", CallData: []byte(`{"symbol":"BTC"}`)}
+
+	err = r.HandleInterchainRequest("1", request, "0x01")
+	require.True(t, errors.Is(err, ErrInvalidInput))
+
+	letters, err := queue.List()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, StageRequest, letters[0].Stage)
+	require.Equal(t, "0x01", letters[0].Request.TxHash)
+	require.Contains(t, letters[0].Error, "service oracle: invalid service input: field $.amount: required")
+
+	// the requests of the services without a schema are not validated
+	require.NoError(t, r.Schemas.Validate(InterchainRequest{ID: "req-2", ServiceName: "weather", CallData: []byte(`{}`)}))
+}
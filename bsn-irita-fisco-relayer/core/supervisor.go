@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"relayer/common"
+	"relayer/logging"
+	"relayer/metrics"
+)
+
+// Task is a unit of work run under supervision until it returns nil or its context is done
+type Task func(ctx context.Context) error
+
+// UnrecoverableError is a task error which aborts all the supervised tasks
+type UnrecoverableError struct {
+	Err error
+}
+
+// Error implements error
+func (e UnrecoverableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e UnrecoverableError) Unwrap() error {
+	return e.Err
+}
+
+// Unrecoverable marks the given error as aborting all the supervised tasks
+func Unrecoverable(err error) error {
+	return UnrecoverableError{Err: err}
+}
+
+// Supervisor runs each task in its own goroutine, like an errgroup whose tasks are isolated:
+// a task failing by an error or a panic is logged, metered and restarted with backoff
+// without affecting the others, and only an unrecoverable error cancels all the tasks
+type Supervisor struct {
+	policy common.RetryPolicy
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mtx sync.Mutex
+	err error
+}
+
+// NewSupervisor constructs a new Supervisor instance
+// The tasks are canceled when the given context is done, and restarted by the backoff of the policy
+func NewSupervisor(ctx context.Context, policy common.RetryPolicy) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Supervisor{
+		policy: policy,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go runs the given task in a new goroutine under supervision
+func (s *Supervisor) Go(name string, task Task) {
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		if err := Supervise(s.ctx, name, s.policy, task); err != nil {
+			s.mtx.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.mtx.Unlock()
+
+			s.cancel()
+		}
+	}()
+}
+
+// Wait blocks until all the tasks return, and returns the unrecoverable error if any
+func (s *Supervisor) Wait() error {
+	s.wg.Wait()
+
+	return s.Err()
+}
+
+// Done returns a channel closed once the tasks are canceled, either by the parent context
+// or by an unrecoverable error
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err returns the unrecoverable error which canceled the tasks, if any
+func (s *Supervisor) Err() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.err
+}
+
+// Supervise runs the task until it returns nil, fails unrecoverably or the context is done
+// The task is restarted with backoff on an error or a panic; the backoff is reset once the
+// task has run longer than the max delay of the policy. The unrecoverable error is returned
+func Supervise(ctx context.Context, name string, policy common.RetryPolicy, task Task) error {
+	logger := logging.Logger.WithField(metrics.LabelTask, name)
+
+	healthyRun := policy.MaxDelay
+	if healthyRun <= 0 {
+		healthyRun = common.DefaultRetryMaxDelay
+	}
+
+	for failures := 0; ; {
+		startedAt := time.Now()
+
+		err := runTask(ctx, task)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+
+		var unrecoverable UnrecoverableError
+		if errors.As(err, &unrecoverable) {
+			logger.Errorf("task failed unrecoverably: %s", err)
+			return err
+		}
+
+		metrics.TaskFailures.WithLabelValues(name).Inc()
+
+		if time.Since(startedAt) > healthyRun {
+			failures = 0
+		}
+
+		failures++
+
+		delay := policy.Delay(failures)
+		logger.Errorf("task failed, restarting in %s: %s", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTask runs the task, converting a panic into an error
+func runTask(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return task(ctx)
+}
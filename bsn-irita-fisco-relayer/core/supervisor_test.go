@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+var testSupervisorPolicy = common.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// listener returns a task processing a block per tick until its context is done
+func listener(processed *int64) Task {
+	return func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case <-time.After(time.Millisecond):
+				atomic.AddInt64(processed, 1)
+			}
+		}
+	}
+}
+
+func TestSupervisorIsolatesPanics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	supervisor := NewSupervisor(ctx, testSupervisorPolicy)
+
+	var chain1, chain2, panics int64
+
+	supervisor.Go("fisco-1-1", listener(&chain1))
+	supervisor.Go("fisco-1-2", listener(&chain2))
+	supervisor.Go("fisco-1-3", func(ctx context.Context) error {
+		atomic.AddInt64(&panics, 1)
+		panic("listener crashed")
+	})
+
+	// the panicking listener is restarted while the others keep processing
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&panics) >= 3
+	}, time.Second, time.Millisecond)
+
+	processed1, processed2 := atomic.LoadInt64(&chain1), atomic.LoadInt64(&chain2)
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&chain1) > processed1 && atomic.LoadInt64(&chain2) > processed2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, supervisor.Wait())
+}
+
+func TestSupervisorRestartsFailedTasks(t *testing.T) {
+	supervisor := NewSupervisor(context.Background(), testSupervisorPolicy)
+
+	var attempts int64
+
+	supervisor.Go("fisco-1-1", func(ctx context.Context) error {
+		if atomic.AddInt64(&attempts, 1) < 3 {
+			return errors.New("connection refused")
+		}
+
+		return nil
+	})
+
+	require.NoError(t, supervisor.Wait())
+	require.Equal(t, int64(3), attempts)
+}
+
+func TestSupervisorAbortsOnUnrecoverableError(t *testing.T) {
+	supervisor := NewSupervisor(context.Background(), testSupervisorPolicy)
+
+	var processed int64
+
+	supervisor.Go("fisco-1-1", listener(&processed))
+	supervisor.Go("fisco-1-2", func(ctx context.Context) error {
+		return Unrecoverable(errors.New("invalid config"))
+	})
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(time.Second):
+		t.Fatal("supervisor not aborted")
+	}
+
+	require.EqualError(t, supervisor.Wait(), "invalid config")
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"relayer/metrics"
+)
+
+// relay milestones marked on the trace of a request
+const (
+	MilestoneEmitted   = "emitted"   // request event emitted on the source chain
+	MilestoneEnqueued  = "enqueued"  // request accepted by the relayer
+	MilestoneResponded = "responded" // response of the request received from the Hub
+	MilestoneBuilt     = "built"     // response tx built, about to be signed
+	MilestoneSigned    = "signed"    // response tx signed
+	MilestoneBroadcast = "broadcast" // response tx broadcast
+	MilestoneConfirmed = "confirmed" // response tx confirmed
+)
+
+// relay stages timed between two consecutive milestones
+const (
+	LatencyStageIngest    = "event_to_enqueue"
+	LatencyStageHub       = "enqueue_to_response"
+	LatencyStageBuild     = "enqueue_to_build"
+	LatencyStageSign      = "build_to_sign"
+	LatencyStageBroadcast = "sign_to_broadcast"
+	LatencyStageConfirm   = "broadcast_to_confirm"
+)
+
+// latencyStages are the relay stages in order, with their start and end milestones
+var latencyStages = []struct {
+	name     string
+	from, to string
+}{
+	{LatencyStageIngest, MilestoneEmitted, MilestoneEnqueued},
+	{LatencyStageHub, MilestoneEnqueued, MilestoneResponded},
+	{LatencyStageBuild, MilestoneResponded, MilestoneBuilt}, // excludes the Hub round trip
+
+	{LatencyStageSign, MilestoneBuilt, MilestoneSigned},
+	{LatencyStageBroadcast, MilestoneSigned, MilestoneBroadcast},
+	{LatencyStageConfirm, MilestoneBroadcast, MilestoneConfirmed},
+}
+
+// RelayTrace records the times the relay of a request reaches its milestones
+// A milestone marked again, e.g. by a retried tx, keeps the latest time. It is safe for concurrent use
+type RelayTrace struct {
+	mtx   sync.Mutex
+	marks map[string]time.Time
+}
+
+type traceKey struct{}
+
+// NewRelayTrace constructs a new RelayTrace instance
+func NewRelayTrace() *RelayTrace {
+	return &RelayTrace{
+		marks: make(map[string]time.Time),
+	}
+}
+
+// WithRelayTrace returns a copy of the context carrying the given trace
+func WithRelayTrace(ctx context.Context, trace *RelayTrace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// RelayTraceFromContext returns the trace carried by the context, nil if none
+func RelayTraceFromContext(ctx context.Context) *RelayTrace {
+	trace, _ := ctx.Value(traceKey{}).(*RelayTrace)
+	return trace
+}
+
+// MarkMilestone marks the milestone now on the trace carried by the context, if any
+// The app chains call it while sending the responses
+func MarkMilestone(ctx context.Context, milestone string) {
+	if trace := RelayTraceFromContext(ctx); trace != nil {
+		trace.Mark(milestone, time.Now())
+	}
+}
+
+// Mark marks the milestone at the given time, ignored if zero
+func (t *RelayTrace) Mark(milestone string, at time.Time) {
+	if at.IsZero() {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.marks[milestone] = at
+}
+
+// At returns the time the milestone was marked at, the zero time if not marked
+func (t *RelayTrace) At(milestone string) time.Time {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return t.marks[milestone]
+}
+
+// Durations returns the durations of the stages whose both milestones are marked
+// The negative durations, e.g. by the clock skew of the source chain, are reported as zero
+func (t *RelayTrace) Durations() map[string]time.Duration {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	durations := make(map[string]time.Duration)
+
+	for _, stage := range latencyStages {
+		from, ok := t.marks[stage.from]
+		if !ok {
+			continue
+		}
+
+		to, ok := t.marks[stage.to]
+		if !ok {
+			continue
+		}
+
+		duration := to.Sub(from)
+		if duration < 0 {
+			duration = 0
+		}
+
+		durations[stage.name] = duration
+	}
+
+	return durations
+}
+
+// observeTrace observes the stage durations of the trace by the dest ID of the request,
+// logging them at debug level for the sampled fraction of the requests
+func (r *Relayer) observeTrace(chainID string, request InterchainRequest, trace *RelayTrace) {
+	durations := trace.Durations()
+	dest := request.GetDestID().String()
+
+	for stage, duration := range durations {
+		metrics.StageLatency.WithLabelValues(stage, dest).Observe(duration.Seconds())
+	}
+
+	if len(durations) == 0 || r.LatencyLogSample <= 0 || rand.Float64() >= r.LatencyLogSample {
+		return
+	}
+
+	fields := make(log.Fields, len(durations))
+	for stage, duration := range durations {
+		fields[stage] = duration.String()
+	}
+
+	r.requestLogger(chainID, request.ID).WithFields(fields).Debug("relay stage latency")
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockTracedChain is an AppChainI marking the milestones of the response tx, the way the FISCO chain does
+type mockTracedChain struct {
+	mockAppChain
+	trace *RelayTrace
+}
+
+func (m *mockTracedChain) SendResponse(ctx context.Context, requestID string, response ResponseI) (string, error) {
+	m.trace = RelayTraceFromContext(ctx)
+
+	for _, milestone := range []string{MilestoneBuilt, MilestoneSigned, MilestoneBroadcast, MilestoneConfirmed} {
+		MarkMilestone(ctx, milestone)
+	}
+
+	return "0x" + requestID, nil
+}
+
+func TestRelayTrace(t *testing.T) {
+	now := time.Now()
+
+	trace := NewRelayTrace()
+	trace.Mark(MilestoneEmitted, now.Add(time.Second)) // source clock ahead
+	trace.Mark(MilestoneEnqueued, now)
+	trace.Mark(MilestoneBuilt, time.Time{})
+	trace.Mark(MilestoneSigned, now.Add(3*time.Second))
+	trace.Mark(MilestoneBroadcast, now.Add(4*time.Second))
+	trace.Mark(MilestoneBroadcast, now.Add(5*time.Second))
+
+	require.Equal(t, map[string]time.Duration{
+		LatencyStageIngest:    0,
+		LatencyStageBroadcast: 2 * time.Second,
+	}, trace.Durations())
+	require.Equal(t, now.Add(5*time.Second), trace.At(MilestoneBroadcast))
+	require.True(t, trace.At(MilestoneBuilt).IsZero())
+
+	// the build is timed from the response of the Hub, not from the enqueuing
+	trace.Mark(MilestoneResponded, now.Add(time.Second))
+	trace.Mark(MilestoneBuilt, now.Add(2*time.Second))
+
+	durations := trace.Durations()
+	require.Equal(t, time.Second, durations[LatencyStageHub])
+	require.Equal(t, time.Second, durations[LatencyStageBuild])
+
+	// no trace is carried by the context
+	MarkMilestone(context.Background(), MilestoneConfirmed)
+	require.Nil(t, RelayTraceFromContext(context.Background()))
+}
+
+func TestTracedRelay(t *testing.T) {
+	r := NewRelayer("fisco", &mockHubChain{silent: true}, nil, nil, nil)
+	r.Encoders.Register("oracle", JSONEncoder{})
+	r.LatencyLogSample = 1
+
+	chain := &mockTracedChain{mockAppChain: mockAppChain{destID: "fisco-1-1"}}
+	r.AppChains["1"] = chain
+	r.AppChainStates["1"] = true
+
+	request := InterchainRequest{ID: "req-1", ServiceName: "oracle", EmittedAt: time.Now().Add(-time.Second)}
+
+	r.track()
+	r.responseCallback("1", request, request, time.Now(), nil)("ic-req-1", ResponseAdaptor{StatusCode: 200, ServiceName: "oracle", Output: "{}"}, nil)
+
+	require.NotNil(t, chain.trace)
+
+	durations := chain.trace.Durations()
+	require.Len(t, durations, len(latencyStages))
+	require.True(t, durations[LatencyStageIngest] >= time.Second)
+}
@@ -5,11 +5,13 @@ go 1.14
 require (
 	github.com/FISCO-BCOS/go-sdk v0.10.0
 	github.com/cockroachdb/pebble v0.0.0-20201118202804-75ede898b66c
+	github.com/cosmos/go-bip39 v1.0.0
 	github.com/ethereum/go-ethereum v1.9.18
 	github.com/gin-gonic/gin v1.4.0
 	github.com/go-sql-driver/mysql v1.4.0
 	github.com/irisnet/service-sdk-go v1.0.1-0.20210416090657-1bdf41efe743
 	github.com/pelletier/go-toml v1.6.0 // indirect
+	github.com/prometheus/client_golang v1.8.0
 	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/afero v1.2.2 // indirect
 	github.com/spf13/cast v1.3.1 // indirect
@@ -17,6 +19,8 @@ require (
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.7.0
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/net v0.0.0-20201010224723-4f7140c49acb
 )
 
 replace (
@@ -0,0 +1,107 @@
+package hub
+
+import (
+	"fmt"
+
+	"github.com/irisnet/service-sdk-go/types"
+
+	"relayer/common"
+	"relayer/keystore"
+	"relayer/logging"
+)
+
+// SetAccounts validates the given signing accounts and replaces the pool accounts with them
+// The accounts must be present in the key DAO; nothing is changed if any is invalid
+func (ic IritaHubChain) SetAccounts(accounts []Account) error {
+	if err := ic.ValidateAccounts(accounts); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		names = append(names, account.KeyName)
+	}
+
+	if err := ic.Pool.SetAccounts(names); err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		ic.passphrases.Store(account.KeyName, account.Passphrase)
+	}
+
+	logging.Logger.Infof("signing accounts of %s: %v", ic.ChainID, names)
+
+	return nil
+}
+
+// ValidateAccounts checks if all the given accounts are present in the key DAO
+func (ic IritaHubChain) ValidateAccounts(accounts []Account) error {
+	for _, account := range accounts {
+		if _, err := ic.ShowKey(account.KeyName, account.Passphrase); err != nil {
+			return fmt.Errorf("failed to load the key %s: %s", account.KeyName, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckAccounts checks if all the signing accounts of the pool are present in the key DAO
+func (ic IritaHubChain) CheckAccounts() error {
+	for _, name := range ic.Pool.Accounts() {
+		if _, err := ic.ShowKey(name, ic.passphrase(name)); err != nil {
+			return fmt.Errorf("failed to load the key %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// passphrase returns the passphrase of the given signing account
+func (ic IritaHubChain) passphrase(keyName string) string {
+	if passphrase, ok := ic.passphrases.Load(keyName); ok {
+		return passphrase.(string)
+	}
+
+	return ic.Passphrase
+}
+
+// signer returns the signer of the given signing account in the keyring
+func (ic IritaHubChain) signer(keyName string) (keystore.Signer, error) {
+	return keystore.NewSigner(keystore.ChainTypeHub, keystore.SignerParams{
+		Keyring:    ic.ServiceClient,
+		KeyName:    keyName,
+		Passphrase: ic.passphrase(keyName),
+	})
+}
+
+// buildAccountBaseTx builds a base tx signed by the given account
+func (ic IritaHubChain) buildAccountBaseTx(keyName string) types.BaseTx {
+	return types.BaseTx{
+		From:     keyName,
+		Password: ic.passphrase(keyName),
+	}
+}
+
+// balanceChecker returns the common.BalanceChecker comparing the balances with the minimum
+// nil is returned if no minimum balance is required
+func (ic IritaHubChain) balanceChecker(minBalance types.Coins) common.BalanceChecker {
+	if minBalance.Empty() {
+		return nil
+	}
+
+	return func(keyName string) (bool, error) {
+		_, balance, err := ic.queryBalance(keyName)
+		if err != nil {
+			logging.Logger.Warnf("failed to query the balance of %s: %s", keyName, err)
+			return false, err
+		}
+
+		if !balance.IsAllGTE(minBalance) {
+			logging.Logger.Warnf("account %s skipped, balance %s below the minimum %s", keyName, balance, minBalance)
+			return false, nil
+		}
+
+		return true, nil
+	}
+}
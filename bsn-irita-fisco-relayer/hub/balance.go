@@ -0,0 +1,166 @@
+package hub
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/irisnet/service-sdk-go/types"
+
+	"relayer/common"
+	"relayer/logging"
+	"relayer/metrics"
+)
+
+// DefaultBalancePollInterval is the default interval between two balance polls
+const DefaultBalancePollInterval = time.Minute
+
+// BalanceAlert is called once the balance of a signing account drops below the threshold
+type BalanceAlert func(keyName string, address string, balance types.Coins, threshold types.Coins)
+
+// balanceQuery returns the address and balance of the given signing account
+type balanceQuery func(keyName string) (address string, balance types.Coins, err error)
+
+// BalancePoller polls the balances of the signing accounts periodically
+// The balances are exported as gauges, and a warning is logged along with the
+// registered alerts once an account drops below the threshold. The alerts are
+// not repeated until the account recovers
+type BalancePoller struct {
+	chainID   string
+	interval  time.Duration
+	threshold types.Coins // no alert is raised if empty
+	accounts  func() []string
+	query     balanceQuery
+
+	mtx    sync.Mutex
+	alerts []BalanceAlert
+	low    map[string]bool // accounts below the threshold by key name
+}
+
+// NewBalancePoller constructs a new BalancePoller instance
+func NewBalancePoller(
+	chainID string,
+	interval time.Duration,
+	threshold types.Coins,
+	accounts func() []string,
+	query balanceQuery,
+) *BalancePoller {
+	if interval <= 0 {
+		interval = DefaultBalancePollInterval
+	}
+
+	return &BalancePoller{
+		chainID:   chainID,
+		interval:  interval,
+		threshold: threshold,
+		accounts:  accounts,
+		query:     query,
+		low:       make(map[string]bool),
+	}
+}
+
+// OnLowBalance registers an alert called once an account drops below the threshold
+func (p *BalancePoller) OnLowBalance(alert BalanceAlert) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.alerts = append(p.alerts, alert)
+}
+
+// Start polls the balances in the background until the context is done
+func (p *BalancePoller) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.Poll(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Poll queries the balances of all the signing accounts once
+func (p *BalancePoller) Poll(ctx context.Context) {
+	for _, keyName := range p.accounts() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		address, balance, err := p.query(keyName)
+		if err != nil {
+			logging.Logger.Warnf("failed to query the balance of %s on %s: %s", keyName, p.chainID, err)
+			continue
+		}
+
+		for _, coin := range balance {
+			amount, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+			metrics.AccountBalance.WithLabelValues(p.chainID, address, coin.Denom).Set(amount)
+		}
+
+		p.check(keyName, address, balance)
+	}
+}
+
+// check raises the alerts if the account drops below the threshold
+func (p *BalancePoller) check(keyName string, address string, balance types.Coins) {
+	if p.threshold.Empty() {
+		return
+	}
+
+	low := !balance.IsAllGTE(p.threshold)
+
+	p.mtx.Lock()
+
+	wasLow := p.low[keyName]
+	p.low[keyName] = low
+	alerts := append([]BalanceAlert(nil), p.alerts...)
+
+	p.mtx.Unlock()
+
+	switch {
+	case low && !wasLow:
+		logging.Logger.Warnf("balance of %s (%s) on %s is %s, below the threshold %s", keyName, address, p.chainID, balance, p.threshold)
+
+		for _, alert := range alerts {
+			alert(keyName, address, balance, p.threshold)
+		}
+
+	case !low && wasLow:
+		logging.Logger.Infof("balance of %s (%s) on %s recovered to %s", keyName, address, p.chainID, balance)
+	}
+}
+
+// queryBalance returns the address and balance of the given signing account
+func (ic IritaHubChain) queryBalance(keyName string) (string, types.Coins, error) {
+	var address string
+	var balance types.Coins
+
+	// the results are read only on success, as the call keeps running after the timeout
+	err := common.CallWithTimeout(context.Background(), ic.RequestTimeout, func() error {
+		signer, err := ic.signer(keyName)
+		if err != nil {
+			return err
+		}
+
+		account, qerr := ic.ServiceClient.QueryAccount(signer.Address())
+		if qerr != nil {
+			return qerr
+		}
+
+		address, balance = signer.Address(), account.Coins
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return address, balance, nil
+}
@@ -0,0 +1,58 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/irisnet/service-sdk-go/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"relayer/metrics"
+)
+
+func TestBalancePoller(t *testing.T) {
+	threshold, err := types.ParseCoins("1000upoint")
+	require.NoError(t, err)
+
+	balances := map[string]string{"node0": "5000upoint", "node1": "500upoint"}
+
+	query := func(keyName string) (string, types.Coins, error) {
+		balance, ok := balances[keyName]
+		if !ok {
+			return "", nil, errors.New("key not found")
+		}
+
+		coins, err := types.ParseCoins(balance)
+
+		return "addr-" + keyName, coins, err
+	}
+
+	accounts := func() []string { return []string{"node0", "node1", "node2"} }
+
+	poller := NewBalancePoller("irita-hub", 0, threshold, accounts, query)
+
+	var alerted []string
+	poller.OnLowBalance(func(keyName string, address string, balance types.Coins, threshold types.Coins) {
+		alerted = append(alerted, keyName)
+	})
+
+	poller.Poll(context.Background())
+
+	require.Equal(t, []string{"node1"}, alerted)
+	require.Equal(t, float64(5000), testutil.ToFloat64(metrics.AccountBalance.WithLabelValues("irita-hub", "addr-node0", "upoint")))
+	require.Equal(t, float64(500), testutil.ToFloat64(metrics.AccountBalance.WithLabelValues("irita-hub", "addr-node1", "upoint")))
+
+	// the alert is not repeated until the account recovers
+	poller.Poll(context.Background())
+	require.Equal(t, []string{"node1"}, alerted)
+
+	balances["node1"] = "2000upoint"
+	poller.Poll(context.Background())
+	require.Equal(t, []string{"node1"}, alerted)
+
+	balances["node1"] = "100upoint"
+	poller.Poll(context.Background())
+	require.Equal(t, []string{"node1", "node1"}, alerted)
+}
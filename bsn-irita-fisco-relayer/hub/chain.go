@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	servicesdk "github.com/irisnet/service-sdk-go"
+	log "github.com/sirupsen/logrus"
 	"github.com/irisnet/service-sdk-go/service"
 	"github.com/irisnet/service-sdk-go/types"
 	"github.com/irisnet/service-sdk-go/types/store"
+	"sync"
 	"time"
 	"relayer/common"
 	"relayer/core"
+	"relayer/keystore"
 	"relayer/logging"
+	"relayer/metrics"
 	"relayer/mysql"
 )
 
@@ -32,8 +36,17 @@ type IritaHubChain struct {
 	KeyName    string
 	Passphrase string
 
-	ServiceInfo   ServiceInfo
-	ServiceClient servicesdk.ServiceClient
+	ServiceInfo    ServiceInfo
+	ServiceClient  servicesdk.ServiceClient
+	RetryPolicy    common.RetryPolicy       // retry policy for the service invocation
+	Sequencer      *common.AccountSequencer // serializes the txs per signing account
+	Pool           *common.AccountPool      // signing accounts to spread the txs over
+	FeeEstimator   FeeEstimator             // estimates the tx fees, the default fee is paid if nil
+	Balances       *BalancePoller           // polls the balances of the signing accounts
+	RequestTimeout time.Duration            // deadline of a single RPC call
+
+	accountNumbers *sync.Map // account numbers by key name
+	passphrases    *sync.Map // passphrases of the signing accounts by key name
 }
 
 // NewIritaHubChain constructs a new Irita-Hub chain
@@ -49,6 +62,7 @@ func NewIritaHubChain(
 	provider string,
 	serviceFee string,
 	qos uint64,
+	keyDAO store.KeyDAO,
 ) IritaHubChain {
 	if len(chainID) == 0 {
 		chainID = defaultChainID
@@ -86,6 +100,10 @@ func NewIritaHubChain(
 		qos = defaultQoS
 	}
 
+	if keyDAO == nil {
+		keyDAO = store.NewFileDAO(keyPath)
+	}
+
 	fee, err := types.ParseDecCoins(defaultFee)
 	if err != nil {
 		panic(err)
@@ -99,10 +117,13 @@ func NewIritaHubChain(
 		Fee:      fee,
 		Mode:     defaultBroadcastMode,
 		Algo:     defaultKeyAlgorithm,
-		KeyDAO:   store.NewFileDAO(keyPath),
+		KeyDAO:   keyDAO,
 		Level:    "debug",
 	}
 
+	// the simulated gas is left unadjusted, the fee estimator applying the gas multiplier
+	config.GasAdjustment = 1
+
 	hub := IritaHubChain{
 		ChainID:     chainID,
 		NodeRPCAddr: nodeRPCAddr,
@@ -116,15 +137,29 @@ func NewIritaHubChain(
 			ServiceFee:  serviceFee,
 			QoS:         qos,
 		},
-		ServiceClient: servicesdk.NewServiceClient(config),
+		ServiceClient:  servicesdk.NewServiceClient(config),
+		RetryPolicy:    common.DefaultRetryPolicy(),
+		RequestTimeout: common.DefaultRequestTimeout,
+
+		accountNumbers: new(sync.Map),
+		passphrases:    new(sync.Map),
 	}
 
+	hub.Sequencer = common.NewAccountSequencer(common.DefaultSubmitLimits(), hub.querySequence)
+	hub.Pool, _ = common.NewAccountPool(common.StrategyRoundRobin, []string{keyName}, nil)
+	hub.passphrases.Store(keyName, passphrase)
+
 	return hub
 }
 
-// BuildIritaHubChain builds an Irita-Hub instance from the given config
-func BuildIritaHubChain(config Config) IritaHubChain {
-	return NewIritaHubChain(
+// BuildIritaHubChain builds an Irita-Hub instance from the given config and key store
+func BuildIritaHubChain(config Config, keyStore keystore.KeyStore) (IritaHubChain, error) {
+	keyDAO, err := NewKeyDAO(keyStore, config.KeyName, config.Passphrase, defaultKeyAlgorithm)
+	if err != nil {
+		return IritaHubChain{}, err
+	}
+
+	hub := NewIritaHubChain(
 		config.ChainID,
 		config.NodeRPCAddr,
 		config.NodeGRPCAddr,
@@ -136,7 +171,46 @@ func BuildIritaHubChain(config Config) IritaHubChain {
 		config.Provider,
 		config.ServiceFee,
 		config.QoS,
+		keyDAO,
 	)
+
+	hub.RetryPolicy = config.RetryPolicy
+	hub.RequestTimeout = config.RequestTimeout
+	hub.Sequencer = common.NewAccountSequencer(config.SubmitLimits, hub.querySequence)
+
+	minBalance, err := types.ParseCoins(config.MinBalance)
+	if err != nil {
+		return IritaHubChain{}, fmt.Errorf("invalid minimum balance %s: %s", config.MinBalance, err)
+	}
+
+	if config.Fee != nil {
+		hub.FeeEstimator, err = NewSimulationFeeEstimator(*config.Fee, hub.simulateGas)
+		if err != nil {
+			return IritaHubChain{}, err
+		}
+	}
+
+	accounts := config.SigningAccounts()
+	names := make([]string, 0, len(accounts))
+
+	for _, account := range accounts {
+		hub.passphrases.Store(account.KeyName, account.Passphrase)
+		names = append(names, account.KeyName)
+	}
+
+	hub.Pool, err = common.NewAccountPool(config.AccountStrategy, names, hub.balanceChecker(minBalance))
+	if err != nil {
+		return IritaHubChain{}, err
+	}
+
+	threshold, err := types.ParseCoins(config.BalanceThreshold)
+	if err != nil {
+		return IritaHubChain{}, fmt.Errorf("invalid balance threshold %s: %s", config.BalanceThreshold, err)
+	}
+
+	hub.Balances = NewBalancePoller(hub.ChainID, config.BalancePollInterval, threshold, hub.Pool.Accounts, hub.queryBalance)
+
+	return hub, nil
 }
 
 // GetChainID implements IritaHubChainI
@@ -146,7 +220,9 @@ func (ic IritaHubChain) GetChainID() string {
 
 // SendInterchainRequest implements IritaHubChainI
 func (ic IritaHubChain) SendInterchainRequest(
+	ctx context.Context,
 	request core.InterchainRequest,
+	sent core.RequestSentCallback,
 	cb core.ResponseCallback,
 ) error {
 	invokeServiceReq, err := ic.BuildServiceInvocationRequest(request)
@@ -154,15 +230,71 @@ func (ic IritaHubChain) SendInterchainRequest(
 		return err
 	}
 
-	reqCtxID, resTx, err := ic.ServiceClient.InvokeService(invokeServiceReq, ic.BuildBaseTx())
+	logger := logging.WithRequestID(request.ID)
+
+	logger.WithField(logging.FieldStage, logging.StageRequestBuilt).Debugf("service invocation request built: %+v", invokeServiceReq)
+
+	var (
+		reqCtxID string
+		resTx    types.ResultTx
+	)
+
+	err = common.Retry(ctx, func() error {
+		// each attempt may be signed by another account of the pool
+		account, release, err := ic.Pool.Acquire()
+		if err != nil {
+			logger.Warnf("failed to select the signing account: %s", err)
+			return err
+		}
+		defer release()
+
+		err = ic.Sequencer.Submit(ctx, account, func(sequence uint64) (func() error, error) {
+			// the tx is broadcast in the commit mode, so the call returns on confirmation
+			submittedAt := time.Now()
+			result, err := ic.broadcastInvocation(ctx, invokeServiceReq, account, sequence, logger)
+			metrics.TxConfirmationTime.WithLabelValues(request.GetDestID().String()).Observe(time.Since(submittedAt).Seconds())
+
+			if err == nil {
+				reqCtxID, resTx = result.reqCtxID, result.tx
+			}
+
+			return nil, err
+		})
+
+		if err != nil {
+			logger.Warnf("failed to invoke the service with the account %s: %s", account, err)
+		}
+
+		return err
+	}, ic.RetryPolicy)
 	if err != nil {
 		mysql.TxErrCollection(request.ID, err.Error())
 		return err
 	}
 
-	logging.Logger.Infof("request context created on %s: %s", ic.ChainID, reqCtxID)
+	logger.WithFields(log.Fields{
+		logging.FieldTxHash: resTx.Hash,
+		logging.FieldStage:  logging.StageTxSubmitted,
+	}).Infof("request context created on %s: %s", ic.ChainID, reqCtxID)
 
-	requests, err := ic.ServiceClient.QueryRequestsByReqCtx(reqCtxID, 1)
+	var requests []service.QueryServiceRequestResponse
+
+	err = common.Retry(ctx, func() error {
+		var reqs []service.QueryServiceRequestResponse
+
+		err := common.CallWithTimeout(ctx, ic.RequestTimeout, func() (err error) {
+			reqs, err = ic.ServiceClient.QueryRequestsByReqCtx(reqCtxID, 1)
+			return
+		})
+		if err != nil {
+			logger.Warnf("failed to query the service requests of %s: %s", reqCtxID, err)
+			return err
+		}
+
+		requests = reqs
+
+		return nil
+	}, ic.RetryPolicy)
 	if err != nil {
 		return err
 	}
@@ -174,17 +306,49 @@ func (ic IritaHubChain) SendInterchainRequest(
 	// TODO
 	mysql.OnInterchainRequestSent(request.ID, requests[0].ID, resTx.Hash)
 
-	logging.Logger.Infof("service request initiated on %s: %s", ic.ChainID, requests[0].ID)
+	logger.WithField(logging.FieldHubRequestID, requests[0].ID).Infof("service request initiated on %s", ic.ChainID)
+
+	if sent != nil {
+		sent(reqCtxID, requests[0].ID)
+	}
 
 	return ic.ResponseListener(reqCtxID, requests[0].ID, cb)
 }
 
+// ResumeInterchainRequest implements IritaHubChainI
+func (ic IritaHubChain) ResumeInterchainRequest(reqCtxID string, icRequestID string, cb core.ResponseCallback) error {
+	return ic.ResponseListener(reqCtxID, icRequestID, cb)
+}
+
+// CheckConnection implements IritaHubChainI
+func (ic IritaHubChain) CheckConnection(ctx context.Context) error {
+	statusCtx, cancel := common.WithTimeout(ctx, ic.RequestTimeout)
+	defer cancel()
+
+	if _, err := ic.ServiceClient.Status(statusCtx); err != nil {
+		return fmt.Errorf("failed to connect to %s: %s", ic.ChainID, err)
+	}
+
+	if err := ic.CheckAccounts(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // BuildServiceInvocationRequest builds the service invocation request from the given interchain request
 func (ic IritaHubChain) BuildServiceInvocationRequest(
 	request core.InterchainRequest,
 ) (service.InvokeServiceRequest, error) {
 	serviceFeeCap, err := types.ParseDecCoins(ic.ServiceInfo.ServiceFee)
-	destID := common.GetDestID(request.DestChainType, request.DestSubChainID, request.DestChainID)
+	if err != nil {
+		return service.InvokeServiceRequest{}, err
+	}
+
+	destID, err := common.NewDestID(request.DestChainType, request.DestSubChainID, request.DestChainID)
+	if err != nil {
+		return service.InvokeServiceRequest{}, fmt.Errorf("invalid dest chain params: %s", err)
+	}
 
 	input := ServiceInput{
 		Header: Header{
@@ -198,7 +362,7 @@ func (ic IritaHubChain) BuildServiceInvocationRequest(
 				TxHash: request.TxHash,
 			},
 			Dest: Dest{
-				ID:              destID,
+				ID:              destID.String(),
 				ChainID:         request.DestChainID,
 				SubChainID:      request.DestSubChainID,
 				EndpointType:    request.EndpointType,
@@ -214,8 +378,14 @@ func (ic IritaHubChain) BuildServiceInvocationRequest(
 		return service.InvokeServiceRequest{}, err
 	}
 
+	// the routed requests may invoke another service
+	serviceName := ic.ServiceInfo.ServiceName
+	if len(request.ServiceName) != 0 {
+		serviceName = request.ServiceName
+	}
+
 	return service.InvokeServiceRequest{
-		ServiceName:   ic.ServiceInfo.ServiceName,
+		ServiceName:   serviceName,
 		Providers:     []string{ic.ServiceInfo.Provider},
 		Input:         string(serviceInput),
 		Timeout:       100,
@@ -224,28 +394,29 @@ func (ic IritaHubChain) BuildServiceInvocationRequest(
 }
 
 // ResponseListener gets and handles the response of the given request context ID by event subscription
+// The callback is called once: with the response, or with an ErrNoResponse cause once the request
+// context completes or the request expires without any, or the state of the request can not be queried
 func (ic IritaHubChain) ResponseListener(reqCtxID string, requestID string, cb core.ResponseCallback) error {
-	response, err := ic.ServiceClient.QueryServiceResponse(requestID)
-	if response.RequestContextID == reqCtxID {
-		resp := core.ResponseAdaptor{
-			StatusCode: 200,
-			Result:     response.Result,
-			Output:     response.Output,
-		}
-
-		cb(requestID, resp)
+	// the subscription and the poller may both see the response
+	var once sync.Once
+	deliver := func(response core.ResponseI, err error) {
+		once.Do(func() { cb(requestID, response, err) })
+	}
 
+	if response, ok := ic.queryResponse(reqCtxID, requestID); ok {
+		deliver(response, nil)
 		return nil
 	}
 
 	callbackWrapper := func(reqCtxID, requestID, result string, response string) {
 		resp := core.ResponseAdaptor{
-			StatusCode: 200,
-			Result:     result,
-			Output:     response,
+			StatusCode:  200,
+			ServiceName: ic.ServiceInfo.ServiceName,
+			Result:      result,
+			Output:      response,
 		}
 
-		cb(requestID, resp)
+		deliver(resp, nil)
 	}
 
 	logging.Logger.Infof("waiting for the service response on %s", ic.ChainID)
@@ -257,20 +428,109 @@ func (ic IritaHubChain) ResponseListener(reqCtxID string, requestID string, cb c
 
 	go func() {
 		for {
-			reqCtx, err := ic.ServiceClient.QueryRequestContext(reqCtxID)
-			status, err2 := ic.ServiceClient.Status(context.Background())
-			req, err3 := ic.ServiceClient.QueryServiceRequest(requestID)
-			if err != nil || err2 != nil || err3 != nil || reqCtx.BatchState == "BATCH_COMPLETED" || status.SyncInfo.LatestBlockHeight > req.ExpirationHeight {
-				logging.Logger.Infof("HUB Unsubscribe RequestID is %s", requestID)
-				_ = ic.ServiceClient.Unsubscribe(subscription)
-				break
+			completed, expirationHeight, expired, err := ic.queryRequestState(reqCtxID, requestID)
+
+			// a hung query or a transient failure is retried on the next round instead of ending the subscription
+			if err != nil && common.IsRetryableError(err) {
+				logging.Logger.Warnf("failed to query the state of the request %s: %s", requestID, err)
+				time.Sleep(time.Second)
+				continue
+			}
+
+			if err == nil && !completed && !expired {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			logging.Logger.Infof("HUB Unsubscribe RequestID is %s", requestID)
+			_ = ic.ServiceClient.Unsubscribe(subscription)
+
+			// the response may have landed since the last event
+			if response, ok := ic.queryResponse(reqCtxID, requestID); ok {
+				deliver(response, nil)
+				return
+			}
+
+			switch {
+			case err != nil:
+				deliver(nil, fmt.Errorf("%w: failed to query the state of the request %s: %s", core.ErrNoResponse, requestID, err))
+			case completed:
+				deliver(nil, fmt.Errorf("%w: request context %s completed without the response of %s", core.ErrNoResponse, reqCtxID, requestID))
+			default:
+				deliver(nil, fmt.Errorf("%w: request %s expired at height %d", core.ErrNoResponse, requestID, expirationHeight))
 			}
-			time.Sleep(time.Second)
+
+			return
 		}
 	}()
 	return nil
 }
 
+// queryResponse returns the response of the given request, false if there is none yet
+func (ic IritaHubChain) queryResponse(reqCtxID string, requestID string) (core.ResponseI, bool) {
+	var response service.QueryServiceResponseResponse
+
+	// the response is only taken on completion, since a timed out call keeps running
+	err := common.CallWithTimeout(context.Background(), ic.RequestTimeout, func() error {
+		resp, err := ic.ServiceClient.QueryServiceResponse(requestID)
+		if err != nil {
+			return err
+		}
+
+		response = resp
+
+		return nil
+	})
+
+	if err != nil || response.RequestContextID != reqCtxID {
+		return nil, false
+	}
+
+	return core.ResponseAdaptor{
+		StatusCode:  200,
+		ServiceName: ic.ServiceInfo.ServiceName,
+		Result:      response.Result,
+		Output:      response.Output,
+	}, true
+}
+
+// queryRequestState returns whether the request context is completed, and the expiration height
+// of the request along with whether the Hub is past it
+func (ic IritaHubChain) queryRequestState(reqCtxID string, requestID string) (completed bool, expirationHeight int64, expired bool, err error) {
+	// the results are only taken on completion, since a timed out call keeps running
+	var (
+		reqCtx       service.QueryRequestContextResp
+		latestHeight int64
+		req          service.QueryServiceRequestResponse
+	)
+
+	err = common.CallWithTimeout(context.Background(), ic.RequestTimeout, func() error {
+		ctx, qerr := ic.ServiceClient.QueryRequestContext(reqCtxID)
+		if qerr != nil {
+			return qerr
+		}
+
+		st, err := ic.ServiceClient.Status(context.Background())
+		if err != nil {
+			return err
+		}
+
+		r, qerr := ic.ServiceClient.QueryServiceRequest(requestID)
+		if qerr != nil {
+			return qerr
+		}
+
+		reqCtx, latestHeight, req = ctx, st.SyncInfo.LatestBlockHeight, r
+
+		return nil
+	})
+	if err != nil {
+		return false, 0, false, err
+	}
+
+	return reqCtx.BatchState == "BATCH_COMPLETED", req.ExpirationHeight, latestHeight > req.ExpirationHeight, nil
+}
+
 // BuildBaseTx builds a base tx
 func (ic IritaHubChain) BuildBaseTx() types.BaseTx {
 	return types.BaseTx{
@@ -1,6 +1,9 @@
 package hub
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/viper"
 
 	"github.com/irisnet/service-sdk-go/types"
@@ -9,6 +12,9 @@ import (
 	cfg "relayer/config"
 )
 
+// DestID identifies the Irita-Hub in the key store
+const DestID = cmn.DestID("irita-hub")
+
 // default config variables
 var (
 	defaultChainID       = "irita-hub"
@@ -27,49 +33,170 @@ var (
 )
 
 const (
-	Prefix       = "hub"
-	ServicePrefix= "service"
-	ChainID      = "chain_id"
-	NodeRPCAddr  = "node_rpc_addr"
-	NodeGRPCAddr = "node_grpc_addr"
-	KeyPath      = "key_path"
-	KeyName      = "key_name"
-	Passphrase   = "passphrase"
-	ServiceName  = "service_name"
-	Schemas      = "schemas"
-	Provider     = "provider"
-	ServiceFee   = "service_fee"
-	QoS          = "qos"
+	Prefix        = "hub"
+	ServicePrefix = "service"
+	ChainID       = "chain_id"
+	NodeRPCAddr   = "node_rpc_addr"
+	NodeGRPCAddr  = "node_grpc_addr"
+	KeyPath       = "key_path"
+	KeyName       = "key_name"
+	Passphrase    = "passphrase"
+	ServiceName   = "service_name"
+	Schemas       = "schemas"
+	Provider      = "provider"
+	ServiceFee    = "service_fee"
+	QoS           = "qos"
+
+	FeePrefix     = "fee"
+	GasPrice      = "gas_price"
+	MinGasPrice   = "min_gas_price"
+	GasMultiplier = "gas_multiplier"
+	MaxFee        = "max_fee"
+	DefaultGas    = "default_gas"
+	DefaultFee    = "default_fee"
+
+	Accounts        = "accounts"
+	AccountStrategy = "account_strategy"
+	MinBalance      = "min_balance"
+
+	BalanceAlertPrefix = "balance_alert"
+	PollInterval       = "interval"
+	Threshold          = "threshold"
 )
 
+// Account is an additional signing account of the relayer
+type Account struct {
+	KeyName    string `mapstructure:"key_name"`
+	Passphrase string `mapstructure:"passphrase"`
+}
+
 // Config is a config struct for IRITA-HUB
 type Config struct {
-	ChainID      string `yaml:"chain_id"`
-	NodeRPCAddr  string `yaml:"node_rpc_addr"`
-	NodeGRPCAddr string `yaml:"node_grpc_addr"`
-	KeyPath      string `yaml:"key_path"`
-	KeyName      string `yaml:"key_name"`
-	Passphrase   string `yaml:"passphrase"`
-	ServiceName  string `yaml:"chain_id"`// service name
-	Schemas      string `yaml:"chain_id"` // input and output schemas
-	Provider     string `yaml:"chain_id"` // service provider
-	ServiceFee   string `yaml:"chain_id"` // service fee
-	QoS          uint64 `yaml:"chain_id"`  // quality of service, in terms of the minimum response time
+	ChainID        string `yaml:"chain_id"`
+	NodeRPCAddr    string `yaml:"node_rpc_addr"`
+	NodeGRPCAddr   string `yaml:"node_grpc_addr"`
+	KeyPath        string `yaml:"key_path"`
+	KeyName        string `yaml:"key_name"`
+	Passphrase     string `yaml:"passphrase"`
+	ServiceName    string `yaml:"chain_id"` // service name
+	Schemas        string `yaml:"chain_id"` // input and output schemas
+	Provider       string `yaml:"chain_id"` // service provider
+	ServiceFee     string `yaml:"chain_id"` // service fee
+	QoS            uint64 `yaml:"chain_id"` // quality of service, in terms of the minimum response time
+	RetryPolicy    cmn.RetryPolicy
+	SubmitLimits   cmn.SubmitLimits
+	RequestTimeout time.Duration // deadline of a single RPC call
+
+	ExtraAccounts   []Account // signing accounts besides the key, picked from the key DAO
+	AccountStrategy string    // selection strategy of the signing accounts
+	MinBalance      string    // minimum balance for an account to be selected, in the min denom
+
+	BalancePollInterval time.Duration // interval between two balance polls of the signing accounts
+	BalanceThreshold    string        // balance below which an alert is raised, no alert if empty
+
+	Fee *FeeConfig // fee estimation params, the fixed default fee is paid if nil
+}
+
+// SigningAccounts returns all the signing accounts, led by the key
+func (c Config) SigningAccounts() []Account {
+	return append([]Account{{KeyName: c.KeyName, Passphrase: c.Passphrase}}, c.ExtraAccounts...)
 }
 
 // NewConfig constructs a new Config from viper
-func NewConfig(v *viper.Viper) Config {
+func NewConfig(v *viper.Viper) (Config, error) {
+	var accounts []Account
+	if err := v.UnmarshalKey(cfg.GetConfigKey(Prefix, Accounts), &accounts); err != nil {
+		return Config{}, fmt.Errorf("invalid hub accounts: %s", err)
+	}
+
+	fee, err := loadFeeConfig(v)
+	if err != nil {
+		return Config{}, err
+	}
+
 	return Config{
-		ChainID:      v.GetString(cfg.GetConfigKey(Prefix, ChainID)),
-		NodeRPCAddr:  v.GetString(cfg.GetConfigKey(Prefix, NodeRPCAddr)),
-		NodeGRPCAddr: v.GetString(cfg.GetConfigKey(Prefix, NodeGRPCAddr)),
-		KeyPath:      v.GetString(cfg.GetConfigKey(Prefix, KeyPath)),
-		KeyName:      v.GetString(cfg.GetConfigKey(Prefix, KeyName)),
-		Passphrase:   v.GetString(cfg.GetConfigKey(Prefix, Passphrase)),
-		ServiceName:  v.GetString(cfg.GetConfigKey(ServicePrefix, ServiceName)),
-		Schemas:      v.GetString(cfg.GetConfigKey(ServicePrefix, Schemas)),
-		Provider:     v.GetString(cfg.GetConfigKey(ServicePrefix, Provider)),
-		ServiceFee:   v.GetString(cfg.GetConfigKey(ServicePrefix, ServiceFee)),
-		QoS:          v.GetUint64(cfg.GetConfigKey(ServicePrefix, QoS)),
+		ChainID:        v.GetString(cfg.GetConfigKey(Prefix, ChainID)),
+		NodeRPCAddr:    v.GetString(cfg.GetConfigKey(Prefix, NodeRPCAddr)),
+		NodeGRPCAddr:   v.GetString(cfg.GetConfigKey(Prefix, NodeGRPCAddr)),
+		KeyPath:        v.GetString(cfg.GetConfigKey(Prefix, KeyPath)),
+		KeyName:        v.GetString(cfg.GetConfigKey(Prefix, KeyName)),
+		Passphrase:     v.GetString(cfg.GetConfigKey(Prefix, Passphrase)),
+		ServiceName:    v.GetString(cfg.GetConfigKey(ServicePrefix, ServiceName)),
+		Schemas:        v.GetString(cfg.GetConfigKey(ServicePrefix, Schemas)),
+		Provider:       v.GetString(cfg.GetConfigKey(ServicePrefix, Provider)),
+		ServiceFee:     v.GetString(cfg.GetConfigKey(ServicePrefix, ServiceFee)),
+		QoS:            v.GetUint64(cfg.GetConfigKey(ServicePrefix, QoS)),
+		RetryPolicy:    cfg.LoadRetryPolicy(v, Prefix),
+		SubmitLimits:   cfg.LoadSubmitLimits(v, Prefix),
+		RequestTimeout: cfg.LoadRequestTimeout(v, Prefix),
+
+		ExtraAccounts:   accounts,
+		AccountStrategy: v.GetString(cfg.GetConfigKey(Prefix, AccountStrategy)),
+		MinBalance:      v.GetString(cfg.GetConfigKey(Prefix, MinBalance)),
+
+		BalancePollInterval: v.GetDuration(cfg.GetConfigKey(Prefix, cfg.GetConfigKey(BalanceAlertPrefix, PollInterval))),
+		BalanceThreshold:    v.GetString(cfg.GetConfigKey(Prefix, cfg.GetConfigKey(BalanceAlertPrefix, Threshold))),
+
+		Fee: fee,
+	}, nil
+}
+
+// loadFeeConfig loads the fee estimation params
+// nil is returned if no gas price is configured
+func loadFeeConfig(v *viper.Viper) (*FeeConfig, error) {
+	key := func(k string) string {
+		return cfg.GetConfigKey(Prefix, cfg.GetConfigKey(FeePrefix, k))
 	}
+
+	if len(v.GetString(key(GasPrice))) == 0 {
+		return nil, nil
+	}
+
+	gasPrice, err := types.ParseDecCoin(v.GetString(key(GasPrice)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gas price: %s", err)
+	}
+
+	config := &FeeConfig{
+		GasPrice:      gasPrice,
+		GasMultiplier: DefaultGasMultiplier,
+		DefaultFee:    Fee{Gas: defaultGas},
+	}
+
+	if v.IsSet(key(GasMultiplier)) {
+		config.GasMultiplier = v.GetFloat64(key(GasMultiplier))
+	}
+
+	if minGasPrice := v.GetString(key(MinGasPrice)); len(minGasPrice) != 0 {
+		if config.MinGasPrice, err = types.ParseDecCoin(minGasPrice); err != nil {
+			return nil, fmt.Errorf("invalid min gas price: %s", err)
+		}
+	}
+
+	if maxFee := v.GetString(key(MaxFee)); len(maxFee) != 0 {
+		if config.MaxFee, err = types.ParseDecCoin(maxFee); err != nil {
+			return nil, fmt.Errorf("invalid max fee: %s", err)
+		}
+	}
+
+	if v.IsSet(key(DefaultGas)) {
+		config.DefaultFee.Gas = v.GetUint64(key(DefaultGas))
+	}
+
+	defaultFeeStr := defaultFee
+	if fee := v.GetString(key(DefaultFee)); len(fee) != 0 {
+		defaultFeeStr = fee
+	}
+
+	if config.DefaultFee.Amount, err = types.ParseDecCoin(defaultFeeStr); err != nil {
+		return nil, fmt.Errorf("invalid default fee: %s", err)
+	}
+
+	config.DefaultFee.Estimated = config.DefaultFee.Amount
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
 }
@@ -0,0 +1,151 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/irisnet/service-sdk-go/types"
+
+	"relayer/logging"
+)
+
+// DefaultGasMultiplier is the default safety margin applied to the simulated gas
+const DefaultGasMultiplier = 1.2
+
+// Tx is a tx to be signed by the given account with the explicit sequence
+type Tx struct {
+	Sender        string
+	AccountNumber uint64
+	Sequence      uint64
+	Msgs          []types.Msg
+	BaseTx        types.BaseTx
+}
+
+// Fee is the gas and fee paid by a tx
+type Fee struct {
+	Gas       uint64
+	Amount    types.DecCoin // fee paid, capped by the max fee
+	Estimated types.DecCoin // fee estimated before capping
+}
+
+// FeeEstimator defines the interface to estimate the fee of a tx
+type FeeEstimator interface {
+	Estimate(ctx context.Context, tx Tx) (Fee, error)
+}
+
+// GasSimulator simulates the given tx and returns the gas used
+type GasSimulator func(tx Tx) (uint64, error)
+
+// FeeConfig defines the fee estimation params
+type FeeConfig struct {
+	GasPrice      types.DecCoin // price of a gas unit
+	MinGasPrice   types.DecCoin // lowest price of a gas unit accepted by the node, the gas price if zero
+	GasMultiplier float64       // safety margin applied to the simulated gas
+	MaxFee        types.DecCoin // upper bound of the fee, unbounded if zero
+	DefaultFee    Fee           // fee used if the simulation fails
+}
+
+// Validate validates the fee config
+func (c FeeConfig) Validate() error {
+	if c.GasPrice.Amount.IsNil() || !c.GasPrice.IsValid() {
+		return fmt.Errorf("invalid gas price %s", c.GasPrice)
+	}
+
+	if c.GasMultiplier < 1 {
+		return fmt.Errorf("gas multiplier must be no less than 1, got %v", c.GasMultiplier)
+	}
+
+	if c.capped() && c.MaxFee.Denom != c.GasPrice.Denom {
+		return fmt.Errorf("max fee %s and gas price %s in different denoms", c.MaxFee, c.GasPrice)
+	}
+
+	if !c.MinGasPrice.Amount.IsNil() && !c.MinGasPrice.IsZero() {
+		if c.MinGasPrice.Denom != c.GasPrice.Denom {
+			return fmt.Errorf("min gas price %s and gas price %s in different denoms", c.MinGasPrice, c.GasPrice)
+		}
+
+		if c.MinGasPrice.Amount.GT(c.GasPrice.Amount) {
+			return fmt.Errorf("min gas price %s above the gas price %s", c.MinGasPrice, c.GasPrice)
+		}
+	}
+
+	return nil
+}
+
+// minGasPrice returns the lowest price of a gas unit accepted by the node
+func (c FeeConfig) minGasPrice() types.DecCoin {
+	if c.MinGasPrice.Amount.IsNil() || c.MinGasPrice.IsZero() {
+		return c.GasPrice
+	}
+
+	return c.MinGasPrice
+}
+
+// capped returns true if the max fee is set
+func (c FeeConfig) capped() bool {
+	return !c.MaxFee.Amount.IsNil() && !c.MaxFee.IsZero()
+}
+
+// SimulationFeeEstimator estimates the fee by simulating the tx on the node
+type SimulationFeeEstimator struct {
+	config   FeeConfig
+	simulate GasSimulator
+}
+
+var _ FeeEstimator = SimulationFeeEstimator{}
+
+// NewSimulationFeeEstimator constructs a new SimulationFeeEstimator instance
+func NewSimulationFeeEstimator(config FeeConfig, simulate GasSimulator) (SimulationFeeEstimator, error) {
+	if err := config.Validate(); err != nil {
+		return SimulationFeeEstimator{}, err
+	}
+
+	return SimulationFeeEstimator{
+		config:   config,
+		simulate: simulate,
+	}, nil
+}
+
+// Estimate implements FeeEstimator
+// The simulated gas is scaled by the multiplier and priced by the gas price, the fee
+// being capped by the max fee; the default fee is returned if the simulation fails
+// The simulated gas is expected unadjusted, the multiplier being the only safety margin
+// An error is returned if the capped fee prices the gas below the min gas price, as the
+// node would reject the tx
+func (e SimulationFeeEstimator) Estimate(ctx context.Context, tx Tx) (Fee, error) {
+	gasUsed, err := e.simulate(tx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Fee{}, ctx.Err()
+		}
+
+		logging.Logger.Warnf("failed to simulate the tx, falling back to the default fee %s: %s", e.config.DefaultFee.Amount, err)
+
+		return e.config.DefaultFee, nil
+	}
+
+	gas := uint64(math.Ceil(float64(gasUsed) * e.config.GasMultiplier))
+
+	estimated := types.NewDecCoinFromDec(e.config.GasPrice.Denom, e.config.GasPrice.Amount.MulInt64(int64(gas)))
+
+	fee := Fee{
+		Gas:       gas,
+		Amount:    estimated,
+		Estimated: estimated,
+	}
+
+	if e.config.capped() && estimated.Amount.GT(e.config.MaxFee.Amount) {
+		minFee := e.config.minGasPrice().Amount.MulInt64(int64(gas))
+		if e.config.MaxFee.Amount.LT(minFee) {
+			return Fee{}, fmt.Errorf(
+				"max fee %s below the min fee %s%s of %d gas at the min gas price %s",
+				e.config.MaxFee, minFee, e.config.GasPrice.Denom, gas, e.config.minGasPrice(),
+			)
+		}
+
+		fee.Amount = e.config.MaxFee
+	}
+
+	return fee, nil
+}
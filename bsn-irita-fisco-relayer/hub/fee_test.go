@@ -0,0 +1,125 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/irisnet/service-sdk-go/types"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseDecCoin(t *testing.T, coin string) types.DecCoin {
+	c, err := types.ParseDecCoin(coin)
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestSimulationFeeEstimator(t *testing.T) {
+	config := FeeConfig{
+		GasPrice:      mustParseDecCoin(t, "0.00002point"),
+		MinGasPrice:   mustParseDecCoin(t, "0.000005point"),
+		GasMultiplier: 1.5,
+		MaxFee:        mustParseDecCoin(t, "10point"),
+		DefaultFee:    Fee{Gas: 200000, Amount: mustParseDecCoin(t, "4point")},
+	}
+
+	gasUsed := uint64(100000)
+
+	estimator, err := NewSimulationFeeEstimator(config, func(tx Tx) (uint64, error) {
+		return gasUsed, nil
+	})
+	require.NoError(t, err)
+
+	fee, err := estimator.Estimate(context.Background(), Tx{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(150000), fee.Gas)
+	require.Equal(t, "3.000000000000000000point", fee.Amount.String())
+	require.Equal(t, fee.Estimated, fee.Amount)
+
+	// the fee spike is bounded by the cap
+	gasUsed = 1000000
+
+	fee, err = estimator.Estimate(context.Background(), Tx{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1500000), fee.Gas)
+	require.Equal(t, "30.000000000000000000point", fee.Estimated.String())
+	require.Equal(t, config.MaxFee, fee.Amount)
+
+	// the cap pricing the gas below the min gas price is rejected
+	config.MinGasPrice = mustParseDecCoin(t, "0.00001point")
+
+	estimator, err = NewSimulationFeeEstimator(config, func(tx Tx) (uint64, error) {
+		return gasUsed, nil
+	})
+	require.NoError(t, err)
+
+	_, err = estimator.Estimate(context.Background(), Tx{})
+	require.Error(t, err)
+
+	gasUsed = 500000
+
+	fee, err = estimator.Estimate(context.Background(), Tx{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(750000), fee.Gas)
+	require.Equal(t, config.MaxFee, fee.Amount)
+
+	// the min gas price defaults to the gas price, so that any capped fee is rejected
+	config.MinGasPrice = types.DecCoin{}
+
+	estimator, err = NewSimulationFeeEstimator(config, func(tx Tx) (uint64, error) {
+		return gasUsed, nil
+	})
+	require.NoError(t, err)
+
+	_, err = estimator.Estimate(context.Background(), Tx{})
+	require.Error(t, err)
+}
+
+func TestSimulationFeeEstimatorFallback(t *testing.T) {
+	config := FeeConfig{
+		GasPrice:      mustParseDecCoin(t, "0.00002point"),
+		GasMultiplier: DefaultGasMultiplier,
+		DefaultFee:    Fee{Gas: 200000, Amount: mustParseDecCoin(t, "4point")},
+	}
+
+	estimator, err := NewSimulationFeeEstimator(config, func(tx Tx) (uint64, error) {
+		return 0, errors.New("connection refused")
+	})
+	require.NoError(t, err)
+
+	fee, err := estimator.Estimate(context.Background(), Tx{})
+	require.NoError(t, err)
+	require.Equal(t, config.DefaultFee, fee)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = estimator.Estimate(ctx, Tx{})
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestFeeConfigValidate(t *testing.T) {
+	config := FeeConfig{GasMultiplier: DefaultGasMultiplier}
+	require.Error(t, config.Validate())
+
+	config.GasPrice = mustParseDecCoin(t, "0.00002point")
+	require.NoError(t, config.Validate())
+
+	config.MaxFee = mustParseDecCoin(t, "10stake")
+	require.Error(t, config.Validate())
+
+	config.MaxFee = mustParseDecCoin(t, "10point")
+	config.MinGasPrice = mustParseDecCoin(t, "0.00003point")
+	require.Error(t, config.Validate())
+
+	config.MinGasPrice = mustParseDecCoin(t, "0.00001stake")
+	require.Error(t, config.Validate())
+
+	config.MinGasPrice = mustParseDecCoin(t, "0.00001point")
+	require.NoError(t, config.Validate())
+
+	config.GasMultiplier = 0.5
+	require.Error(t, config.Validate())
+}
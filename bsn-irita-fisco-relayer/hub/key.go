@@ -1,5 +1,17 @@
 package hub
 
+import (
+	"fmt"
+
+	"github.com/irisnet/service-sdk-go/crypto"
+	cryptoamino "github.com/irisnet/service-sdk-go/crypto/codec"
+	"github.com/irisnet/service-sdk-go/crypto/hd"
+	cryptotypes "github.com/irisnet/service-sdk-go/crypto/types"
+	"github.com/irisnet/service-sdk-go/types/store"
+
+	"relayer/keystore"
+)
+
 // AddKey implements KeyManager
 func (ic IritaHubChain) AddKey(name string, passphrase string) (addr string, mnemonic string, err error) {
 	return ic.ServiceClient.Insert(name, passphrase)
@@ -30,3 +42,53 @@ func (ic IritaHubChain) ExportKey(name string, passphrase string) (keyArmor stri
 func (ic IritaHubChain) RecoverKey(name string, passphrase string, mnemonic string) (addr string, err error) {
 	return ic.ServiceClient.Recover(name, passphrase, mnemonic)
 }
+
+// NewKeyDAO builds the key DAO holding the Irita-Hub signing key from the key store
+// The key is recovered into memory under the given name, so that no key file is written
+// nil is returned if the key store has no hub key, in which case the keyring is used
+func NewKeyDAO(keyStore keystore.KeyStore, name string, passphrase string, algo string) (store.KeyDAO, error) {
+	secret, err := keyStore.GetSecret(DestID)
+	if err == keystore.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer secret.Wipe()
+
+	if len(secret.Algo()) != 0 && secret.Algo() != algo {
+		return nil, fmt.Errorf("the hub requires a %s key, but found %s", algo, secret.Algo())
+	}
+
+	var privKey cryptotypes.PrivKey
+
+	if len(secret.PrivateKey()) != 0 {
+		signingAlgo, err := hd.NewSigningAlgoFromString(algo)
+		if err != nil {
+			return nil, err
+		}
+
+		privKey = signingAlgo.Generate()(secret.PrivateKey())
+	} else {
+		km, err := crypto.NewMnemonicKeyManager(secret.Mnemonic(), algo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover the hub key: %s", err)
+		}
+
+		_, privKey = km.Generate()
+	}
+
+	keyDAO := store.NewMemory(nil)
+
+	err = keyDAO.Write(name, passphrase, store.KeyInfo{
+		Name:         name,
+		PubKey:       cryptoamino.MarshalPubkey(privKey.PubKey()),
+		PrivKeyArmor: string(cryptoamino.MarshalPrivKey(privKey)),
+		Algo:         algo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keyDAO, nil
+}
@@ -0,0 +1,312 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/irisnet/service-sdk-go/service"
+	"github.com/irisnet/service-sdk-go/types"
+	log "github.com/sirupsen/logrus"
+
+	"relayer/common"
+)
+
+const (
+	// attributeKeyRequestContextID is the event attribute holding the request context ID
+	attributeKeyRequestContextID = "request_context_id"
+
+	// eventTypeTx and attributeKeyAccountSequence index the txs by the signer address and sequence
+	eventTypeTx                 = "tx"
+	attributeKeyAccountSequence = "acc_seq"
+
+	// maxBroadcastAttempts is the number of broadcasts of a tx at the same sequence
+	maxBroadcastAttempts = 3
+
+	// broadcastLookupDelay is the delay before looking a timed out tx up, leaving it a few blocks to land
+	broadcastLookupDelay = 5 * time.Second
+)
+
+// invocation is the result of a service invocation tx
+type invocation struct {
+	reqCtxID string
+	tx       types.ResultTx
+}
+
+// querySequence implements common.SequenceFetcher, the account being identified by the key name
+// The account number is cached for the subsequent txs
+func (ic IritaHubChain) querySequence(keyName string) (uint64, error) {
+	signer, err := ic.signer(keyName)
+	if err != nil {
+		return 0, err
+	}
+
+	account, err := ic.ServiceClient.QueryAccount(signer.Address())
+	if err != nil {
+		return 0, err
+	}
+
+	ic.accountNumbers.Store(keyName, account.AccountNumber)
+
+	return account.Sequence, nil
+}
+
+// broadcastInvocation invokes the service with the given sequence of the signing account
+// and waits for its confirmation. A timed out broadcast may still land, so the tx is then
+// looked up by its sequence and only broadcast again at the same one if unused, the Hub
+// accepting a single tx per sequence. The outcome left unknown fails permanently, so that
+// the invocation is never retried at another sequence or by another account
+func (ic IritaHubChain) broadcastInvocation(
+	ctx context.Context,
+	request service.InvokeServiceRequest,
+	account string,
+	sequence uint64,
+	logger *log.Entry,
+) (invocation, error) {
+	broadcast := func() (invocation, error) {
+		var result invocation
+
+		// the results are only taken on completion, since a timed out call keeps running
+		err := common.CallWithTimeout(ctx, ic.RequestTimeout, func() error {
+			reqCtxID, tx, err := ic.invokeService(ctx, request, ic.buildAccountBaseTx(account), sequence, logger)
+			if err != nil {
+				return err
+			}
+
+			result = invocation{reqCtxID: reqCtxID, tx: tx}
+
+			return nil
+		})
+
+		return result, err
+	}
+
+	lookup := func() (invocation, bool, error) {
+		return ic.findInvocation(account, sequence)
+	}
+
+	return broadcastAtSequence(ctx, broadcast, lookup, broadcastLookupDelay, logger)
+}
+
+// broadcastAtSequence broadcasts a tx at a fixed sequence, see broadcastInvocation
+// The lookup returns false if the sequence is not used by any tx yet
+func broadcastAtSequence(
+	ctx context.Context,
+	broadcast func() (invocation, error),
+	lookup func() (invocation, bool, error),
+	delay time.Duration,
+	logger *log.Entry,
+) (invocation, error) {
+	result, err := broadcast()
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return result, err
+	}
+
+	// past a timeout, any failure may be caused by the earlier tx landing, e.g. a sequence mismatch
+	for attempt := 1; ; attempt++ {
+		logger.Warnf("broadcast of the service invocation tx failed: %s, looking it up", err)
+
+		// the lookup is not bound to the context, as the tx may have landed anyway
+		time.Sleep(delay)
+
+		result, found, lookupErr := lookup()
+		if lookupErr != nil {
+			return invocation{}, common.Permanent(fmt.Errorf("outcome of the timed out service invocation tx unknown: %s", lookupErr))
+		}
+
+		if found {
+			return result, nil
+		}
+
+		if attempt >= maxBroadcastAttempts || ctx.Err() != nil {
+			return invocation{}, common.Permanent(fmt.Errorf("service invocation tx timed out and not found after %d broadcasts", attempt))
+		}
+
+		if result, err = broadcast(); err == nil {
+			return result, nil
+		}
+	}
+}
+
+// findInvocation looks the service invocation tx of the account at the given sequence up,
+// false if the sequence is not used yet
+func (ic IritaHubChain) findInvocation(account string, sequence uint64) (invocation, bool, error) {
+	var (
+		next uint64
+		txs  types.ResultSearchTxs
+	)
+
+	signer, err := ic.signer(account)
+	if err != nil {
+		return invocation{}, false, err
+	}
+
+	// the results are only taken on completion, since a timed out call keeps running
+	err = common.CallWithTimeout(context.Background(), ic.RequestTimeout, func() error {
+		s, err := ic.querySequence(account)
+		if err != nil {
+			return err
+		}
+
+		if s <= sequence {
+			next = s
+			return nil
+		}
+
+		builder := types.NewEventQueryBuilder().AddCondition(
+			types.NewCond(eventTypeTx, attributeKeyAccountSequence).EQ(types.EventValue(fmt.Sprintf("%s/%d", signer.Address(), sequence))),
+		)
+
+		res, err := ic.ServiceClient.QueryTxs(builder, 1, 1)
+		if err != nil {
+			return err
+		}
+
+		next, txs = s, res
+
+		return nil
+	})
+	if err != nil {
+		return invocation{}, false, err
+	}
+
+	if next <= sequence {
+		return invocation{}, false, nil
+	}
+
+	if len(txs.Txs) == 0 {
+		return invocation{}, false, fmt.Errorf("sequence %d of %s used by a tx not found", sequence, account)
+	}
+
+	found := txs.Txs[0]
+
+	// the failed tx consumed the sequence without creating the request context
+	if found.Result.Code != 0 {
+		return invocation{}, false, fmt.Errorf("service invocation tx %s failed: %s", found.Hash, found.Result.Log)
+	}
+
+	reqCtxID, err := found.Result.Events.GetValue(types.EventTypeCreateContext, attributeKeyRequestContextID)
+	if err != nil {
+		return invocation{}, false, err
+	}
+
+	return invocation{
+		reqCtxID: reqCtxID,
+		tx: types.ResultTx{
+			GasWanted: found.Result.GasWanted,
+			GasUsed:   found.Result.GasUsed,
+			Events:    found.Result.Events,
+			Hash:      found.Hash,
+			Height:    found.Height,
+		},
+	}, true, nil
+}
+
+// invokeService invokes the service with the given sequence of the signing account
+// It mirrors ServiceClient.InvokeService, which manages the sequence on its own
+// The gas and fee are estimated if a fee estimator is set
+func (ic IritaHubChain) invokeService(
+	ctx context.Context,
+	request service.InvokeServiceRequest,
+	baseTx types.BaseTx,
+	sequence uint64,
+	logger *log.Entry,
+) (string, types.ResultTx, error) {
+	tx, err := ic.buildInvocationTx(request, baseTx, sequence)
+	if err != nil {
+		return "", types.ResultTx{}, err
+	}
+
+	if ic.FeeEstimator != nil {
+		fee, err := ic.FeeEstimator.Estimate(ctx, tx)
+		if err != nil {
+			return "", types.ResultTx{}, err
+		}
+
+		logger.Infof("fee of the tx: gas %d, estimated %s, capped %s", fee.Gas, fee.Estimated, fee.Amount)
+
+		tx.BaseTx.Gas = fee.Gas
+		tx.BaseTx.Fee = types.NewDecCoins(fee.Amount)
+	}
+
+	result, err := ic.sendTx(tx)
+	if err != nil {
+		return "", types.ResultTx{}, err
+	}
+
+	reqCtxID, e := result.Events.GetValue(types.EventTypeCreateContext, attributeKeyRequestContextID)
+	if e != nil {
+		return "", result, e
+	}
+
+	return reqCtxID, result, nil
+}
+
+// buildInvocationTx builds the service invocation tx signed by the account of the base tx
+func (ic IritaHubChain) buildInvocationTx(
+	request service.InvokeServiceRequest,
+	baseTx types.BaseTx,
+	sequence uint64,
+) (Tx, error) {
+	accountNumber, ok := ic.accountNumbers.Load(baseTx.From)
+	if !ok {
+		return Tx{}, fmt.Errorf("account number of the key %s unknown", baseTx.From)
+	}
+
+	consumer, err := ic.signer(baseTx.From)
+	if err != nil {
+		return Tx{}, err
+	}
+
+	serviceFeeCap, err := ic.ServiceClient.ToMinCoin(request.ServiceFeeCap...)
+	if err != nil {
+		return Tx{}, err
+	}
+
+	msg := &service.MsgCallService{
+		ServiceName:       request.ServiceName,
+		Providers:         request.Providers,
+		Consumer:          consumer.Address(),
+		Input:             request.Input,
+		ServiceFeeCap:     serviceFeeCap,
+		Timeout:           request.Timeout,
+		Repeated:          request.Repeated,
+		RepeatedFrequency: request.RepeatedFrequency,
+		RepeatedTotal:     request.RepeatedTotal,
+	}
+
+	if err := msg.ValidateBasic(); err != nil {
+		return Tx{}, err
+	}
+
+	return Tx{
+		Sender:        consumer.Address(),
+		AccountNumber: accountNumber.(uint64),
+		Sequence:      sequence,
+		Msgs:          []types.Msg{msg},
+		BaseTx:        baseTx,
+	}, nil
+}
+
+// sendTx signs and broadcasts the given tx
+func (ic IritaHubChain) sendTx(tx Tx) (types.ResultTx, error) {
+	result, err := ic.ServiceClient.BuildAndSendWithAccount(tx.Sender, tx.AccountNumber, tx.Sequence, tx.Msgs, tx.BaseTx)
+	if err != nil {
+		return types.ResultTx{}, err
+	}
+
+	return result, nil
+}
+
+// simulateGas implements GasSimulator
+func (ic IritaHubChain) simulateGas(tx Tx) (uint64, error) {
+	tx.BaseTx.Simulate = true
+
+	result, err := ic.sendTx(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(result.GasWanted), nil
+}
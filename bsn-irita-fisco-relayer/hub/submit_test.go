@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+	"relayer/logging"
+)
+
+func TestBroadcastAtSequence(t *testing.T) {
+	logger := logging.WithRequestID("req-1")
+	timeout := fmt.Errorf("request timed out after 1s: %w", context.DeadlineExceeded)
+
+	broadcasts := 0
+	broadcast := func(errs ...error) func() (invocation, error) {
+		return func() (invocation, error) {
+			err := errs[broadcasts]
+			broadcasts++
+
+			if err != nil {
+				return invocation{}, err
+			}
+
+			return invocation{reqCtxID: "ctx-1"}, nil
+		}
+	}
+
+	// the timed out tx landed, and is not broadcast again
+	result, err := broadcastAtSequence(context.Background(), broadcast(timeout), func() (invocation, bool, error) {
+		return invocation{reqCtxID: "ctx-0"}, true, nil
+	}, 0, logger)
+	require.NoError(t, err)
+	require.Equal(t, "ctx-0", result.reqCtxID)
+	require.Equal(t, 1, broadcasts)
+
+	// the sequence is unused, the tx is broadcast again at the same one
+	broadcasts = 0
+	result, err = broadcastAtSequence(context.Background(), broadcast(timeout, nil), func() (invocation, bool, error) {
+		return invocation{}, false, nil
+	}, 0, logger)
+	require.NoError(t, err)
+	require.Equal(t, "ctx-1", result.reqCtxID)
+	require.Equal(t, 2, broadcasts)
+
+	// the mismatch past a timeout is resolved by the lookup, never by another sequence
+	broadcasts = 0
+	lookups := 0
+	result, err = broadcastAtSequence(context.Background(), broadcast(timeout, errors.New("account sequence mismatch")), func() (invocation, bool, error) {
+		lookups++
+		return invocation{reqCtxID: "ctx-0"}, lookups == 2, nil
+	}, 0, logger)
+	require.NoError(t, err)
+	require.Equal(t, "ctx-0", result.reqCtxID)
+
+	// the unknown outcome is not retried
+	broadcasts = 0
+	_, err = broadcastAtSequence(context.Background(), broadcast(timeout), func() (invocation, bool, error) {
+		return invocation{}, false, errors.New("connection refused")
+	}, 0, logger)
+	require.True(t, common.IsPermanentError(err))
+	require.False(t, common.IsRetryableError(err))
+
+	broadcasts = 0
+	_, err = broadcastAtSequence(context.Background(), broadcast(timeout, timeout, timeout), func() (invocation, bool, error) {
+		return invocation{}, false, nil
+	}, 0, logger)
+	require.True(t, common.IsPermanentError(err))
+	require.Equal(t, maxBroadcastAttempts, broadcasts)
+
+	// the errors other than a timeout are returned as is
+	broadcasts = 0
+	_, err = broadcastAtSequence(context.Background(), broadcast(errors.New("account sequence mismatch")), nil, 0, logger)
+	require.True(t, common.IsSequenceMismatchError(err))
+}
@@ -0,0 +1,58 @@
+package keystore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	cfg "relayer/config"
+)
+
+const (
+	Prefix = "keystore"
+
+	Type    = "type"
+	Files   = "files"
+	Dir     = "dir"
+	EnvVars = "env_vars"
+
+	TypeFile = "file"
+	TypeEnv  = "env"
+
+	// fiscoKeyFile is the legacy FISCO private key file option
+	fiscoKeyFile = "fisco.priv_key_file"
+	fiscoType    = "fisco"
+)
+
+// NewKeyStore constructs the KeyStore selected by the config
+// The file KeyStore is used by default
+func NewKeyStore(v *viper.Viper) (KeyStore, error) {
+	keyStoreType := strings.ToLower(v.GetString(cfg.GetConfigKey(Prefix, Type)))
+
+	switch keyStoreType {
+	case "", TypeFile:
+		files := v.GetStringMapString(cfg.GetConfigKey(Prefix, Files))
+		if _, ok := files[fiscoType]; !ok && len(v.GetString(fiscoKeyFile)) != 0 {
+			files[fiscoType] = v.GetString(fiscoKeyFile)
+		}
+
+		dir := v.GetString(cfg.GetConfigKey(Prefix, Dir))
+		if len(dir) == 0 {
+			defaultDir, err := DefaultKeyDirPath()
+			if err != nil {
+				return nil, err
+			}
+
+			dir = defaultDir
+		}
+
+		return NewFileKeyStore(files, dir), nil
+
+	case TypeEnv:
+		return NewEnvKeyStore(v.GetStringMapString(cfg.GetConfigKey(Prefix, EnvVars))), nil
+
+	default:
+		return nil, fmt.Errorf("keystore type %s is not supported", keyStoreType)
+	}
+}
@@ -0,0 +1,134 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+
+	"relayer/common"
+)
+
+const (
+	// EnvKeyPrefix is the prefix of the default env var name of a chain key
+	EnvKeyPrefix = "RELAYER_KEY_"
+
+	privateKeyHexLength = 64
+)
+
+// EnvKeyStore is a KeyStore reading the keys from environment variables
+// A variable holds either a hex encoded private key or a mnemonic
+type EnvKeyStore struct {
+	vars map[string]string // env var name by dest ID or chain type
+}
+
+var _ KeyManager = (*EnvKeyStore)(nil)
+
+// NewEnvKeyStore constructs a new EnvKeyStore from the env var names
+// keyed by either the dest ID or the chain type
+func NewEnvKeyStore(vars map[string]string) *EnvKeyStore {
+	return &EnvKeyStore{
+		vars: vars,
+	}
+}
+
+// EnvVarName returns the name of the env var holding the key of the given chain
+// It defaults to RELAYER_KEY_<DEST_ID>, e.g. RELAYER_KEY_FISCO_1_1
+func (ks *EnvKeyStore) EnvVarName(destID common.DestID) string {
+	if name, ok := lookup(ks.vars, destID); ok && len(name) != 0 {
+		return name
+	}
+
+	return EnvKeyPrefix + strings.ToUpper(strings.Replace(destID.String(), common.DestIDDelimiter, "_", -1))
+}
+
+// GetSecret implements KeyStore
+func (ks *EnvKeyStore) GetSecret(destID common.DestID) (Secret, error) {
+	name := ks.EnvVarName(destID)
+
+	value, ok := os.LookupEnv(name)
+	if !ok || len(strings.TrimSpace(value)) == 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	secret, err := parseSecret(value)
+	if err != nil {
+		// the value is kept out of the error, which may be logged
+		return nil, fmt.Errorf("invalid key in the env var %s: %s", name, err)
+	}
+
+	return secret, nil
+}
+
+// List implements KeyManager
+// The names are those of the configured env vars which are set, and the dest IDs
+// of the set RELAYER_KEY_<DEST_ID> vars, lowercased
+func (ks *EnvKeyStore) List() ([]string, error) {
+	seen := make(map[string]bool)
+
+	for name, envVar := range ks.vars {
+		if len(strings.TrimSpace(os.Getenv(envVar))) != 0 {
+			seen[strings.ToLower(name)] = true
+		}
+	}
+
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], EnvKeyPrefix) || len(strings.TrimSpace(parts[1])) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimPrefix(parts[0], EnvKeyPrefix))
+		seen[strings.Replace(name, "_", common.DestIDDelimiter, -1)] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Add implements KeyManager
+// The environment of the relayer can not be written, so ErrReadOnly is returned
+func (ks *EnvKeyStore) Add(name string, privKey []byte, algo string) error {
+	return fmt.Errorf("%w: set the env var %s to the key instead", ErrReadOnly, ks.EnvVarName(common.DestID(name)))
+}
+
+// Delete implements KeyManager
+// The environment of the relayer can not be written, so ErrReadOnly is returned
+func (ks *EnvKeyStore) Delete(name string) error {
+	return fmt.Errorf("%w: unset the env var %s instead", ErrReadOnly, ks.EnvVarName(common.DestID(name)))
+}
+
+// parseSecret parses the hex encoded private key or the mnemonic
+func parseSecret(value string) (*memSecret, error) {
+	value = strings.TrimSpace(value)
+
+	if words := strings.Fields(value); len(words) > 1 {
+		mnemonic := strings.Join(words, " ")
+		if !bip39.IsMnemonicValid(mnemonic) {
+			return nil, fmt.Errorf("malformed mnemonic")
+		}
+
+		return newMnemonicSecret([]byte(mnemonic)), nil
+	}
+
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	if len(value) != privateKeyHexLength {
+		return nil, fmt.Errorf("expected a %d-character hex private key or a mnemonic", privateKeyHexLength)
+	}
+
+	privKey, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("malformed hex private key")
+	}
+
+	return newPrivateKeySecret(privKey, ""), nil
+}
@@ -0,0 +1,208 @@
+package keystore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/FISCO-BCOS/go-sdk/conf"
+
+	"relayer/common"
+)
+
+const (
+	// DefaultKeyDir is the key directory relative to the home directory
+	DefaultKeyDir = ".relayer/keys"
+
+	// keyFileExt is the extension of the key files in the key directory
+	keyFileExt = ".pem"
+)
+
+// FileKeyStore is a KeyStore reading the PEM private key files from disk
+// The keys are looked up in the configured files, then in the key directory
+// as <name>.pem, the dest ID taking precedence over the chain type. Chains
+// without a key file are reported as ErrKeyNotFound, so that the Irita-Hub
+// keeps using its keyring directory
+type FileKeyStore struct {
+	files map[string]string // key file path by dest ID or chain type
+	dir   string            // directory of the managed key files, none if empty
+}
+
+var _ KeyManager = (*FileKeyStore)(nil)
+
+// NewFileKeyStore constructs a new FileKeyStore from the key file paths
+// keyed by either the dest ID or the chain type, and the key directory
+func NewFileKeyStore(files map[string]string, dir string) *FileKeyStore {
+	return &FileKeyStore{
+		files: files,
+		dir:   dir,
+	}
+}
+
+// DefaultKeyDirPath returns the default key directory under the relayer home directory
+func DefaultKeyDirPath() (string, error) {
+	homeDir, err := common.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, DefaultKeyDir), nil
+}
+
+// GetSecret implements KeyStore
+func (ks *FileKeyStore) GetSecret(destID common.DestID) (Secret, error) {
+	path, ok := ks.keyFile(destID)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	keyBytes, curve, err := conf.LoadECPrivateKeyFromPEM(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the private key of %s from %s: %s", destID, path, err)
+	}
+
+	switch curve {
+	case "secp256k1":
+		return newPrivateKeySecret(keyBytes, AlgoSecp256k1), nil
+
+	case "sm2p256v1":
+		return newPrivateKeySecret(keyBytes, AlgoSM2), nil
+
+	default:
+		Zero(keyBytes)
+		return nil, fmt.Errorf("unsupported curve %s of the private key %s", curve, path)
+	}
+}
+
+// List implements KeyManager
+func (ks *FileKeyStore) List() ([]string, error) {
+	seen := make(map[string]bool)
+
+	for name, path := range ks.files {
+		if len(path) != 0 {
+			seen[strings.ToLower(name)] = true
+		}
+	}
+
+	if len(ks.dir) != 0 {
+		infos, err := ioutil.ReadDir(ks.dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read the key directory: %s", err)
+		}
+
+		for _, info := range infos {
+			if !info.IsDir() && strings.HasSuffix(info.Name(), keyFileExt) {
+				seen[strings.TrimSuffix(info.Name(), keyFileExt)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Add implements KeyManager
+// The key is written to the key directory, readable by the owner only
+func (ks *FileKeyStore) Add(name string, privKey []byte, algo string) error {
+	if err := ValidateKeyName(name); err != nil {
+		return err
+	}
+
+	name = strings.ToLower(name)
+
+	if len(ks.dir) == 0 {
+		return fmt.Errorf("%w: no key directory configured", ErrReadOnly)
+	}
+
+	if _, ok := ks.files[name]; ok {
+		return fmt.Errorf("key %s already exists in the keystore files", name)
+	}
+
+	bz, err := encodePrivateKeyPEM(privKey, algo)
+	if err != nil {
+		return err
+	}
+
+	defer Zero(bz)
+
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create the key directory: %s", err)
+	}
+
+	// the file is never overwritten
+	f, err := os.OpenFile(ks.dirFile(name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return fmt.Errorf("key %s already exists", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to create the key file: %s", err)
+	}
+
+	if _, err := f.Write(bz); err != nil {
+		f.Close()
+		os.Remove(ks.dirFile(name))
+
+		return fmt.Errorf("failed to write the key file: %s", err)
+	}
+
+	return f.Close()
+}
+
+// Delete implements KeyManager
+// Only the keys of the key directory are deleted, the configured files are left untouched
+func (ks *FileKeyStore) Delete(name string) error {
+	name = strings.ToLower(name)
+
+	if path, ok := ks.files[name]; ok && len(path) != 0 {
+		return fmt.Errorf("key %s is configured as the file %s, remove it from the config instead", name, path)
+	}
+
+	if len(ks.dir) == 0 {
+		return ErrKeyNotFound
+	}
+
+	if err := os.Remove(ks.dirFile(name)); os.IsNotExist(err) {
+		return ErrKeyNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete the key file: %s", err)
+	}
+
+	return nil
+}
+
+// keyFile returns the key file of the given chain, false if none is present
+func (ks *FileKeyStore) keyFile(destID common.DestID) (string, bool) {
+	for _, name := range []string{destID.String(), destID.ChainType()} {
+		name = strings.ToLower(name)
+		if len(name) == 0 {
+			continue
+		}
+
+		if path, ok := ks.files[name]; ok && len(path) != 0 {
+			return path, true
+		}
+
+		if len(ks.dir) == 0 {
+			continue
+		}
+
+		if info, err := os.Stat(ks.dirFile(name)); err == nil && !info.IsDir() {
+			return ks.dirFile(name), true
+		}
+	}
+
+	return "", false
+}
+
+// dirFile returns the path of the key file of the given name in the key directory
+func (ks *FileKeyStore) dirFile(name string) string {
+	return filepath.Join(ks.dir, name+keyFileExt)
+}
@@ -0,0 +1,139 @@
+package keystore
+
+import (
+	"errors"
+	"strings"
+
+	"relayer/common"
+)
+
+const (
+	// AlgoSecp256k1 is the secp256k1 key algorithm
+	AlgoSecp256k1 = "secp256k1"
+	// AlgoSM2 is the sm2 key algorithm
+	AlgoSM2 = "sm2"
+)
+
+var (
+	// ErrKeyNotFound is returned if no key is configured for the chain
+	ErrKeyNotFound = errors.New("signing key not found")
+	// ErrReadOnly is returned when writing the keys of a read-only KeyStore
+	ErrReadOnly = errors.New("keystore is read-only")
+)
+
+// KeyStore defines the interface to retrieve the signing keys of chains
+type KeyStore interface {
+	// GetSecret returns the key material of the given chain
+	// ErrKeyNotFound is returned if no key is configured for the chain
+	GetSecret(destID common.DestID) (Secret, error)
+}
+
+// KeyManager defines the interface to manage the keys of a KeyStore by name,
+// the name being the dest ID or the chain type the key applies to
+type KeyManager interface {
+	KeyStore
+
+	// List returns the names of the keys present, sorted
+	List() ([]string, error)
+
+	// Add stores the raw private key of the given algorithm under the name
+	// An error is returned if a key of the name exists
+	Add(name string, privKey []byte, algo string) error
+
+	// Delete removes the key of the name
+	// ErrKeyNotFound is returned if no key of the name exists
+	Delete(name string) error
+}
+
+// Secret holds the key material of a signing account
+// The caller should call Wipe once the key material is consumed
+type Secret interface {
+	// PrivateKey returns the raw private key, nil if the key is a mnemonic
+	PrivateKey() []byte
+
+	// Mnemonic returns the mnemonic, empty if the key is a raw private key
+	Mnemonic() string
+
+	// Algo returns the key algorithm, empty if unknown
+	Algo() string
+
+	// Wipe zeroes the key material
+	Wipe()
+}
+
+// memSecret is a Secret holding the key material in memory
+type memSecret struct {
+	privKey  []byte
+	mnemonic []byte
+	algo     string
+}
+
+var _ Secret = (*memSecret)(nil)
+
+// newPrivateKeySecret constructs a secret from the raw private key
+func newPrivateKeySecret(privKey []byte, algo string) *memSecret {
+	return &memSecret{
+		privKey: privKey,
+		algo:    algo,
+	}
+}
+
+// newMnemonicSecret constructs a secret from the mnemonic
+func newMnemonicSecret(mnemonic []byte) *memSecret {
+	return &memSecret{
+		mnemonic: mnemonic,
+	}
+}
+
+// PrivateKey implements Secret
+func (s *memSecret) PrivateKey() []byte {
+	return s.privKey
+}
+
+// Mnemonic implements Secret
+func (s *memSecret) Mnemonic() string {
+	return string(s.mnemonic)
+}
+
+// Algo implements Secret
+func (s *memSecret) Algo() string {
+	return s.algo
+}
+
+// Wipe implements Secret
+func (s *memSecret) Wipe() {
+	Zero(s.privKey)
+	Zero(s.mnemonic)
+
+	s.privKey = nil
+	s.mnemonic = nil
+}
+
+// String keeps the key material out of the logs
+func (s *memSecret) String() string {
+	return "secret{<redacted>}"
+}
+
+// GoString keeps the key material out of the logs
+func (s *memSecret) GoString() string {
+	return s.String()
+}
+
+// Zero overwrites the given bytes with zeros
+func Zero(bz []byte) {
+	for i := range bz {
+		bz[i] = 0
+	}
+}
+
+// lookup returns the entry of the given chain from the table keyed by
+// either the dest ID or the chain type, the dest ID taking precedence
+func lookup(table map[string]string, destID common.DestID) (string, bool) {
+	if v, ok := table[strings.ToLower(destID.String())]; ok {
+		return v, true
+	}
+
+	v, ok := table[strings.ToLower(destID.ChainType())]
+
+	return v, ok
+}
@@ -0,0 +1,147 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+const (
+	testDestID     = common.DestID("fisco-1-1")
+	testPrivKeyHex = "c87509a1c067bbde78beb793e6fa76530b6382a4c0241e5e4a9ec0a0f44dc0d3"
+	testMnemonic   = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+)
+
+func TestEnvVarName(t *testing.T) {
+	ks := NewEnvKeyStore(map[string]string{"irita-hub": "HUB_MNEMONIC", "eth": "ETH_KEY"})
+
+	require.Equal(t, "HUB_MNEMONIC", ks.EnvVarName("irita-hub"))
+	require.Equal(t, "ETH_KEY", ks.EnvVarName("eth-3"))
+	require.Equal(t, "RELAYER_KEY_FISCO_1_1", ks.EnvVarName(testDestID))
+}
+
+func TestEnvKeyStore(t *testing.T) {
+	ks := NewEnvKeyStore(nil)
+	name := ks.EnvVarName(testDestID)
+	defer os.Unsetenv(name)
+
+	_, err := ks.GetSecret(testDestID)
+	require.Equal(t, ErrKeyNotFound, err)
+
+	require.NoError(t, os.Setenv(name, "0x"+testPrivKeyHex))
+	secret, err := ks.GetSecret(testDestID)
+	require.NoError(t, err)
+	require.Equal(t, testPrivKeyHex, fmt.Sprintf("%x", secret.PrivateKey()))
+	require.Empty(t, secret.Mnemonic())
+
+	require.NoError(t, os.Setenv(name, " "+testMnemonic+"\n"))
+	secret, err = ks.GetSecret(testDestID)
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, secret.Mnemonic())
+	require.Nil(t, secret.PrivateKey())
+
+	for _, invalid := range []string{"c875", "zz" + testPrivKeyHex[2:], "abandon about"} {
+		require.NoError(t, os.Setenv(name, invalid))
+		_, err = ks.GetSecret(testDestID)
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), invalid)
+	}
+}
+
+func TestSecretWipe(t *testing.T) {
+	privKey := []byte{1, 2, 3}
+	secret := newPrivateKeySecret(privKey, AlgoSM2)
+
+	require.NotContains(t, fmt.Sprintf("%v %+v %#v", secret, secret, secret), "\x01")
+
+	secret.Wipe()
+	require.Equal(t, []byte{0, 0, 0}, privKey)
+	require.Nil(t, secret.PrivateKey())
+}
+
+func TestFileKeyStoreNotFound(t *testing.T) {
+	ks := NewFileKeyStore(map[string]string{"eth": "key.pem"}, "")
+
+	_, err := ks.GetSecret(testDestID)
+	require.Equal(t, ErrKeyNotFound, err)
+
+	_, err = ks.GetSecret("eth-3")
+	require.Error(t, err)
+	require.NotEqual(t, ErrKeyNotFound, err)
+}
+
+func TestFileKeyStoreManage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ks := NewFileKeyStore(map[string]string{"eth": "key.pem"}, dir)
+
+	info, err := AddMnemonic(ks, "FISCO-1-1", testMnemonic, AlgoSecp256k1)
+	require.NoError(t, err)
+	require.Equal(t, KeyInfo{Name: "fisco-1-1", ChainType: ChainTypeFISCO, Algo: AlgoSecp256k1, Address: "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"}, info)
+
+	_, err = AddMnemonic(ks, "fisco-1-1", testMnemonic, AlgoSecp256k1)
+	require.Error(t, err)
+
+	_, err = AddMnemonic(ks, "irita-hub", testMnemonic, AlgoSM2)
+	require.NoError(t, err)
+
+	_, err = AddMnemonic(ks, "../fisco", testMnemonic, AlgoSecp256k1)
+	require.Error(t, err)
+
+	names, err := ks.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"eth", "fisco-1-1", "irita-hub"}, names)
+
+	described, err := Describe(ks, "fisco-1-1", "")
+	require.NoError(t, err)
+	require.Equal(t, info, described)
+
+	secret, err := ks.GetSecret("irita-hub")
+	require.NoError(t, err)
+	require.Equal(t, AlgoSM2, secret.Algo())
+
+	_, err = ks.GetSecret("fisco-1-2")
+	require.Equal(t, ErrKeyNotFound, err)
+
+	require.Error(t, ks.Delete("eth"))
+	require.NoError(t, ks.Delete("fisco-1-1"))
+	require.Equal(t, ErrKeyNotFound, ks.Delete("fisco-1-1"))
+
+	_, err = ks.GetSecret(testDestID)
+	require.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestEnvKeyStoreReadOnly(t *testing.T) {
+	ks := NewEnvKeyStore(map[string]string{"irita-hub": "RELAYER_TEST_HUB_KEY"})
+	name := ks.EnvVarName(testDestID)
+	defer os.Unsetenv(name)
+	defer os.Unsetenv("RELAYER_TEST_HUB_KEY")
+
+	require.NoError(t, os.Setenv(name, testPrivKeyHex))
+	require.NoError(t, os.Setenv("RELAYER_TEST_HUB_KEY", testMnemonic))
+
+	names, err := ks.List()
+	require.NoError(t, err)
+	require.Contains(t, names, "fisco-1-1")
+	require.Contains(t, names, "irita-hub")
+
+	info, err := Describe(ks, "irita-hub", "")
+	require.NoError(t, err)
+	require.Equal(t, AlgoSM2, info.Algo)
+	require.NotEmpty(t, info.Address)
+
+	_, err = AddMnemonic(ks, "eth-3", testMnemonic, AlgoSecp256k1)
+	require.True(t, errors.Is(err, ErrReadOnly))
+	require.True(t, errors.Is(ks.Delete("fisco-1-1"), ErrReadOnly))
+
+	_, err = ks.GetSecret(testDestID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,205 @@
+package keystore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/irisnet/service-sdk-go/crypto/hd"
+	"github.com/irisnet/service-sdk-go/types"
+
+	"relayer/common"
+)
+
+// EVMHDPath is the derivation path of the EVM keys recovered from a mnemonic,
+// in the format of the hd package, i.e. without the m/ prefix
+const EVMHDPath = "44'/60'/0'/0/0"
+
+// KeyInfo is the public information of a key, without the key material
+type KeyInfo struct {
+	Name      string `json:"name"`
+	ChainType string `json:"chain_type"`
+	Algo      string `json:"algo"`
+	Address   string `json:"address"`
+}
+
+// KeyChainType returns the chain type of the key of the given name,
+// which is either a dest ID or a chain type
+func KeyChainType(name string) string {
+	name = strings.ToLower(name)
+	if isKnownChainType(name) {
+		return name
+	}
+
+	return common.DestID(name).ChainType()
+}
+
+// ValidateKeyName validates the key name, which is either a supported chain
+// type or the dest ID of a chain of a supported type
+func ValidateKeyName(name string) error {
+	if strings.ContainsAny(name, `/\`) || !isKnownChainType(KeyChainType(name)) {
+		return fmt.Errorf("invalid key name %s: expected a dest ID or one of the chain types %s", name, strings.Join(knownChainTypes(), ", "))
+	}
+
+	if KeyChainType(name) == strings.ToLower(name) {
+		return nil
+	}
+
+	return common.DestID(name).Validate()
+}
+
+// DefaultAlgo returns the default key algorithm of the chain type
+// The Irita-Hub keys are sm2, the others secp256k1
+func DefaultAlgo(chainType string) string {
+	switch strings.ToLower(chainType) {
+	case ChainTypeHub:
+		return AlgoSM2
+	default:
+		return AlgoSecp256k1
+	}
+}
+
+// DerivePrivateKey returns a copy of the raw private key of the secret for the chain type,
+// derived by the chain derivation path if the secret is a mnemonic
+func DerivePrivateKey(chainType string, secret Secret, algo string) ([]byte, error) {
+	if len(secret.PrivateKey()) != 0 {
+		privKey := make([]byte, len(secret.PrivateKey()))
+		copy(privKey, secret.PrivateKey())
+
+		return privKey, nil
+	}
+
+	signingAlgo, err := hd.NewSigningAlgoFromString(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	hdPath := EVMHDPath
+	if isCosmosChainType(chainType) {
+		hdPath = hd.FullPath
+	}
+
+	privKey, err := signingAlgo.Derive()(secret.Mnemonic(), "", hdPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the private key: %s", err)
+	}
+
+	return privKey, nil
+}
+
+// DeriveAddress returns the address of the account of the raw private key in the chain format
+func DeriveAddress(chainType string, privKey []byte, algo string) (string, error) {
+	if isCosmosChainType(chainType) {
+		signingAlgo, err := hd.NewSigningAlgoFromString(algo)
+		if err != nil {
+			return "", err
+		}
+
+		return types.AccAddress(signingAlgo.Generate()(privKey).PubKey().Address()).String(), nil
+	}
+
+	signer, err := NewEVMSigner(privKey, algo)
+	if err != nil {
+		return "", err
+	}
+
+	defer signer.Wipe()
+
+	return signer.Address(), nil
+}
+
+// Describe returns the public information of the key of the given name
+// The algorithm of the key takes precedence over the given one, which
+// defaults to the algorithm of the chain type
+func Describe(ks KeyStore, name string, algo string) (KeyInfo, error) {
+	chainType := KeyChainType(name)
+
+	secret, err := ks.GetSecret(common.DestID(name))
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	defer secret.Wipe()
+
+	if len(secret.Algo()) != 0 {
+		algo = secret.Algo()
+	} else if len(algo) == 0 {
+		algo = DefaultAlgo(chainType)
+	}
+
+	privKey, err := DerivePrivateKey(chainType, secret, algo)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	defer Zero(privKey)
+
+	address, err := DeriveAddress(chainType, privKey, algo)
+	if err != nil {
+		return KeyInfo{}, fmt.Errorf("invalid key %s: %s", name, err)
+	}
+
+	return KeyInfo{
+		Name:      name,
+		ChainType: chainType,
+		Algo:      algo,
+		Address:   address,
+	}, nil
+}
+
+// AddMnemonic stores the private key derived from the mnemonic for the chain type of the name,
+// returning the public information of the added key
+func AddMnemonic(ks KeyManager, name string, mnemonic string, algo string) (KeyInfo, error) {
+	chainType := KeyChainType(name)
+
+	secret := newMnemonicSecret([]byte(mnemonic))
+	defer secret.Wipe()
+
+	privKey, err := DerivePrivateKey(chainType, secret, algo)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	defer Zero(privKey)
+
+	address, err := DeriveAddress(chainType, privKey, algo)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	if err := ks.Add(name, privKey, algo); err != nil {
+		return KeyInfo{}, err
+	}
+
+	return KeyInfo{
+		Name:      strings.ToLower(name),
+		ChainType: chainType,
+		Algo:      algo,
+		Address:   address,
+	}, nil
+}
+
+// isCosmosChainType returns true if the chain type signs by a Cosmos account
+func isCosmosChainType(chainType string) bool {
+	switch strings.ToLower(chainType) {
+	case ChainTypeCosmos, ChainTypeIrita, ChainTypeHub:
+		return true
+	default:
+		return false
+	}
+}
+
+// isKnownChainType returns true if a signer is supported for the chain type
+func isKnownChainType(chainType string) bool {
+	for _, t := range knownChainTypes() {
+		if t == chainType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// knownChainTypes returns the chain types a signer is supported for
+func knownChainTypes() []string {
+	return []string{ChainTypeCosmos, ChainTypeIrita, ChainTypeHub, ChainTypeEVM, ChainTypeEth, ChainTypeFISCO}
+}
@@ -0,0 +1,82 @@
+package keystore
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+)
+
+// pemTypePrivateKey is the PEM block type of the PKCS #8 private keys
+const pemTypePrivateKey = "PRIVATE KEY"
+
+// object identifiers of the PKCS #8 EC private keys the FISCO SDK reads
+var (
+	oidPublicKeyECDSA      = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidNamedCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidNamedCurveSM2       = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+)
+
+// pkcs8 is the ASN.1 structure of the PKCS #8 private key info, see RFC 5208
+type pkcs8 struct {
+	Version    int
+	Algo       algorithmIdentifier
+	PrivateKey []byte
+}
+
+// algorithmIdentifier is the ASN.1 structure of the same name, see RFC 5280
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// ecPrivateKey is the ASN.1 structure of the EC private key, see RFC 5915
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+}
+
+// encodePrivateKeyPEM encodes the raw private key of the given algorithm into the
+// PKCS #8 PEM format loaded by the FileKeyStore
+func encodePrivateKeyPEM(privKey []byte, algo string) ([]byte, error) {
+	var curve asn1.ObjectIdentifier
+
+	switch algo {
+	case AlgoSecp256k1:
+		curve = oidNamedCurveSecp256k1
+	case AlgoSM2:
+		curve = oidNamedCurveSM2
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %s", algo)
+	}
+
+	params, err := asn1.Marshal(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: privKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer Zero(key)
+
+	der, err := asn1.Marshal(pkcs8{
+		Algo: algorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PrivateKey: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer Zero(der)
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der}), nil
+}
@@ -0,0 +1,205 @@
+package keystore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FISCO-BCOS/go-sdk/smcrypto"
+	"github.com/ethereum/go-ethereum/crypto"
+	cryptotypes "github.com/irisnet/service-sdk-go/crypto/types"
+	"github.com/irisnet/service-sdk-go/types"
+)
+
+// chain types of the signers
+const (
+	ChainTypeCosmos = "cosmos"
+	ChainTypeIrita  = "irita"
+	ChainTypeHub    = "irita-hub"
+	ChainTypeEVM    = "evm"
+	ChainTypeEth    = "eth"
+	ChainTypeFISCO  = "fisco"
+)
+
+// digestLength is the length of the digest signed by the EVM signers
+const digestLength = 32
+
+// Signer defines the interface to sign by the account of a chain,
+// decoupled from the chain specific key handling
+type Signer interface {
+	// Address returns the address of the account in the chain format
+	Address() string
+
+	// Sign signs the msg the way the chain signs its txs: the Cosmos signers sign
+	// the SHA-256 hash of the sign bytes, returning R || S, and the EVM signers
+	// sign the 32-byte Keccak-256 or SM3 digest, e.g. the sighash of a tx,
+	// returning R || S || V for secp256k1 and R || S || public key for sm2
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Keyring defines the keyring operations the Cosmos signer relies on
+// It is implemented by the Irita-Hub service client
+type Keyring interface {
+	Sign(name, password string, data []byte) ([]byte, cryptotypes.PubKey, error)
+	Find(name, password string) (cryptotypes.PubKey, types.AccAddress, error)
+}
+
+// SignerParams holds the key of a signer, in a keyring for the Cosmos
+// chains or as a raw private key for the EVM chains
+type SignerParams struct {
+	Keyring    Keyring
+	KeyName    string
+	Passphrase string
+	PrivateKey []byte
+	Algo       string // key algorithm, secp256k1 by default
+}
+
+// NewSigner constructs the Signer of the given chain type
+func NewSigner(chainType string, params SignerParams) (Signer, error) {
+	switch strings.ToLower(chainType) {
+	case ChainTypeCosmos, ChainTypeIrita, ChainTypeHub:
+		if params.Keyring == nil {
+			return nil, fmt.Errorf("the %s signer requires a keyring", chainType)
+		}
+
+		return NewCosmosSigner(params.Keyring, params.KeyName, params.Passphrase)
+
+	case ChainTypeEVM, ChainTypeEth, ChainTypeFISCO:
+		return NewEVMSigner(params.PrivateKey, params.Algo)
+
+	default:
+		return nil, fmt.Errorf("no signer for the chain type %s", chainType)
+	}
+}
+
+// CosmosSigner is a Signer backed by a key of the Cosmos keyring
+type CosmosSigner struct {
+	keyring    Keyring
+	name       string
+	passphrase string
+	address    string
+}
+
+var _ Signer = CosmosSigner{}
+
+// NewCosmosSigner constructs a new CosmosSigner instance on the given key
+// The key is looked up once to check it is present in the keyring
+func NewCosmosSigner(keyring Keyring, name string, passphrase string) (CosmosSigner, error) {
+	_, address, err := keyring.Find(name, passphrase)
+	if err != nil {
+		return CosmosSigner{}, fmt.Errorf("failed to load the key %s: %s", name, err)
+	}
+
+	return CosmosSigner{
+		keyring:    keyring,
+		name:       name,
+		passphrase: passphrase,
+		address:    address.String(),
+	}, nil
+}
+
+// Address implements Signer
+func (s CosmosSigner) Address() string {
+	return s.address
+}
+
+// Sign implements Signer
+func (s CosmosSigner) Sign(msg []byte) ([]byte, error) {
+	sig, _, err := s.keyring.Sign(s.name, s.passphrase, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign by the key %s: %s", s.name, err)
+	}
+
+	return sig, nil
+}
+
+// EVMSigner is a Signer backed by the secp256k1 or sm2 private key of an EVM account
+type EVMSigner struct {
+	privKey []byte
+	algo    string
+	address string
+}
+
+var _ Signer = (*EVMSigner)(nil)
+
+// NewEVMSigner constructs a new EVMSigner instance on a copy of the given private key
+func NewEVMSigner(privKey []byte, algo string) (*EVMSigner, error) {
+	if len(algo) == 0 {
+		algo = AlgoSecp256k1
+	}
+
+	key := make([]byte, len(privKey))
+	copy(key, privKey)
+
+	s := &EVMSigner{
+		privKey: key,
+		algo:    algo,
+	}
+
+	switch algo {
+	case AlgoSecp256k1:
+		ecdsaKey, err := crypto.ToECDSA(key)
+		if err != nil {
+			Zero(key)
+			return nil, fmt.Errorf("invalid secp256k1 private key: %s", err)
+		}
+
+		s.address = crypto.PubkeyToAddress(ecdsaKey.PublicKey).Hex()
+
+	case AlgoSM2:
+		if _, err := smcrypto.ToSM2(key); err != nil {
+			Zero(key)
+			return nil, fmt.Errorf("invalid sm2 private key: %s", err)
+		}
+
+		s.address = smcrypto.SM2KeyToAddress(key).Hex()
+
+	default:
+		Zero(key)
+		return nil, fmt.Errorf("unsupported key algorithm %s", algo)
+	}
+
+	return s, nil
+}
+
+// Address implements Signer
+func (s *EVMSigner) Address() string {
+	return s.address
+}
+
+// Algo returns the key algorithm of the signer
+func (s *EVMSigner) Algo() string {
+	return s.algo
+}
+
+// Sign implements Signer
+func (s *EVMSigner) Sign(digest []byte) ([]byte, error) {
+	if len(digest) != digestLength {
+		return nil, fmt.Errorf("expected a %d-byte digest, got %d bytes", digestLength, len(digest))
+	}
+
+	if s.algo == AlgoSM2 {
+		return smcrypto.Sign(digest, s.privKey)
+	}
+
+	ecdsaKey, err := crypto.ToECDSA(s.privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.Sign(digest, ecdsaKey)
+}
+
+// Wipe zeroes the private key
+func (s *EVMSigner) Wipe() {
+	Zero(s.privKey)
+}
+
+// String keeps the key material out of the logs
+func (s *EVMSigner) String() string {
+	return fmt.Sprintf("signer{%s}", s.address)
+}
+
+// GoString keeps the key material out of the logs
+func (s *EVMSigner) GoString() string {
+	return s.String()
+}
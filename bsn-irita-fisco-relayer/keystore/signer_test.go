@@ -0,0 +1,110 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/irisnet/service-sdk-go/crypto/keys/secp256k1"
+	cryptotypes "github.com/irisnet/service-sdk-go/crypto/types"
+	"github.com/irisnet/service-sdk-go/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testMsg = "hello relayer"
+
+// testKeyring is a Keyring holding a single secp256k1 key
+type testKeyring struct {
+	name    string
+	privKey *secp256k1.PrivKey
+}
+
+func (k testKeyring) Sign(name, password string, data []byte) ([]byte, cryptotypes.PubKey, error) {
+	if name != k.name {
+		return nil, nil, errors.New("key not found")
+	}
+
+	sig, err := k.privKey.Sign(data)
+
+	return sig, k.privKey.PubKey(), err
+}
+
+func (k testKeyring) Find(name, password string) (cryptotypes.PubKey, types.AccAddress, error) {
+	if name != k.name {
+		return nil, nil, errors.New("key not found")
+	}
+
+	return k.privKey.PubKey(), types.AccAddress(k.privKey.PubKey().Address()), nil
+}
+
+func TestEVMSigner(t *testing.T) {
+	privKey, err := hex.DecodeString(testPrivKeyHex)
+	require.NoError(t, err)
+
+	signer, err := NewSigner(ChainTypeFISCO, SignerParams{PrivateKey: privKey})
+	require.NoError(t, err)
+	require.Equal(t, "0x627306090abaB3A6e1400e9345bC60c78a8BEf57", signer.Address())
+
+	digest := crypto.Keccak256([]byte(testMsg))
+
+	sig, err := signer.Sign(digest)
+	require.NoError(t, err)
+	require.Equal(t, "59c0c425448f1f28bc3bc43f1a553ce4bd15537e7b91e377376db730a4e2be6029d98e62dcce60219144ee3eb02b29eb58650efae16fb7c9bc7deca70ba3068501", hex.EncodeToString(sig))
+
+	pubKey, err := crypto.SigToPub(digest, sig)
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), crypto.PubkeyToAddress(*pubKey).Hex())
+
+	_, err = signer.Sign([]byte(testMsg))
+	require.Error(t, err)
+
+	// the signer holds a copy of the key
+	privKey[0] = 0
+	sig2, err := signer.Sign(digest)
+	require.NoError(t, err)
+	require.Equal(t, sig, sig2)
+	require.NotContains(t, signer.(*EVMSigner).GoString(), testPrivKeyHex)
+}
+
+func TestEVMSignerSM2(t *testing.T) {
+	privKey, err := hex.DecodeString(testPrivKeyHex)
+	require.NoError(t, err)
+
+	signer, err := NewSigner(ChainTypeFISCO, SignerParams{PrivateKey: privKey, Algo: AlgoSM2})
+	require.NoError(t, err)
+	require.Equal(t, "0xCC87E7c9F48dB43085a6f4969866ee9f6a0c18EA", signer.Address())
+
+	// the sm2 signatures are randomized, ending with the public key
+	sig, err := signer.Sign(crypto.Keccak256([]byte(testMsg)))
+	require.NoError(t, err)
+	require.Len(t, sig, 128)
+
+	_, err = NewSigner(ChainTypeFISCO, SignerParams{PrivateKey: privKey, Algo: "ed25519"})
+	require.Error(t, err)
+}
+
+func TestCosmosSigner(t *testing.T) {
+	privKey, err := hex.DecodeString(testPrivKeyHex)
+	require.NoError(t, err)
+
+	keyring := testKeyring{name: "node0", privKey: &secp256k1.PrivKey{Key: privKey}}
+
+	signer, err := NewSigner(ChainTypeHub, SignerParams{Keyring: keyring, KeyName: "node0"})
+	require.NoError(t, err)
+	require.Equal(t, "iaa1gh2qtw0dg587ezgyf7dh4xdyaah79nfla3j9s9", signer.Address())
+
+	sig, err := signer.Sign([]byte(testMsg))
+	require.NoError(t, err)
+	require.Equal(t, "a642ebf092df3ab55b291607efa22ead583efc0d0c2c586e7970dc6d00209f6f4a9b02cb7306d7ffe764687ea966b31f187e43ba931e3c9a1a16665116b2e9e2", hex.EncodeToString(sig))
+	require.True(t, keyring.privKey.PubKey().VerifySignature([]byte(testMsg), sig))
+
+	_, err = NewSigner(ChainTypeHub, SignerParams{Keyring: keyring, KeyName: "node1"})
+	require.Error(t, err)
+
+	_, err = NewSigner(ChainTypeHub, SignerParams{KeyName: "node0"})
+	require.Error(t, err)
+
+	_, err = NewSigner("unknown", SignerParams{})
+	require.Error(t, err)
+}
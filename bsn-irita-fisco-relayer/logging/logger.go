@@ -1,9 +1,30 @@
 package logging
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
+
+	"relayer/common"
+)
+
+// structured log field names
+const (
+	FieldDestID       = "dest_id"
+	FieldRequestID    = "request_id"
+	FieldHubRequestID = "hub_request_id"
+	FieldTxHash       = "tx_hash"
+	FieldStage        = "stage"
+)
+
+// relay stages logged with FieldStage
+const (
+	StageEventReceived   = "event_received"
+	StageRequestBuilt    = "request_built"
+	StageTxSubmitted     = "tx_submitted"
+	StageResponseRelayed = "response_relayed"
 )
 
 // Logger is a logger instance
@@ -16,3 +37,60 @@ func init() {
 
 	Logger.SetLevel(log.InfoLevel)
 }
+
+// SetLevel sets the global log level from the given level name
+// The default level is kept if the name is empty
+func SetLevel(level string) error {
+	if len(level) == 0 {
+		return nil
+	}
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	Logger.SetLevel(lvl)
+
+	return nil
+}
+
+// ParseLevel parses the log level name, which defaults to info if empty
+func ParseLevel(level string) (log.Level, error) {
+	if len(level) == 0 {
+		return log.InfoLevel, nil
+	}
+
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return lvl, fmt.Errorf("invalid log level %q: %s", level, err)
+	}
+
+	return lvl, nil
+}
+
+// SetFormat sets the global log format, which is either "text" or "json"
+// The default format is kept if the format is empty
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return nil
+
+	case "json":
+		Logger.SetFormatter(&log.JSONFormatter{})
+		return nil
+
+	default:
+		return fmt.Errorf("log format %s not supported", format)
+	}
+}
+
+// WithChain returns a log entry with the given dest ID attached
+func WithChain(destID common.DestID) *log.Entry {
+	return Logger.WithField(FieldDestID, destID.String())
+}
+
+// WithRequestID returns a log entry with the given request ID attached
+func WithRequestID(id string) *log.Entry {
+	return Logger.WithField(FieldRequestID, id)
+}
@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"relayer/logging"
+)
+
+const (
+	Namespace = "relayer"
+
+	LabelSource  = "source"
+	LabelDest    = "dest"
+	LabelChain   = "chain"
+	LabelTask    = "task"
+	LabelAccount = "account"
+	LabelDenom   = "denom"
+	LabelStage   = "stage"
+
+	DefaultAddress = ":8083"
+)
+
+var (
+	// RequestsReceived counts the interchain requests received from the source chains
+	RequestsReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_received_total",
+			Help:      "Number of interchain requests received",
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// RequestsRelayed counts the interchain requests whose responses are sent back successfully
+	RequestsRelayed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_relayed_total",
+			Help:      "Number of interchain requests relayed successfully",
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// RequestsFiltered counts the interchain requests skipped by the event filters
+	RequestsFiltered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_filtered_total",
+			Help:      "Number of interchain requests skipped by the event filters",
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// RequestsDeadLettered counts the interchain requests recorded to the dead letter queue
+	RequestsDeadLettered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_dead_lettered_total",
+			Help:      "Number of interchain requests recorded to the dead letter queue",
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// RequestsDuplicated counts the duplicate interchain requests dropped
+	RequestsDuplicated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_duplicated_total",
+			Help:      "Number of duplicate interchain requests dropped",
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// RelayErrors counts the failed relays
+	RelayErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "relay_errors_total",
+			Help:      "Number of relay errors",
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// RelayLatency observes the duration from receiving a request to sending back its response
+	RelayLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "relay_latency_seconds",
+			Help:      "End-to-end relay latency in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+		},
+		[]string{LabelSource, LabelDest},
+	)
+
+	// StageLatency observes the duration of each relay stage, from the source event to the response confirmation
+	StageLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "relay_stage_latency_seconds",
+			Help:      "Relay latency of each stage in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 14),
+		},
+		[]string{LabelStage, LabelDest},
+	)
+
+	// TxConfirmationTime observes the duration from submitting a tx to its confirmation
+	// The Hub txs are labeled by the dest ID of their request, the app chain txs by the dest ID of the chain
+	TxConfirmationTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "tx_confirmation_seconds",
+			Help:      "Transaction confirmation time in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+		},
+		[]string{LabelDest},
+	)
+
+	// SubscriptionFailures counts the failed subscriptions to the new blocks of the chains, polled meanwhile
+	SubscriptionFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "subscription_failures_total",
+			Help:      "Number of failed new blocks subscriptions",
+		},
+		[]string{LabelChain},
+	)
+
+	// SubscriptionReconnects counts the renewed subscriptions to the new blocks of the chains, e.g. after a drop
+	SubscriptionReconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "subscription_reconnects_total",
+			Help:      "Number of renewed new blocks subscriptions",
+		},
+		[]string{LabelChain},
+	)
+
+	// TaskFailures counts the panics and errors of the supervised tasks, each followed by a restart
+	TaskFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "task_failures_total",
+			Help:      "Number of failures of the supervised tasks",
+		},
+		[]string{LabelTask},
+	)
+
+	// CircuitState reports the circuit breaker state of the chains: 0 closed, 1 half-open, 2 open
+	CircuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "circuit_state",
+			Help:      "Circuit breaker state of the chain, 0 closed, 1 half-open, 2 open",
+		},
+		[]string{LabelChain},
+	)
+
+	// EventQueueDepth reports the requests accepted from the source chains and not yet responded
+	EventQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "event_queue_depth",
+			Help:      "Number of interchain requests in flight in the event queue",
+		},
+	)
+
+	// EventQueueCapacity reports the depth beyond which the listeners pause
+	EventQueueCapacity = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "event_queue_capacity",
+			Help:      "Capacity of the event queue pausing the listeners once reached",
+		},
+	)
+
+	// PendingConfirmations reports the response txs broadcast and awaiting their confirmation
+	PendingConfirmations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "pending_confirmations",
+			Help:      "Number of response transactions awaiting confirmation",
+		},
+		[]string{LabelChain},
+	)
+
+	// ChainPolls counts the polls of the new blocks of the chains, spread over the interval by the jitter
+	ChainPolls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "chain_polls_total",
+			Help:      "Number of polls of the chain endpoints for new blocks",
+		},
+		[]string{LabelChain},
+	)
+
+	// PollInterval observes the delay between two polls of the chains
+	PollInterval = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "poll_interval_seconds",
+			Help:      "Delay between two polls of the chain endpoints",
+			Buckets:   []float64{0.25, 0.5, 0.75, 1, 1.25, 1.5, 2, 5, 10, 30},
+		},
+		[]string{LabelChain},
+	)
+
+	// AccountBalance reports the balances of the signing accounts by denom
+	AccountBalance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "account_balance",
+			Help:      "Balance of the signing account, in the given denom",
+		},
+		[]string{LabelChain, LabelAccount, LabelDenom},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestsReceived,
+		RequestsRelayed,
+		RequestsFiltered,
+		RequestsDuplicated,
+		RequestsDeadLettered,
+		RelayErrors,
+		RelayLatency,
+		StageLatency,
+		TxConfirmationTime,
+		SubscriptionFailures,
+		SubscriptionReconnects,
+		TaskFailures,
+		CircuitState,
+		AccountBalance,
+		EventQueueDepth,
+		EventQueueCapacity,
+		PendingConfirmations,
+		ChainPolls,
+		PollInterval,
+	)
+}
+
+// StartServer starts the HTTP server exposing /metrics on the given address
+func StartServer(address string) *http.Server {
+	if len(address) == 0 {
+		address = DefaultAddress
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+
+	go func() {
+		logging.Logger.Infof("metrics server listening on %s", address)
+
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.Logger.Errorf("metrics server stopped: %s", err)
+		}
+	}()
+
+	return srv
+}
@@ -2,17 +2,27 @@ package server
 
 import (
 	"fmt"
+	"net/http"
+
+	"relayer/logging"
 )
 
 // StartWebServer starts the web server with a ChainManager instance
+// The server runs in the background until it is shut down
 func StartWebServer(
 	chainManager *ChainManager,
 	port int,
-) {
-	srv := NewHTTPService(chainManager)
-
-	err := srv.Router.Run(fmt.Sprintf(":%d", port))
-	if err != nil {
-		fmt.Println(err)
+) *http.Server {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: NewHTTPService(chainManager),
 	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.Logger.Errorf("web server stopped: %s", err)
+		}
+	}()
+
+	return srv
 }
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"relayer/common"
 	"relayer/core"
 )
 
@@ -40,8 +41,33 @@ func (cm *ChainManager) GetChains() []string {
 	return cm.relayer.GetChains()
 }
 
+// CheckHealth reports the health of the running app chains
+func (cm *ChainManager) CheckHealth() core.HealthReport {
+	return cm.relayer.CheckHealth()
+}
+
+// CheckLiveness reports whether the main loop of the relayer is running
+func (cm *ChainManager) CheckLiveness() core.LivenessReport {
+	return cm.relayer.CheckLiveness()
+}
+
 // GetChainStatus retrieves the status of the specified app chain
 func (cm *ChainManager) GetChainStatus(chainID string) (state bool, height int64, err error) {
 	return cm.relayer.GetChainStatus(chainID)
 }
 
+
+// ListDeadLetters retrieves the dead-lettered requests
+func (cm *ChainManager) ListDeadLetters() ([]core.DeadLetter, error) {
+	return cm.relayer.ListDeadLetters()
+}
+
+// ResubmitDeadLetter relays the dead-lettered request again
+func (cm *ChainManager) ResubmitDeadLetter(requestID string) error {
+	return cm.relayer.ResubmitDeadLetter(requestID)
+}
+
+// GetRequestStatus retrieves the relay status of the specified request of the given source chain, if any
+func (cm *ChainManager) GetRequestStatus(requestID string, destID string) (core.RequestStatus, error) {
+	return cm.relayer.GetRequestStatus(requestID, common.DestID(destID))
+}
@@ -46,7 +46,13 @@ func (srv *HTTPService) createRouter() {
 		fiscobcos.GET("/chains/:chainid/status", srv.GetChainStatus)
 	}
 
+	api.GET("/deadletters", srv.ListDeadLetters)
+	api.POST("/deadletters/:requestid/resubmit", srv.ResubmitDeadLetter)
+	api.GET("/requests/:requestid/status", srv.GetRequestStatus)
+
 	r.GET("/health", srv.ShowHealth)
+	r.GET("/healthz", srv.Healthz)
+	r.GET("/livez", srv.Livez)
 
 	srv.Router = r
 }
@@ -168,11 +174,80 @@ func (srv *HTTPService) GetChainStatus(c *gin.Context) {
 	onSuccess(c, ChainStatus{State: state, Height: height})
 }
 
+// ListDeadLetters returns the dead-lettered requests
+func (srv *HTTPService) ListDeadLetters(c *gin.Context) {
+	letters, err := srv.ChainManager.ListDeadLetters()
+	if err != nil {
+		onError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	onSuccess(c, letters)
+}
+
+// ResubmitDeadLetter relays the dead-lettered request again
+func (srv *HTTPService) ResubmitDeadLetter(c *gin.Context) {
+	requestID := c.Param("requestid")
+	if len(requestID) == 0 {
+		onError(c, http.StatusBadRequest, "request ID can not be empty")
+		return
+	}
+
+	if err := srv.ChainManager.ResubmitDeadLetter(requestID); err != nil {
+		onError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	onSuccess(c, nil)
+}
+
+// GetRequestStatus returns the relay status of the request
+// The dest ID of the source chain may be given by the dest_id query param
+func (srv *HTTPService) GetRequestStatus(c *gin.Context) {
+	requestID := c.Param("requestid")
+	if len(requestID) == 0 {
+		onError(c, http.StatusBadRequest, "request ID can not be empty")
+		return
+	}
+
+	status, err := srv.ChainManager.GetRequestStatus(requestID, c.Query("dest_id"))
+	if err != nil {
+		onError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	onSuccess(c, status)
+}
+
 // ShowHealth returns the health state
 func (srv *HTTPService) ShowHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"result": true})
 }
 
+// Healthz returns 200 if every running app chain is connected and not stale, and 503 otherwise
+func (srv *HTTPService) Healthz(c *gin.Context) {
+	report := srv.ChainManager.CheckHealth()
+
+	code := http.StatusOK
+	if !report.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, report)
+}
+
+// Livez returns 200 if the main loop of the relayer is running, and 503 otherwise
+func (srv *HTTPService) Livez(c *gin.Context) {
+	report := srv.ChainManager.CheckLiveness()
+
+	code := http.StatusOK
+	if !report.Alive {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, report)
+}
+
 func onError(c *gin.Context, code int, msg string) {
 	logging.Logger.Errorf(msg)
 
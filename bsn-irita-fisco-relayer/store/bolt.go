@@ -0,0 +1,304 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"relayer/common"
+)
+
+// bolt buckets namespacing the keys by store type
+var (
+	bucketCheckpoint = []byte("checkpoint") // dest ID => height
+	bucketDedup      = []byte("dedup")      // dest ID/request ID => seen at
+	bucketStatus     = []byte("status")     // dest ID/request ID => status
+	bucketMeta       = []byte("meta")
+
+	keyMigrated = []byte("migrated")
+)
+
+// boltOpenTimeout bounds the wait for the file lock held by another process
+const boltOpenTimeout = 5 * time.Second
+
+// BoltStateStore is a StateStore backed by a single BoltDB file
+// Each write is a transaction synced to disk
+type BoltStateStore struct {
+	db *bbolt.DB
+}
+
+var _ StateStore = (*BoltStateStore)(nil)
+
+// NewBoltStateStore opens the BoltDB state file at the given path, creating it if needed
+// An error is returned if the file is locked by another process
+func NewBoltStateStore(path string) (*BoltStateStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the state directory: %s", err)
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the state file %s: %s", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{bucketCheckpoint, bucketDedup, bucketStatus, bucketMeta} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize the state file %s: %s", path, err)
+	}
+
+	return &BoltStateStore{
+		db: db,
+	}, nil
+}
+
+// NewReadOnlyBoltStateStore opens the existing BoltDB state file at the given path for reading only
+// The writes fail, and an error is returned if the file is locked by another process
+func NewReadOnlyBoltStateStore(path string) (*BoltStateStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: boltOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the state file %s: %s", path, err)
+	}
+
+	return &BoltStateStore{
+		db: db,
+	}, nil
+}
+
+// Save implements Checkpoint
+func (s *BoltStateStore) Save(destID common.DestID, height int64) error {
+	if err := destID.Validate(); err != nil {
+		return err
+	}
+
+	return s.put(bucketCheckpoint, []byte(destID), []byte(strconv.FormatInt(height, 10)))
+}
+
+// Load implements Checkpoint
+func (s *BoltStateStore) Load(destID common.DestID) (int64, error) {
+	if err := destID.Validate(); err != nil {
+		return 0, err
+	}
+
+	bz, err := s.get(bucketCheckpoint, []byte(destID))
+	if err == ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	height, err := strconv.ParseInt(string(bz), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint of %s: %s", destID, err)
+	}
+
+	return height, nil
+}
+
+// SaveSeen implements StateStore
+func (s *BoltStateStore) SaveSeen(destID common.DestID, requestID string, seenAt time.Time) error {
+	return s.put(bucketDedup, boltRequestKey(destID, requestID), encodeTime(seenAt))
+}
+
+// LoadSeen implements StateStore
+func (s *BoltStateStore) LoadSeen(destID common.DestID, requestID string) (time.Time, error) {
+	bz, err := s.get(bucketDedup, boltRequestKey(destID, requestID))
+	if err == ErrNotFound {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	return decodeTime(bz)
+}
+
+// DeleteSeen implements StateStore
+func (s *BoltStateStore) DeleteSeen(destID common.DestID, requestID string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketDedup).Delete(boltRequestKey(destID, requestID))
+	})
+}
+
+// PruneSeen implements StateStore
+// The marks are removed in a single transaction
+func (s *BoltStateStore) PruneSeen(before time.Time) (int, error) {
+	pruned := 0
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDedup)
+
+		// deleting through the cursor would skip the next key
+		var expired [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			seenAt, err := decodeTime(value)
+			if err != nil || seenAt.Before(before) {
+				expired = append(expired, key)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		pruned = len(expired)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return pruned, nil
+}
+
+// SaveStatus implements StateStore
+func (s *BoltStateStore) SaveStatus(destID common.DestID, requestID string, status []byte) error {
+	return s.put(bucketStatus, boltRequestKey(destID, requestID), status)
+}
+
+// LoadStatus implements StateStore
+func (s *BoltStateStore) LoadStatus(destID common.DestID, requestID string) ([]byte, error) {
+	return s.get(bucketStatus, boltRequestKey(destID, requestID))
+}
+
+// LookupStatus implements StateStore
+// The whole bucket is scanned, as the keys lead with the dest ID
+func (s *BoltStateStore) LookupStatus(requestID string) (map[common.DestID][]byte, error) {
+	statuses := make(map[common.DestID][]byte)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketStatus).ForEach(func(key, value []byte) error {
+			// the dest IDs contain no slash, unlike the request IDs may
+			parts := strings.SplitN(string(key), "/", 2)
+			if len(parts) == 2 && parts[1] == requestID {
+				// the value is only valid during the transaction
+				statuses[common.DestID(parts[0])] = append([]byte{}, value...)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// Close implements StateStore
+func (s *BoltStateStore) Close() error {
+	return s.db.Close()
+}
+
+// put writes the key-value into the given bucket
+func (s *BoltStateStore) put(bucket, key, value []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, value)
+	})
+}
+
+// get retrieves a copy of the value of the key in the given bucket
+func (s *BoltStateStore) get(bucket, key []byte) ([]byte, error) {
+	var value []byte
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bz := tx.Bucket(bucket).Get(key)
+		if bz == nil {
+			return ErrNotFound
+		}
+
+		// the value is only valid during the transaction
+		value = append([]byte{}, bz...)
+
+		return nil
+	})
+
+	return value, err
+}
+
+// boltRequestKey returns the bucket key of the seen mark or the status of the request
+func boltRequestKey(destID common.DestID, requestID string) []byte {
+	return []byte(destID.String() + "/" + requestID)
+}
+
+// MigrateFileState imports the state of the file backend into the BoltDB state store,
+// so that switching the backend keeps the relay progress
+// The import runs in a single transaction, only once per state file: false is returned
+// if the state store was already migrated. The file state is left untouched
+func MigrateFileState(to *BoltStateStore, from *FileStateStore) (bool, error) {
+	heights, err := from.List()
+	if err != nil {
+		return false, fmt.Errorf("failed to list the checkpoints: %s", err)
+	}
+
+	migrated := false
+
+	err = to.db.Update(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket(bucketMeta)
+		if meta.Get(keyMigrated) != nil {
+			return nil
+		}
+
+		checkpoints := tx.Bucket(bucketCheckpoint)
+		for destID, height := range heights {
+			if err := checkpoints.Put([]byte(destID), []byte(strconv.FormatInt(height, 10))); err != nil {
+				return err
+			}
+		}
+
+		statuses := tx.Bucket(bucketStatus)
+		err := from.db.Iterate([]byte(KeyPrefixStatus), func(key, value []byte) error {
+			destID, requestID, err := parseRequestKey(key, KeyPrefixStatus)
+			if err != nil {
+				return fmt.Errorf("invalid status key %s", key)
+			}
+
+			return statuses.Put(boltRequestKey(destID, requestID), append([]byte{}, value...))
+		})
+		if err != nil {
+			return err
+		}
+
+		seen := tx.Bucket(bucketDedup)
+		err = from.db.Iterate([]byte(KeyPrefixDedup), func(key, value []byte) error {
+			destID, requestID, err := parseRequestKey(key, KeyPrefixDedup)
+			if err != nil {
+				return fmt.Errorf("invalid seen mark key %s", key)
+			}
+
+			return seen.Put(boltRequestKey(destID, requestID), append([]byte{}, value...))
+		})
+		if err != nil {
+			return err
+		}
+
+		migrated = true
+
+		return meta.Put(keyMigrated, []byte(time.Now().UTC().Format(time.RFC3339)))
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to migrate the file state: %s", err)
+	}
+
+	return migrated, nil
+}
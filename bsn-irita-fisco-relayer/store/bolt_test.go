@@ -0,0 +1,264 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+func TestBoltStateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.db")
+
+	state, err := NewBoltStateStore(path)
+	require.NoError(t, err)
+
+	destID := common.DestID("fisco-1-5")
+	seenAt := time.Unix(100, 5)
+
+	require.NoError(t, state.Save(destID, 101))
+	require.NoError(t, state.SaveSeen(destID, "req-1", seenAt))
+	require.NoError(t, state.SaveStatus(destID, "req-1", []byte(`{"relayed":true}`)))
+	require.NoError(t, state.Close())
+
+	// reopen to check durability
+	state, err = NewBoltStateStore(path)
+	require.NoError(t, err)
+	defer state.Close()
+
+	height, err := state.Load(destID)
+	require.NoError(t, err)
+	require.Equal(t, int64(101), height)
+
+	height, err = state.Load("fisco-1-6")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), height)
+
+	loaded, err := state.LoadSeen(destID, "req-1")
+	require.NoError(t, err)
+	require.True(t, seenAt.Equal(loaded))
+
+	// the seen marks are namespaced by dest ID
+	loaded, err = state.LoadSeen("fisco-1-6", "req-1")
+	require.NoError(t, err)
+	require.True(t, loaded.IsZero())
+
+	require.NoError(t, state.DeleteSeen(destID, "req-1"))
+
+	loaded, err = state.LoadSeen(destID, "req-1")
+	require.NoError(t, err)
+	require.True(t, loaded.IsZero())
+
+	status, err := state.LoadStatus(destID, "req-1")
+	require.NoError(t, err)
+	require.Equal(t, `{"relayed":true}`, string(status))
+
+	_, err = state.LoadStatus(destID, "req-2")
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestStatusNamespacedByDestID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := NewFileCheckpoint(filepath.Join(dir, "checkpoints"))
+	require.NoError(t, err)
+
+	db, err := NewStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	boltState, err := NewBoltStateStore(filepath.Join(dir, "state.db"))
+	require.NoError(t, err)
+	defer boltState.Close()
+
+	for _, state := range []StateStore{NewFileStateStore(checkpoint, db), boltState} {
+		// the same request ID relayed from two chains keeps a status per chain
+		require.NoError(t, state.SaveStatus("fisco-1-5", "req-1", []byte(`{"chain_id":"5"}`)))
+		require.NoError(t, state.SaveStatus("fisco-1-6", "req-1", []byte(`{"chain_id":"6"}`)))
+		require.NoError(t, state.SaveStatus("fisco-1-6", "req-10", []byte(`{"chain_id":"6"}`)))
+
+		status, err := state.LoadStatus("fisco-1-5", "req-1")
+		require.NoError(t, err)
+		require.Equal(t, `{"chain_id":"5"}`, string(status))
+
+		status, err = state.LoadStatus("fisco-1-6", "req-1")
+		require.NoError(t, err)
+		require.Equal(t, `{"chain_id":"6"}`, string(status))
+
+		statuses, err := state.LookupStatus("req-1")
+		require.NoError(t, err)
+		require.Equal(t, map[common.DestID][]byte{
+			"fisco-1-5": []byte(`{"chain_id":"5"}`),
+			"fisco-1-6": []byte(`{"chain_id":"6"}`),
+		}, statuses)
+
+		statuses, err = state.LookupStatus("req-2")
+		require.NoError(t, err)
+		require.Empty(t, statuses)
+	}
+}
+
+func TestMigrateFileState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := NewFileCheckpoint(filepath.Join(dir, "checkpoints"))
+	require.NoError(t, err)
+
+	db, err := NewStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	fileState := NewFileStateStore(checkpoint, db)
+
+	seenAt := time.Unix(100, 0)
+
+	require.NoError(t, fileState.Save("fisco-1-5", 101))
+	require.NoError(t, fileState.Save("fisco-1-6", 202))
+	require.NoError(t, fileState.SaveSeen("fisco-1-5", "req-1", seenAt))
+	require.NoError(t, fileState.SaveStatus("fisco-1-5", "req-1", []byte(`{"relayed":true}`)))
+	require.NoError(t, fileState.SaveStatus("fisco-1-6", "req-1", []byte(`{"relayed":false}`)))
+
+	// the other keys of the relayer store are not imported
+	require.NoError(t, db.Set([]byte("chainIDs"), []byte(`{}`)))
+
+	boltState, err := NewBoltStateStore(filepath.Join(dir, "state.db"))
+	require.NoError(t, err)
+	defer boltState.Close()
+
+	migrated, err := MigrateFileState(boltState, fileState)
+	require.NoError(t, err)
+	require.True(t, migrated)
+
+	height, err := boltState.Load("fisco-1-5")
+	require.NoError(t, err)
+	require.Equal(t, int64(101), height)
+
+	height, err = boltState.Load("fisco-1-6")
+	require.NoError(t, err)
+	require.Equal(t, int64(202), height)
+
+	loaded, err := boltState.LoadSeen("fisco-1-5", "req-1")
+	require.NoError(t, err)
+	require.True(t, seenAt.Equal(loaded))
+
+	status, err := boltState.LoadStatus("fisco-1-5", "req-1")
+	require.NoError(t, err)
+	require.Equal(t, `{"relayed":true}`, string(status))
+
+	status, err = boltState.LoadStatus("fisco-1-6", "req-1")
+	require.NoError(t, err)
+	require.Equal(t, `{"relayed":false}`, string(status))
+
+	// the progress made on the bolt backend is never overwritten by the file state
+	require.NoError(t, boltState.Save("fisco-1-5", 150))
+
+	migrated, err = MigrateFileState(boltState, fileState)
+	require.NoError(t, err)
+	require.False(t, migrated)
+
+	height, err = boltState.Load("fisco-1-5")
+	require.NoError(t, err)
+	require.Equal(t, int64(150), height)
+}
+
+func TestPruneSeen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := NewFileCheckpoint(filepath.Join(dir, "checkpoints"))
+	require.NoError(t, err)
+
+	db, err := NewStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	boltState, err := NewBoltStateStore(filepath.Join(dir, "state.db"))
+	require.NoError(t, err)
+	defer boltState.Close()
+
+	for _, state := range []StateStore{NewFileStateStore(checkpoint, db), boltState} {
+		require.NoError(t, state.SaveSeen("fisco-1-5", "req-1", time.Unix(100, 0)))
+		require.NoError(t, state.SaveSeen("fisco-1-5", "req-2", time.Unix(200, 0)))
+		require.NoError(t, state.SaveSeen("fisco-1-6", "req-1", time.Unix(300, 0)))
+		require.NoError(t, state.SaveStatus("fisco-1-5", "req-1", []byte(`{"relayed":true}`)))
+
+		// only the marks seen before the given time are removed
+		pruned, err := state.PruneSeen(time.Unix(250, 0))
+		require.NoError(t, err)
+		require.Equal(t, 2, pruned)
+
+		loaded, err := state.LoadSeen("fisco-1-5", "req-2")
+		require.NoError(t, err)
+		require.True(t, loaded.IsZero())
+
+		loaded, err = state.LoadSeen("fisco-1-6", "req-1")
+		require.NoError(t, err)
+		require.True(t, time.Unix(300, 0).Equal(loaded))
+
+		_, err = state.LoadStatus("fisco-1-5", "req-1")
+		require.NoError(t, err)
+
+		pruned, err = state.PruneSeen(time.Unix(250, 0))
+		require.NoError(t, err)
+		require.Equal(t, 0, pruned)
+	}
+}
+
+func TestReadOnlyStateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.db")
+
+	_, err = NewReadOnlyBoltStateStore(path)
+	require.Error(t, err)
+
+	state, err := NewBoltStateStore(path)
+	require.NoError(t, err)
+	require.NoError(t, state.SaveStatus("fisco-1-5", "req-1", []byte(`{"relayed":true}`)))
+	require.NoError(t, state.Close())
+
+	state, err = NewReadOnlyBoltStateStore(path)
+	require.NoError(t, err)
+	defer state.Close()
+
+	status, err := state.LoadStatus("fisco-1-5", "req-1")
+	require.NoError(t, err)
+	require.Equal(t, `{"relayed":true}`, string(status))
+
+	require.Error(t, state.SaveStatus("fisco-1-5", "req-2", []byte(`{}`)))
+
+	// the relayer store is opened read-only as well, and never created
+	_, err = NewReadOnlyStore(filepath.Join(dir, "db"))
+	require.Error(t, err)
+
+	db, err := NewStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	db, err = NewReadOnlyStore(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	value, err := db.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, "value", string(value))
+
+	require.Error(t, db.Set([]byte("key"), []byte("other")))
+}
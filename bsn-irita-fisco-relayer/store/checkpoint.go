@@ -0,0 +1,198 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"relayer/common"
+)
+
+const (
+	// DefaultCheckpointDir is the checkpoint directory relative to the home directory
+	DefaultCheckpointDir = ".relayer/checkpoints"
+
+	checkpointFileExt = ".height"
+)
+
+// Checkpoint defines the interface to persist the relay progress of chains
+type Checkpoint interface {
+	// Save stores the last handled height of the given chain
+	Save(destID common.DestID, height int64) error
+
+	// Load retrieves the last handled height of the given chain
+	// Zero is returned if no checkpoint exists
+	Load(destID common.DestID) (int64, error)
+}
+
+// FileCheckpoint is a Checkpoint implementation backed by one file per chain
+type FileCheckpoint struct {
+	dir string
+	mtx sync.Mutex
+}
+
+var _ Checkpoint = (*FileCheckpoint)(nil)
+
+// NewFileCheckpoint constructs a new FileCheckpoint instance in the given directory
+// The directory is created if it does not exist
+func NewFileCheckpoint(dir string) (*FileCheckpoint, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the checkpoint directory %s: %s", dir, err)
+	}
+
+	return &FileCheckpoint{
+		dir: dir,
+	}, nil
+}
+
+// DefaultCheckpointPath returns the default checkpoint directory under the relayer home directory
+func DefaultCheckpointPath() (string, error) {
+	homeDir, err := common.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, DefaultCheckpointDir), nil
+}
+
+// Save implements Checkpoint
+// The file is replaced atomically by writing a temp file and renaming it
+func (c *FileCheckpoint) Save(destID common.DestID, height int64) error {
+	if err := destID.Validate(); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	tmp, err := ioutil.TempFile(c.dir, destID.String()+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.WriteString(strconv.FormatInt(height, 10))
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the checkpoint of %s: %s", destID, err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path(destID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the checkpoint of %s: %s", destID, err)
+	}
+
+	return nil
+}
+
+// Load implements Checkpoint
+func (c *FileCheckpoint) Load(destID common.DestID) (int64, error) {
+	if err := destID.Validate(); err != nil {
+		return 0, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	bz, err := ioutil.ReadFile(c.path(destID))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	height, err := strconv.ParseInt(strings.TrimSpace(string(bz)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint of %s: %s", destID, err)
+	}
+
+	return height, nil
+}
+
+// List retrieves the checkpoints of all the chains
+func (c *FileCheckpoint) List() (map[common.DestID]int64, error) {
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	heights := make(map[common.DestID]int64)
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), checkpointFileExt) {
+			continue
+		}
+
+		destID := common.DestID(strings.TrimSuffix(file.Name(), checkpointFileExt))
+
+		height, err := c.Load(destID)
+		if err != nil {
+			return nil, err
+		}
+
+		heights[destID] = height
+	}
+
+	return heights, nil
+}
+
+// path returns the checkpoint file path of the given chain
+func (c *FileCheckpoint) path(destID common.DestID) string {
+	return filepath.Join(c.dir, destID.String()+checkpointFileExt)
+}
+
+// MemCheckpoint is a Checkpoint kept in memory on top of a base checkpoint,
+// which is only read but never written
+type MemCheckpoint struct {
+	base    Checkpoint
+	heights map[common.DestID]int64
+	mtx     sync.Mutex
+}
+
+var _ Checkpoint = (*MemCheckpoint)(nil)
+
+// NewMemCheckpoint constructs a new MemCheckpoint instance on the given base checkpoint
+// The base checkpoint is optional
+func NewMemCheckpoint(base Checkpoint) *MemCheckpoint {
+	return &MemCheckpoint{
+		base:    base,
+		heights: map[common.DestID]int64{},
+	}
+}
+
+// Save implements Checkpoint
+func (c *MemCheckpoint) Save(destID common.DestID, height int64) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.heights[destID] = height
+
+	return nil
+}
+
+// Load implements Checkpoint
+func (c *MemCheckpoint) Load(destID common.DestID) (int64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if height, ok := c.heights[destID]; ok {
+		return height, nil
+	}
+
+	if c.base == nil {
+		return 0, nil
+	}
+
+	return c.base.Load(destID)
+}
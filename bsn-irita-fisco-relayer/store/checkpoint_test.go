@@ -0,0 +1,43 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"relayer/common"
+)
+
+func TestFileCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpoint, err := NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	destID := common.DestID("fisco-1-5")
+
+	height, err := checkpoint.Load(destID)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), height)
+
+	require.NoError(t, checkpoint.Save(destID, 100))
+	require.NoError(t, checkpoint.Save(destID, 101))
+
+	// reopen to check durability
+	checkpoint, err = NewFileCheckpoint(dir)
+	require.NoError(t, err)
+
+	height, err = checkpoint.Load(destID)
+	require.NoError(t, err)
+	require.Equal(t, int64(101), height)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	require.Error(t, checkpoint.Save(common.DestID("invalid"), 1))
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"relayer/common"
+)
+
+const (
+	// StateBackendFile keeps the checkpoints in files and the rest in the relayer store
+	StateBackendFile = "file"
+	// StateBackendBolt keeps the whole relay state in a single BoltDB file
+	StateBackendBolt = "bolt"
+
+	// DefaultStateFile is the BoltDB state file relative to the home directory
+	DefaultStateFile = ".relayer/state.db"
+
+	// KeyPrefixStatus is the store key prefix of the request statuses
+	KeyPrefixStatus = "relay:"
+	// KeyPrefixDedup is the store key prefix of the seen marks
+	KeyPrefixDedup = "dedup:"
+)
+
+// StateStore defines the interface to persist the relay state: the checkpoints,
+// the seen marks for deduplication and the request statuses
+type StateStore interface {
+	Checkpoint
+
+	// SaveSeen marks the request of the given source chain as seen at the given time
+	SaveSeen(destID common.DestID, requestID string, seenAt time.Time) error
+
+	// LoadSeen retrieves the time the request of the given source chain was seen at
+	// The zero time is returned if it was never seen
+	LoadSeen(destID common.DestID, requestID string) (time.Time, error)
+
+	// DeleteSeen removes the seen mark of the request of the given source chain
+	DeleteSeen(destID common.DestID, requestID string) error
+
+	// PruneSeen removes the seen marks older than the given time
+	// The number of the removed marks is returned
+	PruneSeen(before time.Time) (int, error)
+
+	// SaveStatus stores the encoded status of the request of the given source chain
+	SaveStatus(destID common.DestID, requestID string, status []byte) error
+
+	// LoadStatus retrieves the encoded status of the request of the given source chain
+	// ErrNotFound is returned if no status exists
+	LoadStatus(destID common.DestID, requestID string) ([]byte, error)
+
+	// LookupStatus retrieves the encoded statuses of the given request by source chain,
+	// e.g. to query a request by its ID alone
+	LookupStatus(requestID string) (map[common.DestID][]byte, error)
+
+	// Close releases the resources of the state store
+	Close() error
+}
+
+// DefaultStatePath returns the default BoltDB state file under the relayer home directory
+func DefaultStatePath() (string, error) {
+	homeDir, err := common.GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, DefaultStateFile), nil
+}
+
+// FileStateStore is a StateStore keeping the checkpoints in a FileCheckpoint,
+// and the seen marks and the request statuses in the relayer store
+type FileStateStore struct {
+	*FileCheckpoint
+
+	db *Store
+}
+
+var _ StateStore = (*FileStateStore)(nil)
+
+// NewFileStateStore constructs a new FileStateStore instance
+func NewFileStateStore(checkpoint *FileCheckpoint, db *Store) *FileStateStore {
+	return &FileStateStore{
+		FileCheckpoint: checkpoint,
+		db:             db,
+	}
+}
+
+// SaveSeen implements StateStore
+func (s *FileStateStore) SaveSeen(destID common.DestID, requestID string, seenAt time.Time) error {
+	return s.db.Set(dedupKey(destID, requestID), encodeTime(seenAt))
+}
+
+// LoadSeen implements StateStore
+func (s *FileStateStore) LoadSeen(destID common.DestID, requestID string) (time.Time, error) {
+	bz, err := s.db.Get(dedupKey(destID, requestID))
+	if err == ErrNotFound {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	return decodeTime(bz)
+}
+
+// DeleteSeen implements StateStore
+func (s *FileStateStore) DeleteSeen(destID common.DestID, requestID string) error {
+	return s.db.Delete(dedupKey(destID, requestID))
+}
+
+// PruneSeen implements StateStore
+func (s *FileStateStore) PruneSeen(before time.Time) (int, error) {
+	var expired [][]byte
+
+	err := s.db.Iterate([]byte(KeyPrefixDedup), func(key, value []byte) error {
+		seenAt, err := decodeTime(value)
+		if err != nil || seenAt.Before(before) {
+			// the key is only valid during the iteration
+			expired = append(expired, append([]byte{}, key...))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, key := range expired {
+		if err := s.db.Delete(key); err != nil {
+			return i, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// SaveStatus implements StateStore
+func (s *FileStateStore) SaveStatus(destID common.DestID, requestID string, status []byte) error {
+	return s.db.Set(statusKey(destID, requestID), status)
+}
+
+// LoadStatus implements StateStore
+func (s *FileStateStore) LoadStatus(destID common.DestID, requestID string) ([]byte, error) {
+	return s.db.Get(statusKey(destID, requestID))
+}
+
+// LookupStatus implements StateStore
+// All the statuses are iterated, as the keys lead with the dest ID
+func (s *FileStateStore) LookupStatus(requestID string) (map[common.DestID][]byte, error) {
+	statuses := make(map[common.DestID][]byte)
+
+	err := s.db.Iterate([]byte(KeyPrefixStatus), func(key, value []byte) error {
+		destID, id, err := parseRequestKey(key, KeyPrefixStatus)
+		if err == nil && id == requestID {
+			// the value is only valid during the iteration
+			statuses[destID] = append([]byte{}, value...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// Close implements StateStore
+// The relayer store is shared, so it is left to its owner to close
+func (s *FileStateStore) Close() error {
+	return nil
+}
+
+// dedupKey returns the relayer store key of the seen mark
+func dedupKey(destID common.DestID, requestID string) []byte {
+	return []byte(fmt.Sprintf("%s%s:%s", KeyPrefixDedup, destID, requestID))
+}
+
+// statusKey returns the relayer store key of the request status
+func statusKey(destID common.DestID, requestID string) []byte {
+	return []byte(fmt.Sprintf("%s%s:%s", KeyPrefixStatus, destID, requestID))
+}
+
+// parseRequestKey parses the dest ID and the request ID of the given relayer store key
+func parseRequestKey(key []byte, prefix string) (common.DestID, string, error) {
+	// the dest IDs contain no colon, unlike the request IDs may
+	parts := strings.SplitN(strings.TrimPrefix(string(key), prefix), ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid key %s", key)
+	}
+
+	return common.DestID(parts[0]), parts[1], nil
+}
+
+// encodeTime encodes the time as the unix nanoseconds
+func encodeTime(t time.Time) []byte {
+	bz := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bz, uint64(t.UnixNano()))
+
+	return bz
+}
+
+// decodeTime decodes the time encoded by encodeTime
+func decodeTime(bz []byte) (time.Time, error) {
+	if len(bz) != 8 {
+		return time.Time{}, fmt.Errorf("invalid time of %d bytes", len(bz))
+	}
+
+	return time.Unix(0, int64(binary.LittleEndian.Uint64(bz))), nil
+}
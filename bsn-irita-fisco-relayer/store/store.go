@@ -6,6 +6,9 @@ import (
 	"github.com/cockroachdb/pebble"
 )
 
+// ErrNotFound is returned if the key does not exist in the store
+var ErrNotFound = pebble.ErrNotFound
+
 // Store defines a struct for data store
 type Store struct {
 	db *pebble.DB
@@ -23,6 +26,19 @@ func NewStore(path string) (*Store, error) {
 	}, nil
 }
 
+// NewReadOnlyStore opens the existing store at the given path for reading only
+// The writes fail, and nothing is created if the store does not exist
+func NewReadOnlyStore(path string) (*Store, error) {
+	db, err := pebble.Open(path, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		db: db,
+	}, nil
+}
+
 // Set writes the given key-value into the store
 func (s *Store) Set(key, value []byte) error {
 	return s.db.Set(key, value, pebble.Sync)
@@ -58,7 +74,6 @@ func (s *Store) GetInt64(key []byte) (int64, error) {
 	return int64(binary.LittleEndian.Uint64(value)), nil
 }
 
-
 // Get retrieves the value of the given key
 func (s *Store) Delete(key []byte) error {
 	err := s.db.Delete(key, nil)
@@ -67,4 +82,42 @@ func (s *Store) Delete(key []byte) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Iterate calls fn for each key-value with the given prefix in key order
+// The iteration stops on the first error returned by fn
+func (s *Store) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	iter := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: prefixUpperBound(prefix),
+	})
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+
+	return iter.Close()
+}
+
+// prefixUpperBound returns the smallest key greater than all keys with the given prefix
+func prefixUpperBound(prefix []byte) []byte {
+	upper := make([]byte, len(prefix))
+	copy(upper, prefix)
+
+	for i := len(upper) - 1; i >= 0; i-- {
+		upper[i]++
+		if upper[i] != 0 {
+			return upper[:i+1]
+		}
+	}
+
+	return nil
+}
+
+// Close flushes and closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}